@@ -0,0 +1,160 @@
+package manta
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// AccessLog describes an access log listed by ListAccessLogs. Path is the
+// full Manta path of the log object, and Time is the start of the hour it
+// covers, in UTC.
+type AccessLog struct {
+	Path string
+	Time time.Time
+	Size uint64
+}
+
+// ListAccessLogsInput represents parameters to a ListAccessLogs operation.
+// If Start or End is set, only logs for hours which begin at or after
+// Start, and before End, are listed.
+type ListAccessLogsInput struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ListAccessLogsOutput contains the outputs of a ListAccessLogs operation,
+// in order of Time.
+type ListAccessLogsOutput struct {
+	Logs []*AccessLog
+}
+
+// ListAccessLogs lists the hourly access logs which Manta has generated for
+// the account below /:account/reports/access-logs.
+func (c *Client) ListAccessLogs(input *ListAccessLogsInput) (*ListAccessLogsOutput, error) {
+	output := &ListAccessLogsOutput{}
+	err := c.listDatedReports(c.reportsPath("access-logs"), input.Start, input.End, func(reportPath string, start time.Time, entry *DirectoryEntry) {
+		output.Logs = append(output.Logs, &AccessLog{
+			Path: reportPath,
+			Time: start,
+			Size: entry.Size,
+		})
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListAccessLogs request: {{err}}", err)
+	}
+
+	return output, nil
+}
+
+// AccessLogCaller identifies the account, and the user of that account if
+// any, which made a request recorded in an access log.
+type AccessLogCaller struct {
+	Login     string   `json:"login"`
+	UUID      string   `json:"uuid"`
+	Groups    []string `json:"groups"`
+	User      string   `json:"user"`
+	UserLogin string   `json:"userLogin"`
+}
+
+// AccessLogRecord is an audit record of a single request in an access log.
+// Path is the path requested, including any query string, and Latency the
+// time taken by Manta to respond. Caller is nil for anonymous requests.
+// Raw holds the record as it was read, for any fields not decoded.
+type AccessLogRecord struct {
+	Time          time.Time
+	RequestID     string
+	Method        string
+	Path          string
+	StatusCode    int
+	Latency       time.Duration
+	RemoteAddress string
+	UserAgent     string
+	Caller        *AccessLogCaller
+	Raw           json.RawMessage
+}
+
+// AccessLogRecordFunc is called by ReadAccessLog for each record of a log.
+// If it returns an error, reading stops and the error is returned.
+type AccessLogRecordFunc func(record *AccessLogRecord) error
+
+// ReadAccessLogInput represents parameters to a ReadAccessLog operation.
+// Path is the full Manta path of the log, as listed by ListAccessLogs.
+type ReadAccessLogInput struct {
+	Path string
+}
+
+// ReadAccessLog streams an access log, calling fn for each record as it is
+// decoded, so that logs need not be held in memory.
+func (c *Client) ReadAccessLog(input *ReadAccessLogInput, fn AccessLogRecordFunc) error {
+	respBody, _, err := c.executeRequest(http.MethodGet, c.absoluteObjectPath(input.Path), nil, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing ReadAccessLog request: {{err}}", err)
+	}
+
+	decoder := json.NewDecoder(respBody)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errwrap.Wrapf("Error decoding ReadAccessLog response: {{err}}", err)
+		}
+
+		record, err := decodeAccessLogRecord(raw)
+		if err != nil {
+			return errwrap.Wrapf("Error decoding ReadAccessLog response: {{err}}", err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeAccessLogRecord decodes a single record of an access log, which is
+// written by Manta in the format of a restify audit log entry.
+func decodeAccessLogRecord(raw json.RawMessage) (*AccessLogRecord, error) {
+	var entry struct {
+		Time          time.Time `json:"time"`
+		RequestID     string    `json:"req_id"`
+		RemoteAddress string    `json:"remoteAddress"`
+		Latency       float64   `json:"latency"`
+		Request       struct {
+			Method  string                     `json:"method"`
+			URL     string                     `json:"url"`
+			Headers map[string]json.RawMessage `json:"headers"`
+			Caller  *AccessLogCaller           `json:"caller"`
+		} `json:"req"`
+		Response struct {
+			StatusCode int `json:"statusCode"`
+		} `json:"res"`
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, err
+	}
+
+	var userAgent string
+	if value, ok := entry.Request.Headers["user-agent"]; ok {
+		json.Unmarshal(value, &userAgent)
+	}
+
+	return &AccessLogRecord{
+		Time:          entry.Time,
+		RequestID:     entry.RequestID,
+		Method:        entry.Request.Method,
+		Path:          entry.Request.URL,
+		StatusCode:    entry.Response.StatusCode,
+		Latency:       time.Duration(entry.Latency * float64(time.Millisecond)),
+		RemoteAddress: entry.RemoteAddress,
+		UserAgent:     userAgent,
+		Caller:        entry.Request.Caller,
+		Raw:           raw,
+	}, nil
+}
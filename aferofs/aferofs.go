@@ -0,0 +1,357 @@
+// Package aferofs provides an implementation of afero.Fs backed by the
+// Manta Storage Service, so that applications written against afero may
+// store their files in Manta.
+package aferofs
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/jen20/manta-go"
+	"github.com/spf13/afero"
+)
+
+// ErrNotSupported is returned for operations which have no equivalent in
+// Manta, such as changing the mode or owner of a file.
+var ErrNotSupported = errors.New("operation not supported by Manta")
+
+// Fs implements afero.Fs over the tree at a root directory in Manta.
+//
+// Manta stores objects whole, so a file opened for writing is buffered in a
+// local temporary file and uploaded when it is synced or closed. A file
+// opened read-only is read directly from Manta, using ranged requests when
+// seeking. Directories must be created explicitly, as on a local filesystem.
+type Fs struct {
+	client *manta.Client
+	root   string
+	fs     *manta.FS
+}
+
+var _ afero.Fs = &Fs{}
+
+// NewFs is used to construct an Fs over the tree at root, relative to the
+// account's /stor directory. The root directory must already exist.
+func NewFs(client *manta.Client, root string) *Fs {
+	root = strings.Trim(path.Clean("/"+root), "/")
+	return &Fs{
+		client: client,
+		root:   root,
+		fs:     manta.NewFS(client, root),
+	}
+}
+
+// Name implements afero.Fs.
+func (f *Fs) Name() string {
+	return "MantaFs"
+}
+
+// relativePath cleans name into a path relative to the root of the Fs, as
+// accepted by manta.FS.
+func relativePath(name string) string {
+	relative := strings.Trim(path.Clean("/"+name), "/")
+	if relative == "" {
+		return "."
+	}
+	return relative
+}
+
+// objectPath returns the path relative to /stor of the entry with the given
+// name.
+func (f *Fs) objectPath(name string) string {
+	return strings.TrimPrefix(path.Join(f.root, path.Clean("/"+name)), "/")
+}
+
+// stat returns information about the entry with the given name, and an
+// error for which os.IsNotExist returns true if it does not exist.
+func (f *Fs) stat(name string) (os.FileInfo, error) {
+	info, err := f.fs.Stat(relativePath(name))
+	if err != nil {
+		return nil, renamePathError(err, name)
+	}
+	return info, nil
+}
+
+// Stat implements afero.Fs.
+func (f *Fs) Stat(name string) (os.FileInfo, error) {
+	return f.stat(name)
+}
+
+// Create implements afero.Fs.
+func (f *Fs) Create(name string) (afero.File, error) {
+	return f.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// Open implements afero.Fs.
+func (f *Fs) Open(name string) (afero.File, error) {
+	return f.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile implements afero.Fs. The permission bits are ignored, as Manta
+// has no file modes.
+func (f *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	info, err := f.stat(name)
+	if err != nil {
+		if !os.IsNotExist(err) || flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+		info = nil
+	}
+
+	if info != nil {
+		if flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		}
+		if info.IsDir() {
+			if writable {
+				return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+			}
+			return &directoryFile{
+				fs:   f,
+				name: name,
+				info: info,
+			}, nil
+		}
+	}
+
+	if !writable {
+		if info == nil {
+			if err := f.create(name, flag); err != nil {
+				return nil, err
+			}
+		}
+		file, err := f.fs.Open(relativePath(name))
+		if err != nil {
+			return nil, renamePathError(err, name)
+		}
+		return &objectFile{
+			name: name,
+			file: file,
+		}, nil
+	}
+
+	return newWritableFile(f, name, flag, info)
+}
+
+// create creates an empty object with the given name, for a file opened
+// read-only with os.O_CREATE.
+func (f *Fs) create(name string, flag int) error {
+	err := f.client.PutObject(&manta.PutObjectInput{
+		ObjectPath:   f.objectPath(name),
+		ObjectReader: strings.NewReader(""),
+		CreateOnly:   flag&os.O_EXCL != 0,
+	})
+	if err != nil {
+		if manta.IsAlreadyExistsError(err) {
+			err = os.ErrExist
+		}
+		return pathError("open", name, err)
+	}
+	return nil
+}
+
+// Mkdir implements afero.Fs. The parent directory must exist.
+func (f *Fs) Mkdir(name string, perm os.FileMode) error {
+	if _, err := f.stat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+
+	err := f.client.PutDirectory(&manta.PutDirectoryInput{
+		DirectoryName: f.objectPath(name),
+	})
+	if err != nil {
+		return pathError("mkdir", name, err)
+	}
+	return nil
+}
+
+// MkdirAll implements afero.Fs.
+func (f *Fs) MkdirAll(name string, perm os.FileMode) error {
+	err := f.client.PutDirectoryRecursive(&manta.PutDirectoryInput{
+		DirectoryName: f.objectPath(name),
+	})
+	if err != nil {
+		return pathError("mkdir", name, err)
+	}
+	return nil
+}
+
+// Remove implements afero.Fs. Directories must be empty.
+func (f *Fs) Remove(name string) error {
+	info, err := f.stat(name)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		err = f.client.DeleteDirectory(&manta.DeleteDirectoryInput{
+			DirectoryName: f.objectPath(name),
+		})
+	} else {
+		err = f.client.DeleteObject(&manta.DeleteObjectInput{
+			ObjectPath: f.objectPath(name),
+		})
+	}
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	return nil
+}
+
+// RemoveAll implements afero.Fs. Objects are deleted before the directories
+// which contain them. Removing the root of the Fs empties it, but leaves the
+// root directory in place.
+func (f *Fs) RemoveAll(name string) error {
+	root := f.objectPath(name)
+
+	var objects, directories []string
+	err := f.client.Walk(root, func(entryPath string, entry *manta.DirectoryEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type == manta.EntryTypeDirectory {
+			if entryPath != f.root {
+				directories = append(directories, entryPath)
+			}
+		} else {
+			objects = append(objects, entryPath)
+		}
+		return nil
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return pathError("removeall", name, err)
+	}
+
+	if len(objects) != 0 {
+		output, err := f.client.DeleteObjects(&manta.DeleteObjectsInput{
+			ObjectPaths: objects,
+		})
+		if err != nil {
+			return pathError("removeall", name, output.Failed()[0].Error)
+		}
+	}
+
+	// Walk visits directories before their contents, so deleting in
+	// reverse order removes children first.
+	for i := len(directories) - 1; i >= 0; i-- {
+		err := f.client.DeleteDirectory(&manta.DeleteDirectoryInput{
+			DirectoryName: directories[i],
+		})
+		if err != nil {
+			return pathError("removeall", name, err)
+		}
+	}
+	return nil
+}
+
+// Rename implements afero.Fs. Only objects may be renamed; see
+// manta.Client.MoveObject for the guarantees given.
+func (f *Fs) Rename(oldname, newname string) error {
+	info, err := f.stat(oldname)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: unwrapPathError(err)}
+	}
+	if info.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrNotSupported}
+	}
+
+	err = f.client.MoveObject(&manta.MoveObjectInput{
+		SourcePath:      f.objectPath(oldname),
+		DestinationPath: f.objectPath(newname),
+	})
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return nil
+}
+
+// Chmod implements afero.Fs. Manta has no file modes, so ErrNotSupported is
+// returned.
+func (f *Fs) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: ErrNotSupported}
+}
+
+// Chown implements afero.Fs. Manta has no file owners, so ErrNotSupported is
+// returned.
+func (f *Fs) Chown(name string, uid, gid int) error {
+	return &os.PathError{Op: "chown", Path: name, Err: ErrNotSupported}
+}
+
+// Chtimes implements afero.Fs. The modification time of an object is set by
+// Manta when it is written, so ErrNotSupported is returned.
+func (f *Fs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return &os.PathError{Op: "chtimes", Path: name, Err: ErrNotSupported}
+}
+
+// readDir returns the entries of the directory with the given name, sorted
+// by name.
+func (f *Fs) readDir(name string) ([]os.FileInfo, error) {
+	entries, err := f.fs.ReadDir(relativePath(name))
+	if err != nil {
+		return nil, renamePathError(err, name)
+	}
+
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+// isNotFound returns true if err indicates that an entry does not exist.
+func isNotFound(err error) bool {
+	if manta.IsResourceNotFoundError(err) || manta.IsDirectoryDoesNotExistError(err) {
+		return true
+	}
+	mantaErr := errwrap.GetType(err, &manta.MantaError{})
+	return mantaErr != nil && mantaErr.(*manta.MantaError).StatusCode == http.StatusNotFound
+}
+
+// pathError returns a *os.PathError for err, translating Manta's not found
+// errors to os.ErrNotExist.
+func pathError(op, name string, err error) error {
+	if isNotFound(err) {
+		err = os.ErrNotExist
+	}
+	return &os.PathError{
+		Op:   op,
+		Path: name,
+		Err:  err,
+	}
+}
+
+// renamePathError sets the path of err to name if it is a *fs.PathError from
+// manta.FS, whose paths are relative to the root of the Fs.
+func renamePathError(err error, name string) error {
+	if pathErr, ok := err.(*fs.PathError); ok {
+		pathErr.Path = name
+	}
+	return err
+}
+
+// unwrapPathError returns the error underlying a *os.PathError.
+func unwrapPathError(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
@@ -0,0 +1,365 @@
+package aferofs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jen20/manta-go"
+	"github.com/jen20/manta-go/authentication"
+	"github.com/spf13/afero"
+)
+
+// testSigner signs requests without a key, since the testServer does not
+// check signatures.
+type testSigner struct{}
+
+func (testSigner) Sign(string) (string, error)            { return "Signature test", nil }
+func (testSigner) SignRaw(string) (string, string, error) { return "test", "rsa-sha256", nil }
+func (testSigner) KeyFingerprint() string                 { return "test" }
+func (testSigner) DefaultAlgorithm() string               { return "rsa-sha256" }
+
+// testObject is an object or directory stored by a testServer.
+type testObject struct {
+	data      []byte
+	directory bool
+	etag      string
+	headers   http.Header
+}
+
+// testServer is a minimal in-memory implementation of the Manta storage
+// API for the /stor directory of the account "test".
+type testServer struct {
+	lock     sync.Mutex
+	objects  map[string]*testObject
+	nextETag int
+}
+
+// newTestClient starts a testServer and returns a client which makes requests
+// to it. The server is closed when the test ends.
+func newTestClient(t *testing.T) *manta.Client {
+	s := &testServer{
+		objects: map[string]*testObject{
+			"": {directory: true},
+		},
+	}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	client, err := manta.NewClient(&manta.ClientOptions{
+		Endpoint:    server.URL,
+		AccountName: "test",
+		Signers:     []authentication.Signer{testSigner{}},
+		Logger:      log.New(ioutil.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func writeTestError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": code})
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	objectPath := strings.Trim(strings.TrimPrefix(r.URL.Path, "/test/stor"), "/")
+	object := s.objects[objectPath]
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("If-None-Match") == "*" && object != nil {
+			writeTestError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && (object == nil || object.etag != match) {
+			writeTestError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		if strings.Contains(r.Header.Get("Content-Type"), "type=directory") {
+			s.objects[objectPath] = &testObject{directory: true}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.nextETag++
+		s.objects[objectPath] = &testObject{
+			data:    data,
+			etag:    fmt.Sprintf("etag-%d", s.nextETag),
+			headers: r.Header.Clone(),
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet, http.MethodHead:
+		if object == nil {
+			writeTestError(w, http.StatusNotFound, "ResourceNotFound")
+			return
+		}
+		if object.directory {
+			s.serveDirectory(w, r, objectPath)
+			return
+		}
+		s.serveObject(w, r, object)
+	case http.MethodDelete:
+		delete(s.objects, objectPath)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *testServer) serveObject(w http.ResponseWriter, r *http.Request, object *testObject) {
+	if match := r.Header.Get("If-Match"); match != "" && object.etag != match {
+		writeTestError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+	for key, values := range object.headers {
+		switch lower := strings.ToLower(key); {
+		case strings.HasPrefix(lower, "m-"), lower == "content-type", lower == "content-encoding",
+			lower == "cache-control", lower == "role-tag", lower == "durability-level":
+			w.Header()[key] = values
+		}
+	}
+	w.Header().Set("Etag", object.etag)
+	w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+
+	data := object.data
+	status := http.StatusOK
+	if byteRange := r.Header.Get("Range"); byteRange != "" {
+		bounds := strings.SplitN(strings.TrimPrefix(byteRange, "bytes="), "-", 2)
+		start, _ := strconv.Atoi(bounds[0])
+		end := len(data) - 1
+		if bounds[1] != "" {
+			end, _ = strconv.Atoi(bounds[1])
+		}
+		if start >= len(data) {
+			writeTestError(w, http.StatusRequestedRangeNotSatisfiable, "RequestedRangeNotSatisfiable")
+			return
+		}
+		if end >= len(data) {
+			end = len(data) - 1
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+func (s *testServer) serveDirectory(w http.ResponseWriter, r *http.Request, directoryPath string) {
+	var names []string
+	for key := range s.objects {
+		parent := path.Dir(key)
+		if parent == "." {
+			parent = ""
+		}
+		if key != "" && parent == directoryPath {
+			names = append(names, path.Base(key))
+		}
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "application/x-json-stream; type=directory")
+	w.Header().Set("Result-Set-Size", strconv.Itoa(len(names)))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	marker := r.URL.Query().Get("marker")
+	encoder := json.NewEncoder(w)
+	for _, name := range names {
+		if name < marker {
+			continue
+		}
+		object := s.objects[path.Join(directoryPath, name)]
+		entry := &manta.DirectoryEntry{Name: name, Type: manta.EntryTypeDirectory}
+		if !object.directory {
+			entry.Type = manta.EntryTypeObject
+			entry.ETag = object.etag
+			entry.Size = uint64(len(object.data))
+		}
+		encoder.Encode(entry)
+	}
+}
+
+// testData is the data of the objects stored by the tests.
+var testData = strings.Repeat("0123456789", 100)
+
+func TestFsRoot(t *testing.T) {
+	client := newTestClient(t)
+	if err := client.PutObject(&manta.PutObjectInput{
+		ObjectPath:   "object",
+		ObjectReader: strings.NewReader(testData),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fs := NewFs(client, "")
+
+	info, err := fs.Stat("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() {
+		t.Errorf("root is not a directory")
+	}
+
+	names, err := afero.ReadDir(fs, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0].Name() != "object" {
+		t.Errorf("expected the root to hold object, got %v", names)
+	}
+}
+
+func TestObjectFileRead(t *testing.T) {
+	client := newTestClient(t)
+	inputs := []*manta.PutObjectInput{
+		{ObjectPath: "plain", ObjectReader: strings.NewReader(testData)},
+		{ObjectPath: "compressed", Compress: true, ObjectReader: strings.NewReader(testData)},
+		{ObjectPath: "streamed", Compress: true, ObjectReader: io.MultiReader(strings.NewReader(testData))},
+	}
+	for _, input := range inputs {
+		if err := client.PutObject(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fs := NewFs(client, "")
+
+	for _, input := range inputs {
+		t.Run(input.ObjectPath, func(t *testing.T) {
+			name := "/" + input.ObjectPath
+
+			data, err := afero.ReadFile(fs, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != testData {
+				t.Errorf("ReadFile: data does not match")
+			}
+
+			file, err := fs.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			p := make([]byte, 100)
+			n, err := file.ReadAt(p, 950)
+			if n != 50 || err != io.EOF {
+				t.Errorf("expected ReadAt to return (50, EOF), got (%d, %v)", n, err)
+			}
+			if string(p[:n]) != testData[950:] {
+				t.Errorf("ReadAt: data does not match")
+			}
+
+			end, err := file.Seek(-100, io.SeekEnd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if end != int64(len(testData)-100) {
+				t.Errorf("expected to seek to %d, got %d", len(testData)-100, end)
+			}
+			rest, err := ioutil.ReadAll(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(rest) != testData[len(testData)-100:] {
+				t.Errorf("data read after seeking does not match")
+			}
+		})
+	}
+}
+
+func TestWritableFileKeepsAttributes(t *testing.T) {
+	cases := []struct {
+		name     string
+		flag     int
+		compress bool
+		expected string
+	}{
+		{name: "append", flag: os.O_WRONLY | os.O_APPEND, expected: testData + "more"},
+		{name: "compressed append", flag: os.O_WRONLY | os.O_APPEND, compress: true, expected: testData + "more"},
+		{name: "truncate", flag: os.O_WRONLY | os.O_TRUNC, expected: "more"},
+		{name: "compressed truncate", flag: os.O_WRONLY | os.O_TRUNC, compress: true, expected: "more"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t)
+			err := client.PutObject(&manta.PutObjectInput{
+				ObjectPath:      "object",
+				ContentType:     "text/plain",
+				DurabilityLevel: 3,
+				CacheControl:    "max-age=60",
+				RoleTags:        []string{"readers"},
+				Metadata:        map[string]string{"owner": "test"},
+				Compress:        tc.compress,
+				ComputeSHA256:   true,
+				ObjectReader:    strings.NewReader(testData),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs := NewFs(client, "")
+
+			file, err := fs.OpenFile("/object", tc.flag, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := file.WriteString("more"); err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			head, err := client.HeadObject(&manta.HeadObjectInput{ObjectPath: "object"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if head.ContentType != "text/plain" || head.DurabilityLevel != 3 || head.CacheControl != "max-age=60" {
+				t.Errorf("headers not kept: content type %q, durability %d, cache control %q",
+					head.ContentType, head.DurabilityLevel, head.CacheControl)
+			}
+			if !reflect.DeepEqual(head.RoleTags, []string{"readers"}) {
+				t.Errorf("role tags not kept: %v", head.RoleTags)
+			}
+			if head.Metadata["owner"] != "test" {
+				t.Errorf("metadata not kept: %v", head.Metadata)
+			}
+
+			data, err := afero.ReadFile(fs, "/object")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("expected the object to hold %d bytes, got %d", len(tc.expected), len(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package aferofs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jen20/manta-go"
+	"github.com/spf13/afero"
+)
+
+var (
+	_ afero.File = &objectFile{}
+	_ afero.File = &directoryFile{}
+	_ afero.File = &writableFile{}
+)
+
+// fileInfo implements os.FileInfo for a file opened for writing, whose
+// size changes as it is written.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() os.FileMode  { return 0666 }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return false }
+func (i *fileInfo) Sys() interface{}   { return nil }
+
+// objectFile is an object opened read-only. It reads through the file
+// opened by manta.FS, which requests the data when it is first read and
+// again from the new offset after a seek, and which reads objects
+// compressed or encrypted by the client from the start when they are read
+// from an offset.
+type objectFile struct {
+	name   string
+	file   fs.File
+	closed bool
+}
+
+func (o *objectFile) Name() string {
+	return o.name
+}
+
+func (o *objectFile) Stat() (os.FileInfo, error) {
+	if o.closed {
+		return nil, afero.ErrFileClosed
+	}
+	info, err := o.file.Stat()
+	return info, renamePathError(err, o.name)
+}
+
+func (o *objectFile) Read(p []byte) (int, error) {
+	if o.closed {
+		return 0, afero.ErrFileClosed
+	}
+	n, err := o.file.Read(p)
+	return n, renamePathError(err, o.name)
+}
+
+func (o *objectFile) ReadAt(p []byte, off int64) (int, error) {
+	if o.closed {
+		return 0, afero.ErrFileClosed
+	}
+	n, err := o.file.(io.ReaderAt).ReadAt(p, off)
+	return n, renamePathError(err, o.name)
+}
+
+func (o *objectFile) Seek(offset int64, whence int) (int64, error) {
+	if o.closed {
+		return 0, afero.ErrFileClosed
+	}
+	offset, err := o.file.(io.Seeker).Seek(offset, whence)
+	return offset, renamePathError(err, o.name)
+}
+
+func (o *objectFile) Close() error {
+	if o.closed {
+		return afero.ErrFileClosed
+	}
+	o.closed = true
+	return o.file.Close()
+}
+
+func (o *objectFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: o.name, Err: syscall.EBADF}
+}
+
+func (o *objectFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: o.name, Err: syscall.EBADF}
+}
+
+func (o *objectFile) WriteString(s string) (int, error) {
+	return o.Write([]byte(s))
+}
+
+func (o *objectFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: o.name, Err: syscall.EBADF}
+}
+
+func (o *objectFile) Sync() error {
+	return nil
+}
+
+func (o *objectFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: o.name, Err: syscall.ENOTDIR}
+}
+
+func (o *objectFile) Readdirnames(n int) ([]string, error) {
+	return nil, &os.PathError{Op: "readdir", Path: o.name, Err: syscall.ENOTDIR}
+}
+
+// directoryFile is an open directory. The entries are listed when they are
+// first read.
+type directoryFile struct {
+	fs      *Fs
+	name    string
+	info    os.FileInfo
+	entries []os.FileInfo
+	read    bool
+	offset  int
+}
+
+func (d *directoryFile) Name() string {
+	return d.name
+}
+
+func (d *directoryFile) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *directoryFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.read {
+		entries, err := d.fs.readDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+func (d *directoryFile) Readdirnames(n int) ([]string, error) {
+	infos, err := d.Readdir(n)
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names, err
+}
+
+func (d *directoryFile) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.offset = 0
+		d.read = false
+		return 0, nil
+	}
+	return 0, &os.PathError{Op: "seek", Path: d.name, Err: syscall.EINVAL}
+}
+
+func (d *directoryFile) Close() error {
+	return nil
+}
+
+func (d *directoryFile) Sync() error {
+	return nil
+}
+
+func (d *directoryFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *directoryFile) ReadAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *directoryFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *directoryFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *directoryFile) WriteString(s string) (int, error) {
+	return d.Write([]byte(s))
+}
+
+func (d *directoryFile) Truncate(size int64) error {
+	return &os.PathError{Op: "truncate", Path: d.name, Err: syscall.EISDIR}
+}
+
+// writableFile is an object opened for writing. Its data is held in a local
+// temporary file, which is uploaded to Manta by Sync and by Close if it has
+// been changed since it was last uploaded. The content type, durability
+// level, caching and CORS headers, role tags and metadata of an existing
+// object are kept when it is uploaded.
+type writableFile struct {
+	fs         *Fs
+	name       string
+	file       *os.File
+	modTime    time.Time
+	attributes *manta.PutObjectInput
+	dirty      bool
+	createOnly bool
+	closed     bool
+}
+
+// newWritableFile opens the object with the given name for writing. info
+// describes the existing object, or is nil if it does not exist. Unless the
+// object is truncated, its existing data is downloaded first.
+func newWritableFile(f *Fs, name string, flag int, info os.FileInfo) (*writableFile, error) {
+	temp, err := os.CreateTemp("", "manta-")
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	tempName := temp.Name()
+	temp.Close()
+
+	file, err := os.OpenFile(tempName, os.O_RDWR|(flag&os.O_APPEND), 0600)
+	if err != nil {
+		os.Remove(tempName)
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	w := &writableFile{
+		fs:         f,
+		name:       name,
+		file:       file,
+		modTime:    time.Now(),
+		attributes: &manta.PutObjectInput{},
+		dirty:      info == nil || flag&os.O_TRUNC != 0,
+		createOnly: flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0,
+	}
+
+	if info != nil {
+		var err error
+		if flag&os.O_TRUNC == 0 {
+			w.modTime = info.ModTime()
+			err = w.download(info)
+		} else {
+			err = w.readAttributes(info)
+		}
+		if err != nil {
+			w.discard()
+			return nil, pathError("open", name, err)
+		}
+	}
+
+	return w, nil
+}
+
+// etag returns the ETag of the existing object described by info.
+func etag(info os.FileInfo) string {
+	if entry, ok := info.Sys().(*manta.DirectoryEntry); ok {
+		return entry.ETag
+	}
+	return ""
+}
+
+// download copies the existing data of the object into the temporary file,
+// and records its attributes.
+func (w *writableFile) download(info os.FileInfo) error {
+	output, err := w.fs.client.GetObject(&manta.GetObjectInput{
+		ObjectPath: w.fs.objectPath(w.name),
+		IfMatch:    etag(info),
+	})
+	if err != nil {
+		return err
+	}
+	defer output.ObjectReader.Close()
+
+	// GetObject removes the metadata describing how the data it decoded
+	// was stored, so the rest describes the data as downloaded.
+	w.setAttributes(output.ContentType, output.DurabilityLevel, output.CacheControl,
+		output.Expires, output.CORS, output.RoleTags, output.Metadata, false)
+
+	if _, err := io.Copy(w.file, output.ObjectReader); err != nil {
+		return err
+	}
+	_, err = w.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// readAttributes records the attributes of the existing object, for a file
+// whose data is truncated.
+func (w *writableFile) readAttributes(info os.FileInfo) error {
+	head, err := w.fs.client.HeadObject(&manta.HeadObjectInput{
+		ObjectPath: w.fs.objectPath(w.name),
+		IfMatch:    etag(info),
+	})
+	if err != nil {
+		return err
+	}
+
+	w.setAttributes(head.ContentType, head.DurabilityLevel, head.CacheControl,
+		head.Expires, head.CORS, head.RoleTags, head.Metadata, true)
+	return nil
+}
+
+// setAttributes records the attributes of the existing object to be kept
+// when it is uploaded. The digest stored with the object no longer
+// describes its data once it is written, so is dropped, as is the metadata
+// describing how the data was compressed or encrypted if encoded is true;
+// PutObject records them afresh as configured.
+func (w *writableFile) setAttributes(contentType string, durabilityLevel uint64, cacheControl string,
+	expires time.Time, cors *manta.CORSConfiguration, roleTags []string, metadata map[string]string, encoded bool) {
+
+	w.attributes.ContentType = contentType
+	w.attributes.DurabilityLevel = durabilityLevel
+	w.attributes.CacheControl = cacheControl
+	if !expires.IsZero() {
+		w.attributes.Expires = &expires
+	}
+	w.attributes.CORS = cors
+	w.attributes.RoleTags = roleTags
+
+	w.attributes.Metadata = map[string]string{}
+	for key, value := range metadata {
+		if key == "content-sha256" {
+			continue
+		}
+		if encoded && (strings.HasPrefix(key, "compress-") || strings.HasPrefix(key, "encrypt-")) {
+			continue
+		}
+		w.attributes.Metadata[key] = value
+	}
+}
+
+// discard closes and removes the temporary file.
+func (w *writableFile) discard() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+func (w *writableFile) Name() string {
+	return w.name
+}
+
+func (w *writableFile) Stat() (os.FileInfo, error) {
+	if w.closed {
+		return nil, afero.ErrFileClosed
+	}
+
+	info, err := w.file.Stat()
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: w.name, Err: err}
+	}
+	return &fileInfo{
+		name:    path.Base(w.name),
+		size:    info.Size(),
+		modTime: w.modTime,
+	}, nil
+}
+
+func (w *writableFile) Read(p []byte) (int, error) {
+	if w.closed {
+		return 0, afero.ErrFileClosed
+	}
+	return w.file.Read(p)
+}
+
+func (w *writableFile) ReadAt(p []byte, off int64) (int, error) {
+	if w.closed {
+		return 0, afero.ErrFileClosed
+	}
+	return w.file.ReadAt(p, off)
+}
+
+func (w *writableFile) Seek(offset int64, whence int) (int64, error) {
+	if w.closed {
+		return 0, afero.ErrFileClosed
+	}
+	return w.file.Seek(offset, whence)
+}
+
+func (w *writableFile) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, afero.ErrFileClosed
+	}
+	w.changed()
+	return w.file.Write(p)
+}
+
+func (w *writableFile) WriteAt(p []byte, off int64) (int, error) {
+	if w.closed {
+		return 0, afero.ErrFileClosed
+	}
+	w.changed()
+	return w.file.WriteAt(p, off)
+}
+
+func (w *writableFile) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *writableFile) Truncate(size int64) error {
+	if w.closed {
+		return afero.ErrFileClosed
+	}
+	w.changed()
+	return w.file.Truncate(size)
+}
+
+// changed records that the data must be uploaded.
+func (w *writableFile) changed() {
+	w.dirty = true
+	w.modTime = time.Now()
+}
+
+// Sync uploads the data to Manta if it has changed since it was last
+// uploaded.
+func (w *writableFile) Sync() error {
+	if w.closed {
+		return afero.ErrFileClosed
+	}
+	if !w.dirty {
+		return nil
+	}
+
+	info, err := w.file.Stat()
+	if err != nil {
+		return &os.PathError{Op: "sync", Path: w.name, Err: err}
+	}
+
+	// A SectionReader leaves the offset of the file unchanged.
+	input := *w.attributes
+	input.ObjectPath = w.fs.objectPath(w.name)
+	input.ContentLength = uint64(info.Size())
+	input.ObjectReader = io.NewSectionReader(w.file, 0, info.Size())
+	input.CreateOnly = w.createOnly
+	err = w.fs.client.PutObject(&input)
+	if err != nil {
+		if manta.IsAlreadyExistsError(err) {
+			err = os.ErrExist
+		}
+		return pathError("sync", w.name, err)
+	}
+
+	w.dirty = false
+	w.createOnly = false
+	return nil
+}
+
+// Close uploads the data to Manta as for Sync, and removes the temporary
+// file.
+func (w *writableFile) Close() error {
+	if w.closed {
+		return afero.ErrFileClosed
+	}
+
+	err := w.Sync()
+	w.closed = true
+	w.discard()
+	return err
+}
+
+func (w *writableFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: w.name, Err: syscall.ENOTDIR}
+}
+
+func (w *writableFile) Readdirnames(n int) ([]string, error) {
+	return nil, &os.PathError{Op: "readdir", Path: w.name, Err: syscall.ENOTDIR}
+}
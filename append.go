@@ -0,0 +1,224 @@
+package manta
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// AppendObjectInput represents parameters to an AppendObject operation.
+// ContentLength is the length of the data to append, and may be zero if it
+// is unknown and ObjectReader is not an io.Seeker.
+type AppendObjectInput struct {
+	ObjectPath    string
+	ContentLength uint64
+	ObjectReader  io.Reader
+}
+
+// AppendObject appends the data from ObjectReader to the object at
+// ObjectPath, creating the object if it does not exist. Manta does not
+// support appending to objects, so the object is replaced with a new object
+// holding the existing data followed by the new data, preserving its content
+// type, durability level, cache and CORS headers, role tags and metadata.
+//
+// If the existing object is at least MpuMinPartSize bytes, the new object is
+// assembled by a multipart upload of two parts. The first is a SnapLink to
+// the existing object, so that only the new data is uploaded; the existing
+// data is streamed from Manta and back only if SnapLinks are disabled for
+// the account. Smaller objects cannot be a part other than the last, so they
+// are read and uploaded again with the new data by a single PutObject. In
+// either case the object is only replaced if it has not changed since it was
+// read. If another writer changed it in the meantime, nothing is appended
+// and an error for which IsPreconditionFailedError returns true is
+// returned, so that the append may be retried with the same data.
+//
+// Objects which were encrypted or compressed by the client cannot be
+// appended to.
+func (c *Client) AppendObject(input *AppendObjectInput) error {
+	head, err := c.HeadObject(&HeadObjectInput{
+		ObjectPath: input.ObjectPath,
+	})
+	if err != nil {
+		if !IsResourceNotFoundError(err) && !hasStatusCode(err, http.StatusNotFound) {
+			return errwrap.Wrapf("Error executing AppendObject request: {{err}}", err)
+		}
+
+		err = c.PutObject(&PutObjectInput{
+			ObjectPath:    input.ObjectPath,
+			ContentLength: input.ContentLength,
+			ObjectReader:  input.ObjectReader,
+			CreateOnly:    true,
+		})
+		if err != nil {
+			return errwrap.Wrapf("Error executing AppendObject request: {{err}}", err)
+		}
+		return nil
+	}
+
+	if _, ok := head.Metadata["encrypt-type"]; ok {
+		return errors.New("Cannot append to an object encrypted by the client")
+	}
+	if _, ok := head.Metadata["compress-type"]; ok {
+		return errors.New("Cannot append to an object compressed by the client")
+	}
+	// The stored digest will no longer describe the object.
+	delete(head.Metadata, "content-sha256")
+
+	if head.ContentLength >= MpuMinPartSize {
+		if err := c.appendParts(input, head); err != nil {
+			return errwrap.Wrapf("Error executing AppendObject request: {{err}}", err)
+		}
+		return nil
+	}
+
+	existing, err := c.readAppendSource(input.ObjectPath, head.ETag)
+	if existing != nil {
+		defer existing.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing AppendObject request: {{err}}", err)
+	}
+
+	putInput := &PutObjectInput{
+		ObjectPath:      input.ObjectPath,
+		DurabilityLevel: head.DurabilityLevel,
+		ContentType:     head.ContentType,
+		CacheControl:    head.CacheControl,
+		CORS:            head.CORS,
+		RoleTags:        head.RoleTags,
+		Metadata:        head.Metadata,
+		IfMatch:         head.ETag,
+		ObjectReader:    io.MultiReader(existing, input.ObjectReader),
+	}
+	if !head.Expires.IsZero() {
+		putInput.Expires = &head.Expires
+	}
+	if length, err := appendLength(input); err == nil && length != 0 {
+		putInput.ContentLength = head.ContentLength + length
+	}
+	if err := c.PutObject(putInput); err != nil {
+		return errwrap.Wrapf("Error executing AppendObject request: {{err}}", err)
+	}
+	return nil
+}
+
+// appendLength returns the length of the data to append, or zero if it is
+// unknown.
+func appendLength(input *AppendObjectInput) (uint64, error) {
+	if input.ContentLength != 0 {
+		return input.ContentLength, nil
+	}
+	if seeker, ok := input.ObjectReader.(io.ReadSeeker); ok {
+		return remainingLength(seeker)
+	}
+	return 0, nil
+}
+
+// readAppendSource opens the data of the object at objectPath, failing with
+// a precondition error if its ETag is no longer etag.
+func (c *Client) readAppendSource(objectPath, etag string) (io.ReadCloser, error) {
+	headers := &http.Header{}
+	headers.Set("If-Match", etag)
+	headers.Set("Accept-Encoding", "identity")
+
+	respBody, _, err := c.executeRequest(http.MethodGet, c.absoluteObjectPath(objectPath), nil, headers, nil)
+	return respBody, err
+}
+
+// appendParts replaces the object with a multipart upload of the existing
+// object followed by the new data. Manta evaluates conditional headers given
+// when an upload is created against the target object when the upload is
+// committed, and checks that each part still has the ETag it is committed
+// with, so the commit only succeeds if the object is still the version which
+// was read.
+func (c *Client) appendParts(input *AppendObjectInput, head *HeadObjectOutput) error {
+	headers := &http.Header{}
+	headers.Set("If-Match", head.ETag)
+	var expires *time.Time
+	if !head.Expires.IsZero() {
+		expires = &head.Expires
+	}
+	setCacheHeaders(headers, head.CacheControl, expires)
+	setCORSHeaders(headers, head.CORS)
+	setRoleTagHeader(headers, head.RoleTags)
+
+	uploadHeaders := map[string]string{}
+	for key := range *headers {
+		uploadHeaders[key] = headers.Get(key)
+	}
+
+	upload, err := c.CreateMpuUpload(&CreateMpuUploadInput{
+		ObjectPath:      input.ObjectPath,
+		DurabilityLevel: head.DurabilityLevel,
+		ContentType:     head.ContentType,
+		Metadata:        head.Metadata,
+		Headers:         uploadHeaders,
+	})
+	if err != nil {
+		return err
+	}
+
+	abort := func(err error) error {
+		c.AbortUpload(&AbortUploadInput{
+			ID: upload.ID,
+		})
+		return err
+	}
+
+	first := head.ETag
+	err = c.linkPart(upload.ID, 0, c.absoluteObjectPath(input.ObjectPath))
+	if err != nil {
+		if !IsSnaplinksDisabledError(err) {
+			return abort(err)
+		}
+		if first, err = c.copyAppendSource(upload.ID, input.ObjectPath, head); err != nil {
+			return abort(err)
+		}
+	}
+
+	second, err := c.UploadPart(&UploadPartInput{
+		ID:            upload.ID,
+		PartNumber:    1,
+		ContentLength: input.ContentLength,
+		ObjectReader:  input.ObjectReader,
+	})
+	if err != nil {
+		return abort(err)
+	}
+
+	err = c.CommitUpload(&CommitUploadInput{
+		ID:    upload.ID,
+		Parts: []string{first, second.ETag},
+	})
+	if err != nil {
+		return abort(err)
+	}
+	return nil
+}
+
+// copyAppendSource uploads the data of the existing object as the first part
+// of the multipart upload with the given ID, for accounts on which SnapLinks
+// are disabled, returning the ETag of the part.
+func (c *Client) copyAppendSource(id, objectPath string, head *HeadObjectOutput) (string, error) {
+	existing, err := c.readAppendSource(objectPath, head.ETag)
+	if existing != nil {
+		defer existing.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	part, err := c.UploadPart(&UploadPartInput{
+		ID:            id,
+		PartNumber:    0,
+		ContentLength: head.ContentLength,
+		ObjectReader:  existing,
+	})
+	if err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
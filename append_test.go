@@ -0,0 +1,155 @@
+package manta
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testAppendUpload records the multipart upload made by an append.
+type testAppendUpload struct {
+	lock      sync.Mutex
+	headers   map[string]string
+	linked    bool
+	committed []string
+}
+
+// handleTestAppendUpload serves a multipart upload, accepting parts created
+// as SnapLinks only if links is set.
+func handleTestAppendUpload(s *testServer, links bool) *testAppendUpload {
+	upload := &testAppendUpload{}
+	prefix := "/" + testAccount + "/uploads"
+	s.handle("uploads", func(w http.ResponseWriter, r *http.Request) {
+		upload.lock.Lock()
+		defer upload.lock.Unlock()
+
+		rest := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case r.Method == http.MethodPost && rest == "":
+			var body struct {
+				Headers map[string]string `json:"headers"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			upload.headers = body.Headers
+			json.NewEncoder(w).Encode(&CreateMpuUploadOutput{
+				ID: "append",
+			})
+		case r.Method == http.MethodPut && r.Header.Get("Location") != "":
+			if !links {
+				writeTestError(w, http.StatusForbidden, "SnaplinksDisabledError")
+				return
+			}
+			upload.linked = true
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPut:
+			io.Copy(ioutil.Discard, r.Body)
+			w.Header().Set("Etag", "uploaded")
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && strings.HasSuffix(rest, "/commit"):
+			var body struct {
+				Parts []string `json:"parts"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			upload.committed = body.Parts
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return upload
+}
+
+func TestAppendObjectParts(t *testing.T) {
+	cases := []struct {
+		name    string
+		links   bool
+		streams int
+	}{
+		{name: "linked", links: true, streams: 0},
+		{name: "copied", links: false, streams: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			etag := s.put("object", strings.Repeat("a", MpuMinPartSize), http.Header{
+				"Content-Type":  []string{"text/plain"},
+				"Cache-Control": []string{"max-age=60"},
+				"Role-Tag":      []string{"readers"},
+				"M-Owner":       []string{"test"},
+			})
+			upload := handleTestAppendUpload(s, tc.links)
+
+			err := client.AppendObject(&AppendObjectInput{
+				ObjectPath:   "object",
+				ObjectReader: strings.NewReader("b"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			upload.lock.Lock()
+			defer upload.lock.Unlock()
+			if upload.linked != tc.links {
+				t.Errorf("expected linked to be %t", tc.links)
+			}
+			expected := []string{etag, "uploaded"}
+			if !tc.links {
+				expected = []string{"uploaded", "uploaded"}
+			}
+			if strings.Join(upload.committed, ",") != strings.Join(expected, ",") {
+				t.Errorf("expected %v committed, got %v", expected, upload.committed)
+			}
+			for key, value := range map[string]string{
+				"if-match":      etag,
+				"content-type":  "text/plain",
+				"cache-control": "max-age=60",
+				"role-tag":      "readers",
+				"m-owner":       "test",
+			} {
+				if upload.headers[key] != value {
+					t.Errorf("expected %s %q, got %q", key, value, upload.headers[key])
+				}
+			}
+			if streams := s.countRequests("GET stor/object"); streams != tc.streams {
+				t.Errorf("expected the object to be streamed %d times, got %d", tc.streams, streams)
+			}
+		})
+	}
+}
+
+func TestAppendObjectSmall(t *testing.T) {
+	s, client := newTestServer(t)
+	s.put("object", "a", http.Header{
+		"Cache-Control": []string{"max-age=60"},
+		"Role-Tag":      []string{"readers"},
+		"M-Owner":       []string{"test"},
+	})
+
+	err := client.AppendObject(&AppendObjectInput{
+		ObjectPath:   "object",
+		ObjectReader: strings.NewReader("b"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := client.GetObject(&GetObjectInput{ObjectPath: "object"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.ObjectReader.Close()
+	if data := readTestBody(t, output.ObjectReader); data != "ab" {
+		t.Errorf("expected %q, got %q", "ab", data)
+	}
+	if output.CacheControl != "max-age=60" || strings.Join(output.RoleTags, ",") != "readers" {
+		t.Errorf("headers not kept: cache control %q, role tags %v", output.CacheControl, output.RoleTags)
+	}
+	if output.Metadata["owner"] != "test" {
+		t.Errorf("metadata not kept: %v", output.Metadata)
+	}
+}
@@ -0,0 +1,123 @@
+package manta
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// PutBucketObjectMetadataInput represents parameters to a
+// PutBucketObjectMetadata operation. Metadata keys and role tags are handled
+// in the same way as for PutBucketObject. If IfMatch is set, the metadata is
+// only replaced if the object still has that ETag.
+type PutBucketObjectMetadataInput struct {
+	BucketName  string
+	ObjectName  string
+	ContentType string
+	RoleTags    []string
+	Metadata    map[string]string
+	IfMatch     string
+}
+
+// PutBucketObjectMetadata replaces the metadata of an object in a bucket
+// without uploading its data again. As for PutObjectMetadata, this replaces
+// the complete set of metadata, so any item not given is removed; use
+// UpdateBucketObjectMetadata to change individual items. Buckets must be
+// enabled for the client.
+func (c *Client) PutBucketObjectMetadata(input *PutBucketObjectMetadataInput) error {
+	if !c.enableBuckets {
+		return errBucketsDisabled
+	}
+
+	headers := &http.Header{}
+	if input.ContentType != "" {
+		headers.Set("Content-Type", input.ContentType)
+	}
+	setRoleTagHeader(headers, input.RoleTags)
+	setMetadataHeaders(headers, input.Metadata)
+	setConditionalHeaders(headers, input.IfMatch, "", nil, nil)
+
+	path := c.bucketObjectPath(input.BucketName, input.ObjectName) + "/metadata"
+	respBody, _, err := c.executeRequest(http.MethodPut, path, nil, headers, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing PutBucketObjectMetadata request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// UpdateBucketObjectMetadataInput represents parameters to an
+// UpdateBucketObjectMetadata operation. SetMetadata holds the metadata items
+// to add or change and RemoveMetadata the keys of those to remove, with or
+// without the "m-" prefix. If RoleTags is not nil, it replaces the role tags
+// of the object. Retries is as for UpdateObjectInput.
+type UpdateBucketObjectMetadataInput struct {
+	BucketName     string
+	ObjectName     string
+	SetMetadata    map[string]string
+	RemoveMetadata []string
+	RoleTags       []string
+	Retries        int
+}
+
+// UpdateBucketObjectMetadata changes individual metadata items or the role
+// tags of an object in a bucket, leaving the rest of its metadata and its
+// data as they are. The current metadata is read and the changed set is
+// written on the condition that the object is unchanged, and the update is
+// retried if that condition fails. Buckets must be enabled for the client.
+func (c *Client) UpdateBucketObjectMetadata(input *UpdateBucketObjectMetadataInput) error {
+	retries := input.Retries
+	if retries <= 0 {
+		retries = DefaultUpdateObjectRetries
+	}
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		err = c.updateBucketObjectMetadata(input)
+		if err == nil || !IsPreconditionFailedError(err) {
+			break
+		}
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing UpdateBucketObjectMetadata request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// updateBucketObjectMetadata makes a single attempt at an update.
+func (c *Client) updateBucketObjectMetadata(input *UpdateBucketObjectMetadataInput) error {
+	head, err := c.HeadBucketObject(&HeadBucketObjectInput{
+		BucketName: input.BucketName,
+		ObjectName: input.ObjectName,
+	})
+	if err != nil {
+		return err
+	}
+
+	metadata := head.Metadata
+	for _, key := range input.RemoveMetadata {
+		delete(metadata, strings.TrimPrefix(strings.ToLower(key), metadataHeaderPrefix))
+	}
+	for key, value := range input.SetMetadata {
+		metadata[strings.TrimPrefix(strings.ToLower(key), metadataHeaderPrefix)] = value
+	}
+
+	roleTags := head.RoleTags
+	if input.RoleTags != nil {
+		roleTags = input.RoleTags
+	}
+
+	return c.PutBucketObjectMetadata(&PutBucketObjectMetadataInput{
+		BucketName:  input.BucketName,
+		ObjectName:  input.ObjectName,
+		ContentType: head.ContentType,
+		RoleTags:    roleTags,
+		Metadata:    metadata,
+		IfMatch:     head.ETag,
+	})
+}
@@ -0,0 +1,335 @@
+package manta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	BucketEntryTypeObject = "bucketobject"
+	BucketEntryTypeGroup  = "group"
+)
+
+// BucketObject represents an entry listed by ListBucketObjects. Type is
+// BucketEntryTypeObject for an object, or BucketEntryTypeGroup for a group
+// of objects whose names share a prefix up to the Delimiter of the listing,
+// in which case Name is that prefix and the other fields are empty.
+type BucketObject struct {
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	ETag         string    `json:"etag"`
+	Size         uint64    `json:"size"`
+	ContentType  string    `json:"contentType"`
+	ContentMD5   string    `json:"contentMD5"`
+	ModifiedTime time.Time `json:"mtime"`
+}
+
+// bucketObjectPath returns the path of the object named objectName in the
+// bucket named bucketName.
+func (c *Client) bucketObjectPath(bucketName, objectName string) string {
+	return fmt.Sprintf("%s/objects/%s", c.bucketPath(bucketName), objectName)
+}
+
+// PutBucketObjectInput represents parameters to a PutBucketObject
+// operation. The fields have the meanings of the PutObjectInput fields of
+// the same names. If ObjectReader is an io.ReadSeeker, the upload is
+// retried on failure.
+type PutBucketObjectInput struct {
+	BucketName      string
+	ObjectName      string
+	DurabilityLevel uint64
+	ContentType     string
+	ContentMD5      string
+	ContentLength   uint64
+	RoleTags        []string
+	Metadata        map[string]string
+	IfMatch         string
+	IfNoneMatch     string
+	ObjectReader    io.Reader
+}
+
+// PutBucketObject uploads an object to a bucket, replacing any object of
+// the same name. Buckets must be enabled for the client.
+func (c *Client) PutBucketObject(input *PutBucketObjectInput) error {
+	if !c.enableBuckets {
+		return errBucketsDisabled
+	}
+
+	headers := &http.Header{}
+	if input.DurabilityLevel != 0 {
+		headers.Set("Durability-Level", strconv.FormatUint(input.DurabilityLevel, 10))
+	}
+	if input.ContentType != "" {
+		headers.Set("Content-Type", input.ContentType)
+	}
+	if input.ContentMD5 != "" {
+		headers.Set("Content-MD5", input.ContentMD5)
+	}
+	if input.ContentLength != 0 {
+		headers.Set("Content-Length", strconv.FormatUint(input.ContentLength, 10))
+	}
+	setRoleTagHeader(headers, input.RoleTags)
+	setMetadataHeaders(headers, input.Metadata)
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, nil, nil)
+
+	path := c.bucketObjectPath(input.BucketName, input.ObjectName)
+	respBody, _, err := c.executeRequestReader(http.MethodPut, path, nil, headers, input.ObjectReader)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing PutBucketObject request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// GetBucketObjectInput represents parameters to a GetBucketObject
+// operation.
+type GetBucketObjectInput struct {
+	BucketName        string
+	ObjectName        string
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
+}
+
+// GetBucketObjectOutput contains the outputs for a GetBucketObject
+// operation. It is your responsibility to ensure that the io.ReadCloser
+// ObjectReader is closed. Metadata is keyed in the same way as for
+// GetObjectOutput.
+type GetBucketObjectOutput struct {
+	ContentLength   uint64
+	ContentType     string
+	LastModified    time.Time
+	ContentMD5      string
+	ETag            string
+	DurabilityLevel uint64
+	RoleTags        []string
+	Metadata        map[string]string
+	ObjectReader    io.ReadCloser
+}
+
+// GetBucketObject retrieves an object from a bucket. Buckets must be
+// enabled for the client.
+func (c *Client) GetBucketObject(input *GetBucketObjectInput) (*GetBucketObjectOutput, error) {
+	if !c.enableBuckets {
+		return nil, errBucketsDisabled
+	}
+
+	headers := &http.Header{}
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	headers.Set("Accept-Encoding", "identity")
+
+	path := c.bucketObjectPath(input.BucketName, input.ObjectName)
+	respBody, respHeaders, err := c.executeRequest(http.MethodGet, path, nil, headers, nil)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing GetBucketObject request: {{err}}", err)
+	}
+
+	head := parseBucketObjectHeaders(respHeaders)
+	return &GetBucketObjectOutput{
+		ContentLength:   head.ContentLength,
+		ContentType:     head.ContentType,
+		LastModified:    head.LastModified,
+		ContentMD5:      head.ContentMD5,
+		ETag:            head.ETag,
+		DurabilityLevel: head.DurabilityLevel,
+		RoleTags:        head.RoleTags,
+		Metadata:        head.Metadata,
+		ObjectReader:    respBody,
+	}, nil
+}
+
+// HeadBucketObjectInput represents parameters to a HeadBucketObject
+// operation.
+type HeadBucketObjectInput struct {
+	BucketName        string
+	ObjectName        string
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
+}
+
+// HeadBucketObjectOutput contains the outputs for a HeadBucketObject
+// operation. Metadata is keyed in the same way as for GetObjectOutput.
+type HeadBucketObjectOutput struct {
+	ContentLength   uint64
+	ContentType     string
+	LastModified    time.Time
+	ContentMD5      string
+	ETag            string
+	DurabilityLevel uint64
+	RoleTags        []string
+	Metadata        map[string]string
+}
+
+// HeadBucketObject retrieves the metadata of an object in a bucket, without
+// downloading the object itself. Buckets must be enabled for the client.
+func (c *Client) HeadBucketObject(input *HeadBucketObjectInput) (*HeadBucketObjectOutput, error) {
+	if !c.enableBuckets {
+		return nil, errBucketsDisabled
+	}
+
+	headers := &http.Header{}
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+
+	path := c.bucketObjectPath(input.BucketName, input.ObjectName)
+	respBody, respHeaders, err := c.executeRequest(http.MethodHead, path, nil, headers, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing HeadBucketObject request: {{err}}", err)
+	}
+
+	return parseBucketObjectHeaders(respHeaders), nil
+}
+
+// parseBucketObjectHeaders returns the metadata of a bucket object from the
+// headers of a response to a GET or HEAD request for it.
+func parseBucketObjectHeaders(respHeaders http.Header) *HeadBucketObjectOutput {
+	response := &HeadBucketObjectOutput{
+		ContentType: respHeaders.Get("Content-Type"),
+		ContentMD5:  respHeaders.Get("Content-MD5"),
+		ETag:        respHeaders.Get("Etag"),
+		RoleTags:    splitHeaderList(respHeaders.Get("Role-Tag")),
+		Metadata:    parseMetadataHeaders(respHeaders),
+	}
+
+	lastModified, err := time.Parse(time.RFC1123, respHeaders.Get("Last-Modified"))
+	if err == nil {
+		response.LastModified = lastModified
+	}
+
+	contentLength, err := strconv.ParseUint(respHeaders.Get("Content-Length"), 10, 64)
+	if err == nil {
+		response.ContentLength = contentLength
+	}
+
+	durabilityLevel, err := strconv.ParseUint(respHeaders.Get("Durability-Level"), 10, 64)
+	if err == nil {
+		response.DurabilityLevel = durabilityLevel
+	}
+
+	return response
+}
+
+// DeleteBucketObjectInput represents parameters to a DeleteBucketObject
+// operation. The conditional fields are as for DeleteObjectInput.
+type DeleteBucketObjectInput struct {
+	BucketName        string
+	ObjectName        string
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
+}
+
+// DeleteBucketObject deletes an object from a bucket. Buckets must be
+// enabled for the client.
+func (c *Client) DeleteBucketObject(input *DeleteBucketObjectInput) error {
+	if !c.enableBuckets {
+		return errBucketsDisabled
+	}
+
+	headers := &http.Header{}
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+
+	path := c.bucketObjectPath(input.BucketName, input.ObjectName)
+	respBody, _, err := c.executeRequest(http.MethodDelete, path, nil, headers, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing DeleteBucketObject request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// ListBucketObjectsInput represents parameters to a ListBucketObjects
+// operation. Only objects whose names begin with Prefix are listed if it is
+// set. If Delimiter is set, objects whose names contain it after Prefix are
+// listed as a single entry of type BucketEntryTypeGroup, so that a bucket
+// may be browsed as though it had directories. Limit and Marker page
+// through the objects as for ListDirectory.
+type ListBucketObjectsInput struct {
+	BucketName string
+	Prefix     string
+	Delimiter  string
+	Limit      uint64
+	Marker     string
+}
+
+// ListBucketObjectsOutput contains the outputs of a ListBucketObjects
+// operation. ResultSetSize is the total number of entries, not only those
+// returned, if Manta reports it.
+type ListBucketObjectsOutput struct {
+	Objects       []*BucketObject
+	ResultSetSize uint64
+}
+
+// ListBucketObjects lists the objects in a bucket in order of name. Buckets
+// must be enabled for the client.
+func (c *Client) ListBucketObjects(input *ListBucketObjectsInput) (*ListBucketObjectsOutput, error) {
+	if !c.enableBuckets {
+		return nil, errBucketsDisabled
+	}
+
+	path := fmt.Sprintf("%s/objects", c.bucketPath(input.BucketName))
+	query := &url.Values{}
+	if input.Prefix != "" {
+		query.Set("prefix", input.Prefix)
+	}
+	if input.Delimiter != "" {
+		query.Set("delimiter", input.Delimiter)
+	}
+	if input.Limit != 0 {
+		query.Set("limit", strconv.FormatUint(input.Limit, 10))
+	}
+	if input.Marker != "" {
+		query.Set("marker", input.Marker)
+	}
+
+	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, query, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListBucketObjects request: {{err}}", err)
+	}
+
+	var results []*BucketObject
+	decoder := json.NewDecoder(respBody)
+	for {
+		current := &BucketObject{}
+		if err = decoder.Decode(current); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errwrap.Wrapf("Error decoding ListBucketObjects response: {{err}}", err)
+		}
+		results = append(results, current)
+	}
+
+	output := &ListBucketObjectsOutput{
+		Objects: results,
+	}
+
+	resultSetSize, err := strconv.ParseUint(respHeader.Get("Result-Set-Size"), 10, 64)
+	if err == nil {
+		output.ResultSetSize = resultSetSize
+	}
+
+	return output, nil
+}
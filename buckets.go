@@ -0,0 +1,169 @@
+package manta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// errBucketsDisabled is returned by the operations of the buckets API when
+// it has not been enabled for the client.
+var errBucketsDisabled = errors.New("The buckets API is not enabled; set EnableBuckets in ClientOptions")
+
+// Bucket represents a bucket in Manta, as listed by ListBuckets.
+type Bucket struct {
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	ModifiedTime time.Time `json:"mtime"`
+}
+
+// bucketPath returns the path of the bucket named bucketName.
+func (c *Client) bucketPath(bucketName string) string {
+	return fmt.Sprintf("/%s/buckets/%s", c.accountName, bucketName)
+}
+
+// CreateBucketInput represents parameters to a CreateBucket operation.
+type CreateBucketInput struct {
+	BucketName string
+}
+
+// CreateBucket creates a bucket. Buckets must be enabled for the client.
+func (c *Client) CreateBucket(input *CreateBucketInput) error {
+	if !c.enableBuckets {
+		return errBucketsDisabled
+	}
+
+	respBody, _, err := c.executeRequestNoEncode(http.MethodPut, c.bucketPath(input.BucketName), nil, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing CreateBucket request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// HeadBucketInput represents parameters to a HeadBucket operation.
+type HeadBucketInput struct {
+	BucketName string
+}
+
+// HeadBucket checks that a bucket exists, returning nil if it does. Since
+// the response to a HEAD request has no body, an error for a bucket which
+// does not exist carries only the status code 404. Buckets must be enabled
+// for the client.
+func (c *Client) HeadBucket(input *HeadBucketInput) error {
+	if !c.enableBuckets {
+		return errBucketsDisabled
+	}
+
+	respBody, _, err := c.executeRequestNoEncode(http.MethodHead, c.bucketPath(input.BucketName), nil, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing HeadBucket request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// DeleteBucketInput represents parameters to a DeleteBucket operation.
+type DeleteBucketInput struct {
+	BucketName string
+}
+
+// DeleteBucket deletes a bucket, which must be empty. Buckets must be
+// enabled for the client.
+func (c *Client) DeleteBucket(input *DeleteBucketInput) error {
+	if !c.enableBuckets {
+		return errBucketsDisabled
+	}
+
+	respBody, _, err := c.executeRequestNoEncode(http.MethodDelete, c.bucketPath(input.BucketName), nil, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing DeleteBucket request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// ListBucketsInput represents parameters to a ListBuckets operation. Only
+// buckets whose names begin with Prefix are listed if it is set. Limit and
+// Marker page through the buckets as for ListDirectory.
+type ListBucketsInput struct {
+	Prefix string
+	Limit  uint64
+	Marker string
+}
+
+// ListBucketsOutput contains the outputs of a ListBuckets operation.
+// ResultSetSize is the total number of buckets, not only those returned, if
+// Manta reports it.
+type ListBucketsOutput struct {
+	Buckets       []*Bucket
+	ResultSetSize uint64
+}
+
+// ListBuckets lists the buckets of the account in order of name. Buckets
+// must be enabled for the client.
+func (c *Client) ListBuckets(input *ListBucketsInput) (*ListBucketsOutput, error) {
+	if !c.enableBuckets {
+		return nil, errBucketsDisabled
+	}
+
+	path := fmt.Sprintf("/%s/buckets", c.accountName)
+	query := &url.Values{}
+	if input.Prefix != "" {
+		query.Set("prefix", input.Prefix)
+	}
+	if input.Limit != 0 {
+		query.Set("limit", strconv.FormatUint(input.Limit, 10))
+	}
+	if input.Marker != "" {
+		query.Set("marker", input.Marker)
+	}
+
+	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, query, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListBuckets request: {{err}}", err)
+	}
+
+	var results []*Bucket
+	decoder := json.NewDecoder(respBody)
+	for {
+		current := &Bucket{}
+		if err = decoder.Decode(current); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errwrap.Wrapf("Error decoding ListBuckets response: {{err}}", err)
+		}
+		results = append(results, current)
+	}
+
+	output := &ListBucketsOutput{
+		Buckets: results,
+	}
+
+	resultSetSize, err := strconv.ParseUint(respHeader.Get("Result-Set-Size"), 10, 64)
+	if err == nil {
+		output.ResultSetSize = resultSetSize
+	}
+
+	return output, nil
+}
@@ -0,0 +1,62 @@
+package manta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// contentSHA256Header is the metadata header in which PutObject stores the
+// hex encoded SHA-256 digest of an object when ComputeSHA256 is set.
+const contentSHA256Header = "m-content-sha256"
+
+// computeSHA256 returns the hex encoded SHA-256 digest of the remaining data
+// of reader, leaving its position unchanged.
+func computeSHA256(reader io.ReadSeeker) (string, error) {
+	digest, err := computeDigest(reader, sha256.New())
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest), nil
+}
+
+// verifyingReader computes the SHA-256 digest of the data read from reader,
+// and returns a *ChecksumMismatchError in place of io.EOF if it does not
+// match the expected digest.
+type verifyingReader struct {
+	reader     io.ReadCloser
+	hash       hash.Hash
+	objectPath string
+	expected   string
+}
+
+func newVerifyingReader(reader io.ReadCloser, objectPath, expected string) *verifyingReader {
+	return &verifyingReader{
+		reader:     reader,
+		hash:       sha256.New(),
+		objectPath: objectPath,
+		expected:   expected,
+	}
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		actual := hex.EncodeToString(r.hash.Sum(nil))
+		if actual != r.expected {
+			return n, &ChecksumMismatchError{
+				ObjectPath: r.objectPath,
+				Algorithm:  "SHA-256",
+				Expected:   r.expected,
+				Actual:     actual,
+			}
+		}
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.reader.Close()
+}
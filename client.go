@@ -3,12 +3,15 @@ package manta
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,11 +23,13 @@ import (
 
 // Client represents a connection to the Triton API.
 type Client struct {
-	client      *retryablehttp.Client
-	authorizer  []authentication.Signer
-	endpoint    string
-	accountName string
-	userAgent   string
+	client         *retryablehttp.Client
+	authorizer     []authentication.Signer
+	endpoint       string
+	accountName    string
+	userAgent      string
+	encryptionKeys EncryptionKeyProvider
+	enableBuckets  bool
 }
 
 type ClientOptions struct {
@@ -32,6 +37,22 @@ type ClientOptions struct {
 	AccountName string
 	UserAgent   string
 	Signers     []authentication.Signer
+
+	// EncryptionKeyProvider enables client-side encryption of objects
+	// if set. See PutObject and GetObject.
+	EncryptionKeyProvider EncryptionKeyProvider
+
+	// EnableBuckets enables the operations of the experimental buckets
+	// API, such as CreateBucket, which are only available from Manta
+	// deployments with buckets enabled. Without it, they return an error
+	// without making a request.
+	EnableBuckets bool
+
+	// Logger receives the log of requests made and retried, such as by the
+	// commands under cmd, which discard it. If it is nil, the log is
+	// written to standard error with log.LstdFlags, so clients which do
+	// not set Logger behave as they did before it was added.
+	Logger *log.Logger
 }
 
 // NewClient is used to construct a Client in order to make API
@@ -49,9 +70,14 @@ func NewClient(options *ClientOptions) (*Client, error) {
 		CheckRedirect: doNotFollowRedirects,
 	}
 
+	logger := options.Logger
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
 	retryableClient := &retryablehttp.Client{
 		HTTPClient:   httpClient,
-		Logger:       log.New(os.Stderr, "", log.LstdFlags),
+		Logger:       logger,
 		RetryWaitMin: defaultRetryWaitMin,
 		RetryWaitMax: defaultRetryWaitMax,
 		RetryMax:     defaultRetryMax,
@@ -59,10 +85,12 @@ func NewClient(options *ClientOptions) (*Client, error) {
 	}
 
 	client := &Client{
-		client:      retryableClient,
-		authorizer:  options.Signers,
-		endpoint:    strings.TrimSuffix(options.Endpoint, "/"),
-		accountName: options.AccountName,
+		client:         retryableClient,
+		authorizer:     options.Signers,
+		endpoint:       strings.TrimSuffix(options.Endpoint, "/"),
+		accountName:    options.AccountName,
+		encryptionKeys: options.EncryptionKeyProvider,
+		enableBuckets:  options.EnableBuckets,
 	}
 
 	if options.UserAgent == "" {
@@ -100,27 +128,26 @@ func (c *Client) executeRequest(method, path string, query *url.Values, headers
 	if body != nil && (headers == nil || headers.Get("Content-Type") == "") {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	if headers != nil {
-		for key, values := range *headers {
-			for _, value := range values {
-				req.Header.Set(key, value)
-			}
-		}
+	if err := c.prepareRequest(req.Request, query, headers); err != nil {
+		return nil, nil, err
 	}
 
-	dateHeader := time.Now().UTC().Format(time.RFC1123)
-	req.Header.Set("date", dateHeader)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("Error executing HTTP request: {{err}}", err)
+	}
 
-	authHeader, err := c.authorizer[0].Sign(dateHeader)
+	return c.handleResponse(resp)
+}
+
+func (c *Client) executeRequestNoEncode(method, path string, query *url.Values, headers *http.Header, body io.ReadSeeker) (io.ReadCloser, http.Header, error) {
+	req, err := retryablehttp.NewRequest(method, c.formatURL(path), body)
 	if err != nil {
-		return nil, nil, errwrap.Wrapf("Error signing HTTP request: {{err}}", err)
+		return nil, nil, errwrap.Wrapf("Error constructing HTTP request: {{err}}", err)
 	}
-	req.Header.Set("Authorization", authHeader)
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("User-Agent", "manta-go client API")
 
-	if query != nil {
-		req.URL.RawQuery = query.Encode()
+	if err := c.prepareRequest(req.Request, query, headers); err != nil {
+		return nil, nil, err
 	}
 
 	resp, err := c.client.Do(req)
@@ -128,33 +155,154 @@ func (c *Client) executeRequest(method, path string, query *url.Values, headers
 		return nil, nil, errwrap.Wrapf("Error executing HTTP request: {{err}}", err)
 	}
 
-	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
-		return resp.Body, resp.Header, nil
+	return c.handleResponse(resp)
+}
+
+// executeRequestStream sends body to the server as it is read rather than
+// buffering it first. Since a plain io.Reader cannot be rewound, the request
+// is made directly on the underlying HTTP client and is never retried. If the
+// length of body is not known, it is sent using chunked transfer encoding.
+func (c *Client) executeRequestStream(method, path string, query *url.Values, headers *http.Header, body io.Reader) (io.ReadCloser, http.Header, error) {
+	req, err := http.NewRequest(method, c.formatURL(path), body)
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("Error constructing HTTP request: {{err}}", err)
 	}
 
-	mantaError := &MantaError{
-		StatusCode: resp.StatusCode,
+	if err := c.prepareRequest(req, query, headers); err != nil {
+		return nil, nil, err
+	}
+	if req.ContentLength == 0 && req.Body != nil && req.Body != http.NoBody {
+		req.TransferEncoding = []string{"chunked"}
 	}
 
-	errorDecoder := json.NewDecoder(resp.Body)
-	if err := errorDecoder.Decode(mantaError); err != nil {
-		return nil, nil, errwrap.Wrapf("Error decoding error response: {{err}}", err)
+	resp, err := c.client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("Error executing HTTP request: {{err}}", err)
 	}
-	return nil, nil, mantaError
+
+	return c.handleResponse(resp)
 }
 
-func (c *Client) executeRequestNoEncode(method, path string, query *url.Values, headers *http.Header, body io.ReadSeeker) (io.ReadCloser, http.Header, error) {
-	req, err := retryablehttp.NewRequest(method, c.formatURL(path), body)
-	if err != nil {
-		return nil, nil, errwrap.Wrapf("Error constructing HTTP request: {{err}}", err)
+// executeRequestReader sends body without encoding it, using
+// executeRequestNoEncode if body can be rewound for retries, and
+// executeRequestStream otherwise. Retries resend body from its position
+// at the time of the call, rather than from its beginning.
+func (c *Client) executeRequestReader(method, path string, query *url.Values, headers *http.Header, body io.Reader) (io.ReadCloser, http.Header, error) {
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, errwrap.Wrapf("Error determining request body position: {{err}}", err)
+		}
+		if start != 0 {
+			seeker = &offsetReadSeeker{
+				seeker: seeker,
+				start:  start,
+			}
+		}
+		return c.executeRequestNoEncode(method, path, query, headers, seeker)
 	}
+	return c.executeRequestStream(method, path, query, headers, body)
+}
+
+// offsetReadSeeker presents the part of seeker from start onwards as if it
+// were the whole, so that rewinding it for a retry returns to start.
+type offsetReadSeeker struct {
+	seeker io.ReadSeeker
+	start  int64
+}
 
+func (r *offsetReadSeeker) Read(p []byte) (int, error) {
+	return r.seeker.Read(p)
+}
+
+func (r *offsetReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		offset += r.start
+	}
+	position, err := r.seeker.Seek(offset, whence)
+	return position - r.start, err
+}
+
+// bodyFactoryReader is an io.ReadSeeker which obtains its data by calling
+// getBody, calling it again whenever it is rewound. Seeking forward from the
+// start discards data; seeking relative to the end is not supported.
+type bodyFactoryReader struct {
+	getBody  func() (io.Reader, error)
+	body     io.Reader
+	position int64
+}
+
+func (r *bodyFactoryReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		body, err := r.getBody()
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.position += int64(n)
+	return n, err
+}
+
+// Close closes the current body, if it is an io.Closer.
+func (r *bodyFactoryReader) Close() error {
+	if closer, ok := r.body.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *bodyFactoryReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.position
+	default:
+		return r.position, errors.New("bodyFactoryReader: seeking relative to the end is not supported")
+	}
+
+	if offset == r.position {
+		return r.position, nil
+	}
+	if offset < 0 {
+		return r.position, errors.New("bodyFactoryReader: negative position")
+	}
+
+	if offset < r.position || r.body == nil {
+		r.Close()
+		body, err := r.getBody()
+		if err != nil {
+			return r.position, err
+		}
+		r.body = body
+		r.position = 0
+	}
+
+	n, err := io.CopyN(ioutil.Discard, r.body, offset-r.position)
+	r.position += n
+	return r.position, err
+}
+
+// prepareRequest copies headers and query onto req, and signs it.
+func (c *Client) prepareRequest(req *http.Request, query *url.Values, headers *http.Header) error {
 	if headers != nil {
 		for key, values := range *headers {
 			for _, value := range values {
 				req.Header.Set(key, value)
 			}
 		}
+
+		// net/http ignores a Content-Length header on outgoing requests,
+		// so it must be carried over to the request itself.
+		if contentLength := headers.Get("Content-Length"); contentLength != "" {
+			length, err := strconv.ParseInt(contentLength, 10, 64)
+			if err != nil {
+				return errwrap.Wrapf("Error parsing Content-Length: {{err}}", err)
+			}
+			req.ContentLength = length
+		}
 	}
 
 	dateHeader := time.Now().UTC().Format(time.RFC1123)
@@ -162,7 +310,7 @@ func (c *Client) executeRequestNoEncode(method, path string, query *url.Values,
 
 	authHeader, err := c.authorizer[0].Sign(dateHeader)
 	if err != nil {
-		return nil, nil, errwrap.Wrapf("Error signing HTTP request: {{err}}", err)
+		return errwrap.Wrapf("Error signing HTTP request: {{err}}", err)
 	}
 	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Accept", "*/*")
@@ -172,14 +320,16 @@ func (c *Client) executeRequestNoEncode(method, path string, query *url.Values,
 		req.URL.RawQuery = query.Encode()
 	}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, nil, errwrap.Wrapf("Error executing HTTP request: {{err}}", err)
-	}
+	return nil
+}
 
+// handleResponse returns the body and headers of a successful response, or
+// decodes the body of an unsuccessful one into a MantaError.
+func (c *Client) handleResponse(resp *http.Response) (io.ReadCloser, http.Header, error) {
 	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
 		return resp.Body, resp.Header, nil
 	}
+	defer resp.Body.Close()
 
 	mantaError := &MantaError{
 		StatusCode: resp.StatusCode,
@@ -187,6 +337,12 @@ func (c *Client) executeRequestNoEncode(method, path string, query *url.Values,
 
 	errorDecoder := json.NewDecoder(resp.Body)
 	if err := errorDecoder.Decode(mantaError); err != nil {
+		// Responses to HEAD requests never carry a body, so the status
+		// is all there is to go on.
+		if err == io.EOF {
+			mantaError.Message = http.StatusText(resp.StatusCode)
+			return nil, nil, mantaError
+		}
 		return nil, nil, errwrap.Wrapf("Error decoding error response: {{err}}", err)
 	}
 	return nil, nil, mantaError
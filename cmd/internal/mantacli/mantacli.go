@@ -0,0 +1,108 @@
+// Package mantacli holds the configuration and path handling shared by the
+// commands under cmd, which mirror the node-manta tools of the same names.
+package mantacli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jen20/manta-go"
+	"github.com/jen20/manta-go/authentication"
+)
+
+// Config holds the options common to every command. Each defaults to the
+// environment variable used by node-manta.
+type Config struct {
+	URL     string
+	Account string
+	KeyID   string
+	KeyPath string
+}
+
+// RegisterFlags adds the common options to flags, with their defaults taken
+// from the environment.
+func (c *Config) RegisterFlags(flags *flag.FlagSet) {
+	flags.StringVar(&c.URL, "url", os.Getenv("MANTA_URL"), "Manta URL (MANTA_URL)")
+	flags.StringVar(&c.Account, "account", os.Getenv("MANTA_USER"), "account name (MANTA_USER)")
+	flags.StringVar(&c.KeyID, "keyId", os.Getenv("MANTA_KEY_ID"), "SSH key fingerprint (MANTA_KEY_ID)")
+	flags.StringVar(&c.KeyPath, "key", "", "private key file, if the SSH agent is not used")
+}
+
+// NewClient is used to construct a Client from the configuration. The key
+// is taken from the SSH agent unless KeyPath is set.
+func (c *Config) NewClient() (*manta.Client, error) {
+	if c.URL == "" {
+		return nil, errors.New("MANTA_URL or -url must be set")
+	}
+	if c.Account == "" {
+		return nil, errors.New("MANTA_USER or -account must be set")
+	}
+	if c.KeyID == "" {
+		return nil, errors.New("MANTA_KEY_ID or -keyId must be set")
+	}
+
+	var signer authentication.Signer
+	if c.KeyPath != "" {
+		material, err := ioutil.ReadFile(c.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		if signer, err = authentication.NewPrivateKeySigner(c.KeyID, material, c.Account); err != nil {
+			return nil, err
+		}
+	} else {
+		agentSigner, err := authentication.NewSSHAgentSigner(c.KeyID, c.Account)
+		if err != nil {
+			return nil, err
+		}
+		signer = agentSigner
+	}
+
+	return manta.NewClient(&manta.ClientOptions{
+		Endpoint:    c.URL,
+		AccountName: c.Account,
+		UserAgent:   "manta-go " + path.Base(os.Args[0]),
+		Signers:     []authentication.Signer{signer},
+		Logger:      log.New(ioutil.Discard, "", 0),
+	})
+}
+
+// StorPath converts a Manta path given on the command line, such as
+// "~~/stor/dir" or "/account/stor/dir", to a path relative to the account's
+// /stor directory, as accepted by the client. Only paths in /stor are
+// supported.
+func (c *Config) StorPath(arg string) (string, error) {
+	full := c.FullPath(arg)
+	prefix := "/" + c.Account + "/stor"
+	if full != prefix && !strings.HasPrefix(full, prefix+"/") {
+		return "", fmt.Errorf("only paths below %s are supported", prefix)
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(full, prefix), "/"), nil
+}
+
+// FullPath expands a leading "~~" in a Manta path given on the command line
+// to the account's home directory, and cleans the result.
+func (c *Config) FullPath(arg string) string {
+	if arg == "~~" || strings.HasPrefix(arg, "~~/") {
+		arg = "/" + c.Account + strings.TrimPrefix(arg, "~~")
+	}
+	return path.Clean("/" + arg)
+}
+
+// Setup configures the log package so that errors are reported with the
+// name of the command, and sets the usage message of flags.
+func Setup(flags *flag.FlagSet, usage string) {
+	log.SetFlags(0)
+	log.SetPrefix(path.Base(os.Args[0]) + ": ")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s %s\n", path.Base(os.Args[0]), usage)
+		flags.PrintDefaults()
+	}
+}
@@ -0,0 +1,66 @@
+// Command mget downloads objects from Manta, like the node-manta command of
+// the same name. Objects are written to standard output, one after another,
+// unless an output file is given.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jen20/manta-go"
+	"github.com/jen20/manta-go/cmd/internal/mantacli"
+)
+
+func main() {
+	config := &mantacli.Config{}
+	flags := flag.NewFlagSet("mget", flag.ExitOnError)
+	config.RegisterFlags(flags)
+	output := flags.String("o", "", "file to write the object to, rather than standard output")
+	mantacli.Setup(flags, "[-o file] path...")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 || (*output != "" && flags.NArg() != 1) {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *output != "" {
+		objectPath, err := config.StorPath(flags.Arg(0))
+		if err != nil {
+			log.Fatalf("%s: %s", flags.Arg(0), err)
+		}
+		_, err = client.GetFile(&manta.GetFileInput{
+			ObjectPath: objectPath,
+			FilePath:   *output,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for _, arg := range flags.Args() {
+		objectPath, err := config.StorPath(arg)
+		if err == nil {
+			err = get(client, objectPath, os.Stdout)
+		}
+		if err != nil {
+			log.Fatalf("%s: %s", arg, err)
+		}
+	}
+}
+
+// get copies the object at objectPath to writer.
+func get(client *manta.Client, objectPath string, writer io.Writer) error {
+	_, err := client.GetObjectTo(writer, &manta.GetObjectInput{
+		ObjectPath: objectPath,
+	})
+	return err
+}
@@ -0,0 +1,423 @@
+// Command mjob creates and manages compute jobs in Manta, like the
+// node-manta command of the same name.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jen20/manta-go"
+	"github.com/jen20/manta-go/cmd/internal/mantacli"
+)
+
+const usage = `<command> [options] [args]
+
+commands:
+  create [-f manifest] [-n name] [-m exec]... [-r exec]... [-s asset]...
+         [-open] [-w] [-o]       create a job, reading inputs from standard input
+  get <job>                      show the status of a job
+  list [-s state] [-n name]      list jobs
+  cancel <job>                   cancel a job
+  watch <job>                    wait for a job to finish, showing its progress
+  addinputs [-close] <job>       add inputs to a job from standard input
+  endinput <job>                 end the input of a job
+  inputs <job>                   list the inputs of a job
+  outputs <job>                  list the outputs of a job
+  errors <job>                   list the errors of a job
+  failures <job>                 list the inputs of a job which failed
+`
+
+// command is a subcommand of mjob.
+type command func(client *manta.Client, config *mantacli.Config, args []string) error
+
+var commands = map[string]command{
+	"create":    create,
+	"get":       get,
+	"list":      list,
+	"cancel":    cancel,
+	"watch":     watch,
+	"addinputs": addInputs,
+	"endinput":  endInput,
+	"inputs":    listLines("inputs"),
+	"outputs":   listLines("outputs"),
+	"failures":  listLines("failures"),
+	"errors":    listErrors,
+}
+
+func main() {
+	config := &mantacli.Config{}
+	flags := flag.NewFlagSet("mjob", flag.ExitOnError)
+	config.RegisterFlags(flags)
+	mantacli.Setup(flags, usage)
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	run, ok := commands[flags.Arg(0)]
+	if !ok {
+		log.Printf("unknown command %q", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := run(client, config, flags.Args()[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// jobArg parses the arguments of a command which takes only a job ID.
+func jobArg(name string, args []string) (string, error) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return "", fmt.Errorf("usage: mjob %s <job>", name)
+	}
+	return flags.Arg(0), nil
+}
+
+// signalContext returns a context which is cancelled on an interrupt, so
+// that waiting for a job may be stopped.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// phaseFlag collects the -m and -r options of create, which add phases in
+// the order in which they are given.
+type phaseFlag struct {
+	phaseType string
+	phases    *[]*manta.JobPhase
+}
+
+func (p *phaseFlag) String() string {
+	return ""
+}
+
+func (p *phaseFlag) Set(value string) error {
+	*p.phases = append(*p.phases, &manta.JobPhase{
+		Type: p.phaseType,
+		Exec: value,
+	})
+	return nil
+}
+
+// listFlag collects an option which may be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func create(client *manta.Client, config *mantacli.Config, args []string) error {
+	var phases []*manta.JobPhase
+	var assets listFlag
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	manifest := flags.String("f", "", "job manifest file")
+	name := flags.String("n", "", "name of the job")
+	flags.Var(&phaseFlag{phaseType: "map", phases: &phases}, "m", "add a map phase running exec")
+	flags.Var(&phaseFlag{phaseType: "reduce", phases: &phases}, "r", "add a reduce phase running exec")
+	flags.Var(&assets, "s", "add an asset to every phase")
+	open := flags.Bool("open", false, "leave the input of the job open")
+	wait := flags.Bool("w", false, "wait for the job to finish")
+	outputs := flags.Bool("o", false, "wait for the job to finish and write its outputs")
+	flags.Parse(args)
+
+	input := &manta.CreateJobInput{}
+	if *manifest != "" {
+		file, err := os.Open(*manifest)
+		if err != nil {
+			return err
+		}
+		input, err = manta.ReadJobManifest(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	input.Phases = append(input.Phases, phases...)
+	if *name != "" {
+		input.Name = *name
+	}
+	for _, phase := range input.Phases {
+		for _, asset := range assets {
+			phase.Assets = append(phase.Assets, config.FullPath(asset))
+		}
+	}
+
+	job, err := client.CreateJob(input)
+	if err != nil {
+		return err
+	}
+	if !*outputs {
+		fmt.Println(job.JobID)
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		if err := addInputsFrom(client, config, job.JobID, os.Stdin); err != nil {
+			return err
+		}
+	}
+	if !*open {
+		if err := client.EndJobInput(&manta.EndJobInputInput{
+			JobID: job.JobID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if !*wait && !*outputs {
+		return nil
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	status, err := client.WaitForJob(ctx, &manta.WaitForJobInput{
+		JobID: job.JobID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *outputs {
+		err := client.ForEachJobOutput(&manta.GetJobOutputContentsInput{
+			JobID: job.JobID,
+		}, func(objectPath string, reader io.Reader) error {
+			_, err := io.Copy(os.Stdout, reader)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if status.Job.Cancelled {
+		return fmt.Errorf("job %s was cancelled", job.JobID)
+	}
+	if status.Job.Stats != nil && status.Job.Stats.Errors != 0 {
+		return fmt.Errorf("job %s failed with %d errors", job.JobID, status.Job.Stats.Errors)
+	}
+	return nil
+}
+
+// addInputsFrom adds the Manta paths read from reader, one per line, to a
+// job.
+func addInputsFrom(client *manta.Client, config *mantacli.Config, jobID string, reader io.Reader) error {
+	paths := make(chan string)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(paths)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				paths <- config.FullPath(line)
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	err := client.AddJobInputs(&manta.AddJobInputsInput{
+		JobID:          jobID,
+		ObjectPathChan: paths,
+	})
+	if err != nil {
+		return err
+	}
+	return <-scanErr
+}
+
+func get(client *manta.Client, config *mantacli.Config, args []string) error {
+	jobID, err := jobArg("get", args)
+	if err != nil {
+		return err
+	}
+
+	status, err := client.GetJob(&manta.GetJobInput{
+		JobID: jobID,
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(status.Job)
+}
+
+func list(client *manta.Client, config *mantacli.Config, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	state := flags.String("s", "", "list only jobs in this state")
+	name := flags.String("n", "", "list only jobs with this name")
+	flags.Parse(args)
+
+	iterator := manta.NewJobIterator(client, &manta.ListJobsInput{
+		State: *state,
+		Name:  *name,
+	})
+	for iterator.Next() {
+		fmt.Println(iterator.Job().ID)
+	}
+	return iterator.Err()
+}
+
+func cancel(client *manta.Client, config *mantacli.Config, args []string) error {
+	jobID, err := jobArg("cancel", args)
+	if err != nil {
+		return err
+	}
+
+	return client.CancelJob(&manta.CancelJobInput{
+		JobID: jobID,
+	})
+}
+
+func watch(client *manta.Client, config *mantacli.Config, args []string) error {
+	jobID, err := jobArg("watch", args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	status, err := client.WatchJob(ctx, &manta.WatchJobInput{
+		JobID: jobID,
+		Progress: func(progress *manta.JobProgress) {
+			stats := progress.Job.Stats
+			if stats == nil {
+				stats = &manta.JobStats{}
+			}
+			fmt.Fprintf(os.Stderr, "%s: %d/%d tasks done, %d outputs, %d errors\n",
+				progress.Job.State, stats.TasksDone, stats.Tasks, stats.Outputs, stats.Errors)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(status.Job)
+}
+
+func addInputs(client *manta.Client, config *mantacli.Config, args []string) error {
+	flags := flag.NewFlagSet("addinputs", flag.ExitOnError)
+	closeInput := flags.Bool("close", false, "end the input of the job afterwards")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: mjob addinputs [-close] <job>")
+	}
+	jobID := flags.Arg(0)
+
+	if err := addInputsFrom(client, config, jobID, os.Stdin); err != nil {
+		return err
+	}
+	if !*closeInput {
+		return nil
+	}
+	return client.EndJobInput(&manta.EndJobInputInput{
+		JobID: jobID,
+	})
+}
+
+func endInput(client *manta.Client, config *mantacli.Config, args []string) error {
+	jobID, err := jobArg("endinput", args)
+	if err != nil {
+		return err
+	}
+
+	return client.EndJobInput(&manta.EndJobInputInput{
+		JobID: jobID,
+	})
+}
+
+// listLines returns the command which writes one of the lists of paths of a
+// job, requesting them a page at a time.
+func listLines(name string) command {
+	return func(client *manta.Client, config *mantacli.Config, args []string) error {
+		jobID, err := jobArg(name, args)
+		if err != nil {
+			return err
+		}
+
+		const limit = 1024
+		marker := ""
+		for {
+			var page []string
+			switch name {
+			case "inputs":
+				output, err := client.ListJobInputs(&manta.ListJobInputsInput{JobID: jobID, Limit: limit, Marker: marker})
+				if err != nil {
+					return err
+				}
+				page = output.Inputs
+			case "outputs":
+				output, err := client.ListJobOutputs(&manta.ListJobOutputsInput{JobID: jobID, Limit: limit, Marker: marker})
+				if err != nil {
+					return err
+				}
+				page = output.Outputs
+			case "failures":
+				output, err := client.ListJobFailures(&manta.ListJobFailuresInput{JobID: jobID, Limit: limit, Marker: marker})
+				if err != nil {
+					return err
+				}
+				page = output.Failures
+			}
+
+			// The first item of each page after the first is the
+			// marker, which has already been written.
+			for _, line := range page {
+				if marker != "" && line == marker {
+					continue
+				}
+				fmt.Println(line)
+			}
+			if len(page) < limit {
+				return nil
+			}
+			marker = page[len(page)-1]
+		}
+	}
+}
+
+func listErrors(client *manta.Client, config *mantacli.Config, args []string) error {
+	jobID, err := jobArg("errors", args)
+	if err != nil {
+		return err
+	}
+
+	output, err := client.ListJobErrors(&manta.ListJobErrorsInput{
+		JobID: jobID,
+	})
+	if err != nil {
+		return err
+	}
+	for _, jobError := range output.Errors {
+		if err := printJSON(jobError); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printJSON writes value to standard output as indented JSON.
+func printJSON(value interface{}) error {
+	encoded, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(encoded))
+	return nil
+}
@@ -0,0 +1,133 @@
+// Command mls lists the contents of directories in Manta, like the
+// node-manta command of the same name.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/jen20/manta-go"
+	"github.com/jen20/manta-go/cmd/internal/mantacli"
+)
+
+func main() {
+	config := &mantacli.Config{}
+	flags := flag.NewFlagSet("mls", flag.ExitOnError)
+	config.RegisterFlags(flags)
+	long := flags.Bool("l", false, "use a long listing format")
+	jsonOutput := flags.Bool("j", false, "write entries as JSON, one per line")
+	reverse := flags.Bool("r", false, "list in reverse order of name")
+	mantacli.Setup(flags, "[-l] [-j] [-r] [path...]")
+	flags.Parse(os.Args[1:])
+
+	client, err := config.NewClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	args := flags.Args()
+	if len(args) == 0 {
+		args = []string{"~~/stor"}
+	}
+
+	status := 0
+	for _, arg := range args {
+		storPath, err := config.StorPath(arg)
+		if err == nil {
+			err = list(client, storPath, &listOptions{
+				long:    *long,
+				json:    *jsonOutput,
+				reverse: *reverse,
+				account: config.Account,
+			})
+		}
+		if err != nil {
+			log.Printf("%s: %s", arg, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+type listOptions struct {
+	long    bool
+	json    bool
+	reverse bool
+	account string
+}
+
+// list writes the entries of the directory at storPath, or the object
+// itself if storPath names an object.
+func list(client *manta.Client, storPath string, options *listOptions) error {
+	if storPath != "" {
+		info, err := client.GetInfo(&manta.GetInfoInput{
+			Path: storPath,
+		})
+		if err != nil {
+			return err
+		}
+		if !info.Exists {
+			return fmt.Errorf("no such file or directory")
+		}
+		if info.Type == manta.EntryTypeObject {
+			return printEntry(&manta.DirectoryEntry{
+				Name:         path.Base(storPath),
+				Type:         manta.EntryTypeObject,
+				Size:         info.ContentLength,
+				ModifiedTime: info.LastModified,
+				ETag:         info.ETag,
+			}, options)
+		}
+	}
+
+	iterator := manta.NewDirectoryIterator(client, storPath, &manta.DirectoryIteratorOptions{
+		Reverse: options.reverse,
+	})
+	for iterator.Next() {
+		if err := printEntry(iterator.Entry(), options); err != nil {
+			return err
+		}
+	}
+	return iterator.Err()
+}
+
+// printEntry writes a single entry in the format selected by options.
+func printEntry(entry *manta.DirectoryEntry, options *listOptions) error {
+	switch {
+	case options.json:
+		encoded, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(encoded))
+	case options.long:
+		mode := "-rwxr-xr-x"
+		name := entry.Name
+		if entry.Type == manta.EntryTypeDirectory {
+			mode = "drwxr-xr-x"
+			name += "/"
+		}
+		fmt.Printf("%s 1 %-8s %12d %s %s\n", mode, options.account, entry.Size, formatTime(entry.ModifiedTime), name)
+	default:
+		name := entry.Name
+		if entry.Type == manta.EntryTypeDirectory {
+			name += "/"
+		}
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// formatTime formats a modification time as ls does, showing the year
+// rather than the time for times more than six months ago.
+func formatTime(t time.Time) string {
+	if time.Since(t) > 180*24*time.Hour {
+		return t.Local().Format("Jan _2  2006")
+	}
+	return t.Local().Format("Jan _2 15:04")
+}
@@ -0,0 +1,144 @@
+// Command mput uploads a file, or standard input, to an object in Manta,
+// like the node-manta command of the same name.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jen20/manta-go"
+	"github.com/jen20/manta-go/cmd/internal/mantacli"
+)
+
+// headerFlags collects the -H options, which may be repeated.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+func main() {
+	config := &mantacli.Config{}
+	flags := flag.NewFlagSet("mput", flag.ExitOnError)
+	config.RegisterFlags(flags)
+	file := flags.String("f", "", "local file to upload, rather than standard input")
+	parents := flags.Bool("p", false, "create parent directories as needed")
+	copies := flags.Uint64("c", 0, "number of copies to store")
+	var headers headerFlags
+	flags.Var(&headers, "H", "header to store with the object, as 'name: value', which may be\n"+
+		"Content-Type, Cache-Control or a metadata header beginning m-")
+	mantacli.Setup(flags, "[-f file] [-p] [-c copies] [-H header]... path")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	arg := flags.Arg(0)
+	objectPath, err := config.StorPath(arg)
+	if err != nil {
+		log.Fatalf("%s: %s", arg, err)
+	}
+	if *file != "" && strings.HasSuffix(arg, "/") {
+		objectPath = path.Join(objectPath, filepath.Base(*file))
+	}
+	if objectPath == "" {
+		log.Fatalf("%s: cannot upload to the /stor directory itself", arg)
+	}
+
+	input := &manta.PutObjectInput{
+		ObjectPath:      objectPath,
+		DurabilityLevel: *copies,
+		Metadata:        map[string]string{},
+	}
+	for _, header := range headers {
+		if err := setHeader(input, header); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *parents {
+		if parent := path.Dir(objectPath); parent != "." {
+			if err := client.PutDirectoryRecursive(&manta.PutDirectoryInput{
+				DirectoryName: parent,
+			}); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if *file != "" {
+		if input.CacheControl != "" {
+			log.Fatal("Cache-Control cannot be set when uploading with -f")
+		}
+		err = client.PutFile(&manta.PutFileInput{
+			ObjectPath:      input.ObjectPath,
+			FilePath:        *file,
+			ContentType:     input.ContentType,
+			DurabilityLevel: input.DurabilityLevel,
+			Metadata:        input.Metadata,
+		})
+	} else {
+		input.ObjectReader = stdin()
+		err = client.PutObject(input)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// stdin returns standard input as the body of an upload. Unless it is a
+// regular file, which may be re-read if the upload is retried, it is hidden
+// behind a plain io.Reader so that it is streamed rather than sought.
+func stdin() io.Reader {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode().IsRegular() {
+		return os.Stdin
+	}
+	return struct{ io.Reader }{os.Stdin}
+}
+
+// setHeader applies a header given with -H to input.
+func setHeader(input *manta.PutObjectInput, header string) error {
+	colon := strings.Index(header, ":")
+	if colon < 0 {
+		return fmt.Errorf("invalid header %q: expected 'name: value'", header)
+	}
+	name := strings.ToLower(strings.TrimSpace(header[:colon]))
+	value := strings.TrimSpace(header[colon+1:])
+
+	switch {
+	case name == "content-type":
+		input.ContentType = value
+	case name == "cache-control":
+		input.CacheControl = value
+	case name == "durability-level":
+		level, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid durability level %q", value)
+		}
+		input.DurabilityLevel = level
+	case strings.HasPrefix(name, "m-"):
+		input.Metadata[strings.TrimPrefix(name, "m-")] = value
+	default:
+		return fmt.Errorf("unsupported header %q", name)
+	}
+	return nil
+}
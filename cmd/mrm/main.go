@@ -0,0 +1,115 @@
+// Command mrm removes objects and directories from Manta, like the
+// node-manta command of the same name.
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/jen20/manta-go"
+	"github.com/jen20/manta-go/cmd/internal/mantacli"
+)
+
+func main() {
+	config := &mantacli.Config{}
+	flags := flag.NewFlagSet("mrm", flag.ExitOnError)
+	config.RegisterFlags(flags)
+	recursive := flags.Bool("r", false, "remove directories and their contents recursively")
+	mantacli.Setup(flags, "[-r] path...")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	status := 0
+	for _, arg := range flags.Args() {
+		storPath, err := config.StorPath(arg)
+		if err == nil {
+			err = remove(client, storPath, *recursive)
+		}
+		if err != nil {
+			log.Printf("%s: %s", arg, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+// remove removes the object or directory at storPath. Directories must be
+// empty unless recursive is set.
+func remove(client *manta.Client, storPath string, recursive bool) error {
+	if storPath == "" {
+		return errors.New("cannot remove the /stor directory")
+	}
+
+	info, err := client.GetInfo(&manta.GetInfoInput{
+		Path: storPath,
+	})
+	if err != nil {
+		return err
+	}
+	if !info.Exists {
+		return errors.New("no such file or directory")
+	}
+
+	if info.Type == manta.EntryTypeObject {
+		return client.DeleteObject(&manta.DeleteObjectInput{
+			ObjectPath: storPath,
+		})
+	}
+	if recursive {
+		return removeAll(client, storPath)
+	}
+	return client.DeleteDirectory(&manta.DeleteDirectoryInput{
+		DirectoryName: storPath,
+	})
+}
+
+// removeAll removes the directory at storPath and everything below it. The
+// objects are deleted first, and then the directories from the bottom up.
+func removeAll(client *manta.Client, storPath string) error {
+	var objects, directories []string
+	err := client.Walk(storPath, func(entryPath string, entry *manta.DirectoryEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type == manta.EntryTypeDirectory {
+			directories = append(directories, entryPath)
+		} else {
+			objects = append(objects, entryPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(objects) != 0 {
+		if _, err := client.DeleteObjects(&manta.DeleteObjectsInput{
+			ObjectPaths: objects,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Walk visits directories before their contents, so deleting in
+	// reverse order removes children first.
+	for i := len(directories) - 1; i >= 0; i-- {
+		err := client.DeleteDirectory(&manta.DeleteDirectoryInput{
+			DirectoryName: directories[i],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
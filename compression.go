@@ -0,0 +1,140 @@
+package manta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Objects compressed by PutObject are marked with these metadata headers, in
+// addition to a Content-Encoding of gzip, so that GetObject can distinguish
+// them from objects which were uploaded already compressed.
+const (
+	compressTypeHeader            = "m-compress-type"
+	compressPlaintextLengthHeader = "m-compress-plaintext-content-length"
+
+	compressTypeGzip = "gzip"
+)
+
+// compressObject returns a reader of the gzip compressed form of plaintext,
+// and sets the headers describing the compression. If plaintext is an
+// io.ReadSeeker, so is the returned reader, so that the upload may be
+// retried.
+func compressObject(plaintext io.Reader, contentLength uint64, headers *http.Header) (io.Reader, error) {
+	headers.Set("Content-Encoding", compressTypeGzip)
+	headers.Set(compressTypeHeader, compressTypeGzip)
+	if contentLength != 0 {
+		headers.Set(compressPlaintextLengthHeader, strconv.FormatUint(contentLength, 10))
+	}
+
+	seeker, ok := plaintext.(io.ReadSeeker)
+	if !ok {
+		return newCompressingReader(plaintext), nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	reader := &bodyFactoryReader{
+		getBody: func() (io.Reader, error) {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return newCompressingReader(seeker), nil
+		},
+	}
+
+	return reader, nil
+}
+
+// decompressObject replaces the ObjectReader of output with a reader which
+// decompresses it, using the compression details in headers. ContentLength
+// is set to the length of the uncompressed data if it was recorded when the
+// object was uploaded, and zero otherwise.
+func decompressObject(output *GetObjectOutput, headers http.Header, ranged bool) error {
+	if compressType := headers.Get(compressTypeHeader); compressType != compressTypeGzip {
+		return fmt.Errorf("Unsupported compression type %q", compressType)
+	}
+	if ranged {
+		return ErrObjectNotSeekable
+	}
+
+	reader, err := gzip.NewReader(output.ObjectReader)
+	if err != nil {
+		return err
+	}
+
+	output.ObjectReader = &decompressingReader{
+		Reader: reader,
+		body:   output.ObjectReader,
+	}
+	output.ContentLength = 0
+	if contentLength, err := strconv.ParseUint(headers.Get(compressPlaintextLengthHeader), 10, 64); err == nil {
+		output.ContentLength = contentLength
+	}
+	output.ContentMD5 = ""
+	delete(output.Metadata, "compress-type")
+	delete(output.Metadata, "compress-plaintext-content-length")
+
+	return nil
+}
+
+// compressingReader compresses the data read from reader. Compression is
+// performed as the compressed data is read, so no goroutine is needed.
+type compressingReader struct {
+	reader io.Reader
+	writer *gzip.Writer
+	buffer bytes.Buffer
+	chunk  []byte
+	done   bool
+}
+
+func newCompressingReader(reader io.Reader) *compressingReader {
+	r := &compressingReader{
+		reader: reader,
+		chunk:  make([]byte, 32*1024),
+	}
+	r.writer = gzip.NewWriter(&r.buffer)
+	return r
+}
+
+func (r *compressingReader) Read(p []byte) (int, error) {
+	for r.buffer.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := r.reader.Read(r.chunk)
+		if n > 0 {
+			if _, err := r.writer.Write(r.chunk[:n]); err != nil {
+				return 0, err
+			}
+		}
+		if err == io.EOF {
+			r.done = true
+			if err := r.writer.Close(); err != nil {
+				return 0, err
+			}
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	return r.buffer.Read(p)
+}
+
+// decompressingReader closes both the gzip reader and the response body
+// beneath it.
+type decompressingReader struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (r *decompressingReader) Close() error {
+	r.Reader.Close()
+	return r.body.Close()
+}
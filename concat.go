@@ -0,0 +1,254 @@
+package manta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// concatJobPollInterval is the longest interval at which ConcatenateObjects
+// checks whether a concatenation job has finished.
+const concatJobPollInterval = 2 * time.Second
+
+// ConcatenateObjectsInput represents parameters to a ConcatenateObjects
+// operation. SourcePaths may be absolute or relative to the account's /stor
+// directory, as for PutSnapLinkInput; DestinationPath is relative to /stor.
+//
+// If UseJob is set, the concatenation is always performed by a compute job.
+type ConcatenateObjectsInput struct {
+	SourcePaths     []string
+	DestinationPath string
+	ContentType     string
+	DurabilityLevel uint64
+	UseJob          bool
+}
+
+// ConcatenateObjectsOutput contains the outputs of a ConcatenateObjects
+// operation. JobID is set if the concatenation was performed by a compute
+// job.
+type ConcatenateObjectsOutput struct {
+	JobID string
+}
+
+// ConcatenateObjects creates an object at DestinationPath from the data of
+// each of SourcePaths in turn, as stored. The source objects are left in
+// place.
+//
+// Where every source other than the last is at least MpuMinPartSize bytes, a
+// multipart upload is committed with one part for each source. Each part is
+// created as a SnapLink to its source, so that the object is assembled
+// within Manta; if Manta refuses to link a part, that part and those after
+// it are instead streamed through the client. Otherwise, a compute job is
+// run which concatenates the sources within Manta, and ConcatenateObjects
+// waits for it to finish.
+func (c *Client) ConcatenateObjects(input *ConcatenateObjectsInput) (*ConcatenateObjectsOutput, error) {
+	if len(input.SourcePaths) == 0 {
+		return nil, errors.New("At least one source path is required")
+	}
+
+	sourcePaths := make([]string, len(input.SourcePaths))
+	for i, sourcePath := range input.SourcePaths {
+		sourcePaths[i] = c.absoluteObjectPath(sourcePath)
+	}
+
+	if !input.UseJob && len(sourcePaths) <= MpuMaxParts {
+		sizes, etags, err := c.sourceObjects(sourcePaths)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error executing ConcatenateObjects request: {{err}}", err)
+		}
+
+		partsAllowed := true
+		for _, size := range sizes[:len(sizes)-1] {
+			if size < MpuMinPartSize {
+				partsAllowed = false
+				break
+			}
+		}
+		if partsAllowed {
+			if err := c.concatenateParts(input, sourcePaths, sizes, etags); err != nil {
+				return nil, errwrap.Wrapf("Error executing ConcatenateObjects request: {{err}}", err)
+			}
+			return &ConcatenateObjectsOutput{}, nil
+		}
+	}
+
+	jobID, err := c.concatenateJob(input, sourcePaths)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ConcatenateObjects request: {{err}}", err)
+	}
+
+	return &ConcatenateObjectsOutput{
+		JobID: jobID,
+	}, nil
+}
+
+// sourceObjects returns the size and ETag of each of the objects at the
+// given absolute paths.
+func (c *Client) sourceObjects(absolutePaths []string) ([]uint64, []string, error) {
+	sizes := make([]uint64, len(absolutePaths))
+	etags := make([]string, len(absolutePaths))
+	for i, absolutePath := range absolutePaths {
+		respBody, respHeaders, err := c.executeRequest(http.MethodHead, absolutePath, nil, nil, nil)
+		if respBody != nil {
+			respBody.Close()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		size, err := strconv.ParseUint(respHeaders.Get("Content-Length"), 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("No Content-Length for %s", absolutePath)
+		}
+		sizes[i] = size
+		etags[i] = respHeaders.Get("Etag")
+	}
+	return sizes, etags, nil
+}
+
+// concatenateParts concatenates the sources using a multipart upload with
+// one part per source, linking each source into place as a part until Manta
+// refuses to, and copying the rest.
+func (c *Client) concatenateParts(input *ConcatenateObjectsInput, sourcePaths []string, sizes []uint64, etags []string) error {
+	upload, err := c.CreateMpuUpload(&CreateMpuUploadInput{
+		ObjectPath:      input.DestinationPath,
+		ContentType:     input.ContentType,
+		DurabilityLevel: input.DurabilityLevel,
+	})
+	if err != nil {
+		return err
+	}
+
+	parts := make([]string, len(sourcePaths))
+	linking := true
+	for i, sourcePath := range sourcePaths {
+		if linking && etags[i] != "" {
+			if c.linkPart(upload.ID, uint64(i), sourcePath) == nil {
+				parts[i] = etags[i]
+				continue
+			}
+			linking = false
+		}
+
+		parts[i], err = c.copyPart(upload.ID, uint64(i), sourcePath, sizes[i])
+		if err != nil {
+			c.AbortUpload(&AbortUploadInput{
+				ID: upload.ID,
+			})
+			return err
+		}
+	}
+
+	return c.CommitUpload(&CommitUploadInput{
+		ID:    upload.ID,
+		Parts: parts,
+	})
+}
+
+// linkPart creates a part of the multipart upload with the given ID as a
+// SnapLink to the object at the absolute path sourcePath. A SnapLink shares
+// the ETag of its source, which is used to commit the part.
+func (c *Client) linkPart(id string, partNumber uint64, sourcePath string) error {
+	path := fmt.Sprintf("%s/%d", c.uploadPath(id), partNumber)
+	headers := &http.Header{}
+	headers.Set("Content-Type", "application/json; type=link")
+	headers.Set("Location", sourcePath)
+
+	respBody, _, err := c.executeRequest(http.MethodPut, path, nil, headers, nil)
+	if respBody != nil {
+		respBody.Close()
+	}
+	return err
+}
+
+// copyPart uploads the object at the absolute path sourcePath as a part of
+// the multipart upload with the given ID, returning the ETag of the part.
+func (c *Client) copyPart(id string, partNumber uint64, sourcePath string, size uint64) (string, error) {
+	headers := &http.Header{}
+	headers.Set("Accept-Encoding", "identity")
+
+	respBody, _, err := c.executeRequest(http.MethodGet, sourcePath, nil, headers, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	part, err := c.UploadPart(&UploadPartInput{
+		ID:            id,
+		PartNumber:    partNumber,
+		ContentLength: size,
+		ObjectReader:  respBody,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return part.ETag, nil
+}
+
+// concatenateJob concatenates the sources using a compute job with a single
+// reducer, which reads each source as an asset and writes the result to the
+// destination with mpipe, then waits for the job to finish.
+func (c *Client) concatenateJob(input *ConcatenateObjectsInput, sourcePaths []string) (string, error) {
+	var command []string
+	command = append(command, "cat")
+	for _, sourcePath := range sourcePaths {
+		command = append(command, shellQuote("/assets"+sourcePath))
+	}
+	command = append(command, "|", "mpipe")
+	if input.ContentType != "" {
+		command = append(command, "-H", shellQuote("content-type: "+input.ContentType))
+	}
+	if input.DurabilityLevel != 0 {
+		command = append(command, "-c", strconv.FormatUint(input.DurabilityLevel, 10))
+	}
+	command = append(command, shellQuote(c.absoluteObjectPath(input.DestinationPath)))
+
+	job, err := c.CreateJob(&CreateJobInput{
+		Name: "concatenate",
+		Phases: []*JobPhase{
+			{
+				Type:   "reduce",
+				Assets: sourcePaths,
+				Exec:   strings.Join(command, " "),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.EndJobInput(&EndJobInputInput{
+		JobID: job.JobID,
+	}); err != nil {
+		return job.JobID, err
+	}
+
+	status, err := c.WaitForJob(context.Background(), &WaitForJobInput{
+		JobID:       job.JobID,
+		MaxInterval: concatJobPollInterval,
+	})
+	if err != nil {
+		return job.JobID, err
+	}
+	if status.Job.Cancelled || status.Job.State != JobStateDone {
+		return job.JobID, fmt.Errorf("Concatenation job %s did not complete", job.JobID)
+	}
+	if status.Job.Stats != nil && status.Job.Stats.Errors != 0 {
+		return job.JobID, fmt.Errorf("Concatenation job %s failed", job.JobID)
+	}
+	return job.JobID, nil
+}
+
+// shellQuote quotes s for use as a single word in a POSIX shell command.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,94 @@
+package manta
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// handleTestConcatUpload serves the creation of a multipart upload with the
+// given ID, accepting parts created as SnapLinks only if links is set, and
+// records the ETags committed.
+func handleTestConcatUpload(s *testServer, id string, links bool) *testUpload {
+	upload := &testUpload{}
+	prefix := "/" + testAccount + "/uploads"
+	s.handle("uploads", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case r.Method == http.MethodPost && rest == "":
+			json.NewEncoder(w).Encode(&CreateMpuUploadOutput{
+				ID: id,
+			})
+		case r.Method == http.MethodPut && r.Header.Get("Location") != "":
+			if !links {
+				writeTestError(w, http.StatusBadRequest, "InvalidParameter")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPut:
+			io.Copy(ioutil.Discard, r.Body)
+			w.Header().Set("Etag", "uploaded")
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && strings.HasSuffix(rest, "/commit"):
+			var body struct {
+				Parts []string `json:"parts"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			upload.lock.Lock()
+			upload.committed = body.Parts
+			upload.lock.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return upload
+}
+
+func TestConcatenateObjectsParts(t *testing.T) {
+	cases := []struct {
+		name    string
+		links   bool
+		streams int
+	}{
+		{name: "linked", links: true, streams: 0},
+		{name: "streamed", links: false, streams: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			first := s.put("first", strings.Repeat("a", MpuMinPartSize), nil)
+			second := s.put("second", "b", nil)
+			upload := handleTestConcatUpload(s, "c0ffee", tc.links)
+
+			output, err := client.ConcatenateObjects(&ConcatenateObjectsInput{
+				SourcePaths:     []string{"first", "second"},
+				DestinationPath: "result",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output.JobID != "" {
+				t.Errorf("expected no job, got %q", output.JobID)
+			}
+
+			expected := []string{first, second}
+			if !tc.links {
+				expected = []string{"uploaded", "uploaded"}
+			}
+			upload.lock.Lock()
+			committed := upload.committed
+			upload.lock.Unlock()
+			if strings.Join(committed, ",") != strings.Join(expected, ",") {
+				t.Errorf("expected %v committed, got %v", expected, committed)
+			}
+			if streams := s.countRequests("GET stor/"); streams != tc.streams {
+				t.Errorf("expected %d sources streamed, got %d", tc.streams, streams)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package manta
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// CopyObjectInput represents parameters to a CopyObject operation.
+// SourcePath and DestinationPath are relative to the account's /stor
+// directory.
+type CopyObjectInput struct {
+	SourcePath      string
+	DestinationPath string
+
+	// AbsoluteSourcePath is a full Manta path, such as
+	// /:login/public/path/to/object, used instead of SourcePath to copy an
+	// object from outside the account's /stor directory or from another
+	// account. Only one of SourcePath and AbsoluteSourcePath may be set.
+	AbsoluteSourcePath string
+}
+
+// CopyObject copies an object. Where the source object belongs to the same
+// account, a SnapLink is created, which is instant and uses no additional
+// storage. If the source is in another account, or SnapLinks are disabled,
+// the object is instead streamed from the source and uploaded to the
+// destination, preserving its content type, durability level, caching and
+// CORS headers, role tags and metadata. The data is copied as stored:
+// objects compressed or encrypted by PutObject are not decompressed or
+// decrypted, and remain readable with the same key, while other objects
+// are not encrypted even if the client has an EncryptionKeyProvider.
+func (c *Client) CopyObject(input *CopyObjectInput) error {
+	if input.SourcePath != "" && input.AbsoluteSourcePath != "" {
+		return errors.New("CopyObject: only one of SourcePath and AbsoluteSourcePath may be set")
+	}
+	sourcePath := input.AbsoluteSourcePath
+	if sourcePath == "" {
+		sourcePath = fmt.Sprintf("/%s/stor/%s", c.accountName, input.SourcePath)
+	}
+
+	if strings.HasPrefix(sourcePath, "/"+c.accountName+"/") {
+		err := c.PutSnapLink(&PutSnapLinkInput{
+			LinkPath:   input.DestinationPath,
+			SourcePath: sourcePath,
+		})
+		if err == nil || !IsSnaplinksDisabledError(err) {
+			return err
+		}
+	}
+
+	// Stop the transport from decompressing objects stored with a
+	// Content-Encoding of gzip, so that they are copied as stored.
+	headers := &http.Header{}
+	headers.Set("Accept-Encoding", "identity")
+
+	respBody, respHeaders, err := c.executeRequest(http.MethodGet, sourcePath, nil, headers, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing CopyObject request: {{err}}", err)
+	}
+
+	putInput := &PutObjectInput{
+		ObjectPath:   input.DestinationPath,
+		ContentType:  respHeaders.Get("Content-Type"),
+		CacheControl: respHeaders.Get("Cache-Control"),
+		CORS:         parseCORSHeaders(respHeaders),
+		RoleTags:     splitHeaderList(respHeaders.Get("Role-Tag")),
+		Metadata:     parseMetadataHeaders(respHeaders),
+		Headers:      map[string]string{},
+		ObjectReader: respBody,
+	}
+	for _, header := range []string{"Content-Encoding", "Expires"} {
+		if value := respHeaders.Get(header); value != "" {
+			putInput.Headers[header] = value
+		}
+	}
+	contentLength, err := strconv.ParseUint(respHeaders.Get("Content-Length"), 10, 64)
+	if err == nil {
+		putInput.ContentLength = contentLength
+	}
+	durabilityLevel, err := strconv.ParseUint(respHeaders.Get("Durability-Level"), 10, 64)
+	if err == nil {
+		putInput.DurabilityLevel = durabilityLevel
+	}
+
+	// The data is copied as stored, so the encryption headers of an
+	// encrypted object are carried over as metadata, and a plaintext
+	// object must not be encrypted.
+	unencrypted := *c
+	unencrypted.encryptionKeys = nil
+
+	return unencrypted.PutObject(putInput)
+}
+
+// MoveObjectInput represents parameters to a MoveObject operation. Both
+// paths are relative to the account's /stor directory.
+type MoveObjectInput struct {
+	SourcePath      string
+	DestinationPath string
+}
+
+// MoveObject renames an object by copying it to DestinationPath as
+// described for CopyObject, and then deleting SourcePath. Manta has no
+// atomic rename, so if the delete fails the object will exist at both
+// paths; in that case the error returned is prefixed to indicate that
+// the copy succeeded, and the caller may retry DeleteObject alone.
+func (c *Client) MoveObject(input *MoveObjectInput) error {
+	err := c.CopyObject(&CopyObjectInput{
+		SourcePath:      input.SourcePath,
+		DestinationPath: input.DestinationPath,
+	})
+	if err != nil {
+		return errwrap.Wrapf("Error copying object for MoveObject: {{err}}", err)
+	}
+
+	err = c.DeleteObject(&DeleteObjectInput{
+		ObjectPath: input.SourcePath,
+	})
+	if err != nil {
+		return errwrap.Wrapf("Object copied, but error deleting source for MoveObject: {{err}}", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package manta
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultCopyTreeConcurrency = 10
+
+// CopyTreeInput represents parameters to a CopyTree operation. Both paths are
+// relative to the account's /stor directory. Concurrency is the number of
+// SnapLinks created at once; if it is zero, DefaultCopyTreeConcurrency is
+// used.
+type CopyTreeInput struct {
+	SourcePath      string
+	DestinationPath string
+	Concurrency     int
+}
+
+// CopyTreeOutput contains the outputs of a CopyTree operation.
+type CopyTreeOutput struct {
+	Objects     uint64
+	Directories uint64
+}
+
+// CopyTree copies the tree at SourcePath to DestinationPath by recreating
+// each directory and creating a SnapLink to each object, so that no data is
+// moved and even a large tree is copied quickly. DestinationPath and its
+// parents are created if they do not exist, and existing objects in the
+// destination are replaced. SnapLinks must be enabled for the account.
+//
+// If an error occurs, copying stops and the error is returned, leaving the
+// destination partially copied.
+func (c *Client) CopyTree(input *CopyTreeInput) (*CopyTreeOutput, error) {
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCopyTreeConcurrency
+	}
+	source := strings.Trim(path.Clean("/"+input.SourcePath), "/")
+	destination := strings.Trim(path.Clean("/"+input.DestinationPath), "/")
+
+	if destination == source || source == "" || strings.HasPrefix(destination, source+"/") {
+		return nil, errors.New("DestinationPath must not be within SourcePath for CopyTree")
+	}
+
+	output := &CopyTreeOutput{}
+
+	var lock sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		lock.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		lock.Unlock()
+	}
+	getErr := func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		return firstErr
+	}
+
+	type link struct {
+		linkPath   string
+		sourcePath string
+	}
+
+	work := make(chan link)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for l := range work {
+				err := c.PutSnapLink(&PutSnapLinkInput{
+					LinkPath:   l.linkPath,
+					SourcePath: l.sourcePath,
+				})
+				if err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+
+	// Walk visits each directory before its contents, so directories are
+	// created here, before any links within them are queued.
+	walkErr := c.Walk(source, func(entryPath string, entry *DirectoryEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := getErr(); err != nil {
+			return err
+		}
+
+		targetPath := destination
+		if entryPath != source {
+			targetPath = path.Join(destination, strings.TrimPrefix(entryPath, source+"/"))
+		}
+
+		if entry.Type == EntryTypeDirectory {
+			putDirectory := c.PutDirectory
+			if entryPath == source {
+				putDirectory = c.PutDirectoryRecursive
+			}
+			if err := putDirectory(&PutDirectoryInput{DirectoryName: targetPath}); err != nil {
+				return err
+			}
+			output.Directories++
+			return nil
+		}
+
+		if entryPath == source {
+			parent := path.Dir(targetPath)
+			if parent != "." {
+				if err := c.PutDirectoryRecursive(&PutDirectoryInput{DirectoryName: parent}); err != nil {
+					return err
+				}
+			}
+		}
+
+		work <- link{
+			linkPath:   targetPath,
+			sourcePath: entryPath,
+		}
+		output.Objects++
+		return nil
+	})
+	close(work)
+	wg.Wait()
+
+	if walkErr == nil {
+		walkErr = getErr()
+	}
+	if walkErr != nil {
+		return nil, errwrap.Wrapf("Error executing CopyTree request: {{err}}", walkErr)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,90 @@
+package manta
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultDeleteObjectsConcurrency = 10
+
+// DeleteObjectsInput represents parameters to a DeleteObjects operation.
+// Concurrency is the number of objects deleted at once; if it is zero,
+// DefaultDeleteObjectsConcurrency is used.
+type DeleteObjectsInput struct {
+	ObjectPaths []string
+	Concurrency int
+}
+
+// DeleteObjectResult holds the outcome of deleting a single object as part
+// of a DeleteObjects operation. Error is nil if the object was deleted.
+type DeleteObjectResult struct {
+	ObjectPath string
+	Error      error
+}
+
+// DeleteObjectsOutput contains the outputs of a DeleteObjects operation.
+// Results holds the result for each path, in the same order as
+// ObjectPaths.
+type DeleteObjectsOutput struct {
+	Results []*DeleteObjectResult
+}
+
+// Failed returns the results of the objects which could not be deleted.
+func (o *DeleteObjectsOutput) Failed() []*DeleteObjectResult {
+	var failed []*DeleteObjectResult
+	for _, result := range o.Results {
+		if result.Error != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// DeleteObjects deletes each of a list of objects, using a pool of
+// concurrent workers. A failure to delete one object does not prevent the
+// others from being deleted, so errors are reported per object in the
+// output. If any object could not be deleted, an error wrapping the first
+// failure is also returned, along with the output.
+func (c *Client) DeleteObjects(input *DeleteObjectsInput) (*DeleteObjectsOutput, error) {
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDeleteObjectsConcurrency
+	}
+
+	output := &DeleteObjectsOutput{
+		Results: make([]*DeleteObjectResult, len(input.ObjectPaths)),
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range work {
+				objectPath := input.ObjectPaths[index]
+				output.Results[index] = &DeleteObjectResult{
+					ObjectPath: objectPath,
+					Error: c.DeleteObject(&DeleteObjectInput{
+						ObjectPath: objectPath,
+					}),
+				}
+			}
+		}()
+	}
+
+	for index := range input.ObjectPaths {
+		work <- index
+	}
+	close(work)
+	wg.Wait()
+
+	if failed := output.Failed(); len(failed) != 0 {
+		message := fmt.Sprintf("DeleteObjects failed for %d of %d objects, including %s: {{err}}",
+			len(failed), len(output.Results), failed[0].ObjectPath)
+		return output, errwrap.Wrapf(message, failed[0].Error)
+	}
+	return output, nil
+}
@@ -6,29 +6,56 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/errwrap"
 )
 
-// DirectoryEntry represents an object or directory in Manta.
+// DirectoryEntry represents an object or directory in Manta. Type is one of
+// EntryTypeObject or EntryTypeDirectory. ETag, Size and Durability are only
+// set for objects.
 type DirectoryEntry struct {
 	ETag         string    `json:"etag"`
 	ModifiedTime time.Time `json:"mtime"`
 	Name         string    `json:"name"`
 	Size         uint64    `json:"size"`
 	Type         string    `json:"type"`
+	Durability   uint64    `json:"durability"`
 }
 
+const (
+	ListDirectorySortName  = "name"
+	ListDirectorySortMtime = "mtime"
+	ListDirectorySortNone  = "none"
+)
+
 // ListDirectoryInput represents parameters to a ListDirectory operation.
+//
+// Limit is the maximum number of entries to return, which Manta caps at
+// 1024. Entries are returned in order of name, starting from the entry named
+// Marker if it is set, so that a large directory may be listed in pages by
+// passing the name of the last entry of each page as the Marker of the next.
+// The first entry of each subsequent page is then the last entry of the
+// previous one.
+//
+// Sort may be set to one of the ListDirectorySort constants to choose the
+// order of entries, and Reverse reverses it, so that, for example, the newest
+// entries of a directory of date-named objects can be listed first. Marker
+// may only be used with the default ordering by name, in either direction.
 type ListDirectoryInput struct {
 	DirectoryName string
 	Limit         uint64
 	Marker        string
+	Sort          string
+	Reverse       bool
 }
 
 // ListDirectoryOutput contains the outputs of a ListDirectory operation.
+// ResultSetSize is the total number of entries in the directory, not only
+// those returned.
 type ListDirectoryOutput struct {
 	Entries       []*DirectoryEntry
 	ResultSetSize uint64
@@ -37,13 +64,7 @@ type ListDirectoryOutput struct {
 // ListDirectory lists the contents of a directory.
 func (c *Client) ListDirectory(input *ListDirectoryInput) (*ListDirectoryOutput, error) {
 	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.DirectoryName)
-	query := &url.Values{}
-	if input.Limit != 0 {
-		query.Set("limit", strconv.FormatUint(input.Limit, 10))
-	}
-	if input.Marker != "" {
-		query.Set("manta_path", input.Marker)
-	}
+	query := listDirectoryQuery(input)
 
 	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, query, nil, nil)
 	if respBody != nil {
@@ -54,10 +75,10 @@ func (c *Client) ListDirectory(input *ListDirectoryInput) (*ListDirectoryOutput,
 	}
 
 	var results []*DirectoryEntry
+	decoder := json.NewDecoder(respBody)
 	for {
 		current := &DirectoryEntry{}
-		decoder := json.NewDecoder(respBody)
-		if err = decoder.Decode(&current); err != nil {
+		if err = decoder.Decode(current); err != nil {
 			if err == io.EOF {
 				break
 			}
@@ -78,18 +99,79 @@ func (c *Client) ListDirectory(input *ListDirectoryInput) (*ListDirectoryOutput,
 	return output, nil
 }
 
+// listDirectoryPageSize is the number of entries requested per page when
+// listing a whole directory, which is the most Manta allows.
+const listDirectoryPageSize = 1024
+
+// listDirectoryQuery returns the query string parameters for a request to
+// list a directory.
+func listDirectoryQuery(input *ListDirectoryInput) *url.Values {
+	query := &url.Values{}
+	if input.Limit != 0 {
+		query.Set("limit", strconv.FormatUint(input.Limit, 10))
+	}
+	if input.Marker != "" {
+		query.Set("marker", input.Marker)
+	}
+	if input.Sort != "" {
+		query.Set("sort", input.Sort)
+	}
+	if input.Reverse {
+		query.Set("sort_order", "reverse")
+	}
+	return query
+}
+
+// listDirectoryAll calls fn for every entry of a directory in order of name,
+// requesting the entries a page at a time. Unlike a DirectoryIterator, each
+// page is read in full before fn is called, so no response is held open while
+// fn runs, which may take some time when walking a tree. If fn returns an
+// error, listing stops and the error is returned.
+func (c *Client) listDirectoryAll(directoryName string, fn func(*DirectoryEntry) error) error {
+	marker := ""
+	for {
+		output, err := c.ListDirectory(&ListDirectoryInput{
+			DirectoryName: directoryName,
+			Limit:         listDirectoryPageSize,
+			Marker:        marker,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range output.Entries {
+			if marker != "" && entry.Name == marker {
+				continue
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if len(output.Entries) < listDirectoryPageSize {
+			return nil
+		}
+		marker = output.Entries[len(output.Entries)-1].Name
+	}
+}
+
 // PutDirectoryInput represents parameters to a PutDirectory operation.
+// RoleTags, if set, replace the role tags of the directory.
 type PutDirectoryInput struct {
 	DirectoryName string
+	RoleTags      []string
 }
 
 // PutDirectory in the Joyent Manta Storage Service is an idempotent create-or-update
 // operation. Your private namespace starts at /:login/stor, and you can create any
-// nested set of directories or objects underneath that.
+// nested set of directories or objects underneath that. The parent directory must
+// already exist; otherwise an error for which IsDirectoryDoesNotExistError returns
+// true is returned.
 func (c *Client) PutDirectory(input *PutDirectoryInput) error {
 	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.DirectoryName)
 	headers := &http.Header{}
 	headers.Set("Content-Type", "application/json; type=directory")
+	setRoleTagHeader(headers, input.RoleTags)
 
 	respBody, _, err := c.executeRequest(http.MethodPut, path, nil, headers, nil)
 	if respBody != nil {
@@ -102,12 +184,91 @@ func (c *Client) PutDirectory(input *PutDirectoryInput) error {
 	return nil
 }
 
+// PutDirectoryRecursive creates the directory DirectoryName along with any of
+// its parent directories which do not exist, like `mmkdir -p`. Directories
+// are created from the top down, starting below the deepest directory which
+// already exists. RoleTags are applied to DirectoryName only, and not to the
+// parent directories created for it.
+func (c *Client) PutDirectoryRecursive(input *PutDirectoryInput) error {
+	directoryName := strings.Trim(path.Clean("/"+input.DirectoryName), "/")
+	if directoryName == "" {
+		return nil
+	}
+
+	err := c.PutDirectory(&PutDirectoryInput{
+		DirectoryName: directoryName,
+		RoleTags:      input.RoleTags,
+	})
+	if err == nil || !IsDirectoryDoesNotExistError(err) {
+		return err
+	}
+
+	parent := path.Dir(directoryName)
+	if parent == "." {
+		return err
+	}
+	if err := c.PutDirectoryRecursive(&PutDirectoryInput{
+		DirectoryName: parent,
+	}); err != nil {
+		return err
+	}
+
+	return c.PutDirectory(&PutDirectoryInput{
+		DirectoryName: directoryName,
+		RoleTags:      input.RoleTags,
+	})
+}
+
+// EnsureDirectoryInput represents parameters to an EnsureDirectory
+// operation. If Parents is set, any missing parent directories of
+// DirectoryName are created too.
+type EnsureDirectoryInput struct {
+	DirectoryName string
+	Parents       bool
+}
+
+// EnsureDirectory makes sure that a directory exists at DirectoryName,
+// creating it if nothing exists there and doing nothing if a directory
+// already does, so that it may be called repeatedly with the same result. If
+// an object exists at DirectoryName, a *NotDirectoryError is returned rather
+// than the object being replaced.
+func (c *Client) EnsureDirectory(input *EnsureDirectoryInput) error {
+	info, err := c.GetInfo(&GetInfoInput{
+		Path: input.DirectoryName,
+	})
+	if err != nil {
+		return errwrap.Wrapf("Error executing EnsureDirectory request: {{err}}", err)
+	}
+
+	if info.Exists {
+		if info.Type != EntryTypeDirectory {
+			return &NotDirectoryError{
+				Path: input.DirectoryName,
+			}
+		}
+		return nil
+	}
+
+	putDirectory := c.PutDirectory
+	if input.Parents {
+		putDirectory = c.PutDirectoryRecursive
+	}
+	err = putDirectory(&PutDirectoryInput{
+		DirectoryName: input.DirectoryName,
+	})
+	if err != nil {
+		return errwrap.Wrapf("Error executing EnsureDirectory request: {{err}}", err)
+	}
+	return nil
+}
+
 // DeleteDirectoryInput represents parameters to a DeleteDirectory operation.
 type DeleteDirectoryInput struct {
 	DirectoryName string
 }
 
-// DeleteDirectory deletes a directory. The directory must be empty.
+// DeleteDirectory deletes a directory. The directory must be empty; if it is
+// not, a *DirectoryNotEmptyError is returned.
 func (c *Client) DeleteDirectory(input *DeleteDirectoryInput) error {
 	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.DirectoryName)
 
@@ -116,6 +277,11 @@ func (c *Client) DeleteDirectory(input *DeleteDirectoryInput) error {
 		defer respBody.Close()
 	}
 	if err != nil {
+		if IsDirectoryNotEmptyError(err) {
+			return &DirectoryNotEmptyError{
+				DirectoryName: input.DirectoryName,
+			}
+		}
 		return errwrap.Wrapf("Error executing DeleteDirectory request: {{err}}", err)
 	}
 
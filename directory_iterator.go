@@ -0,0 +1,177 @@
+package manta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// DirectoryIterator iterates over the entries of a directory in order of
+// name, decoding each entry from the response as it is needed and requesting
+// further pages as each is exhausted, so that directories with millions of
+// entries can be processed in constant memory.
+//
+// A DirectoryIterator is used in the same way as bufio.Scanner:
+//
+//	iterator := manta.NewDirectoryIterator(client, "logs", nil)
+//	defer iterator.Close()
+//	for iterator.Next() {
+//		entry := iterator.Entry()
+//		...
+//	}
+//	if err := iterator.Err(); err != nil {
+//		...
+//	}
+type DirectoryIterator struct {
+	client        *Client
+	directoryName string
+	reverse       bool
+
+	body          io.ReadCloser
+	decoder       *json.Decoder
+	pageMarker    string
+	pageCount     int
+	requested     bool
+	marker        string
+	resultSetSize uint64
+
+	entry *DirectoryEntry
+	err   error
+	done  bool
+}
+
+// DirectoryIteratorOptions represents the configuration of a
+// DirectoryIterator. If Reverse is set, entries are returned in reverse order
+// of name. If Marker is set, iteration starts from the entry with that name,
+// which is included if it exists.
+type DirectoryIteratorOptions struct {
+	Reverse bool
+	Marker  string
+}
+
+// NewDirectoryIterator is used to construct a DirectoryIterator over the
+// directory at directoryName, relative to the account's /stor directory. If
+// options is nil, default options are used. No request is made until Next is
+// first called.
+func NewDirectoryIterator(client *Client, directoryName string, options *DirectoryIteratorOptions) *DirectoryIterator {
+	iterator := &DirectoryIterator{
+		client:        client,
+		directoryName: directoryName,
+	}
+	if options != nil {
+		iterator.reverse = options.Reverse
+		iterator.marker = options.Marker
+	}
+	return iterator
+}
+
+// Next advances the iterator to the next entry, which is then available
+// from Entry. It returns false when there are no more entries or an error
+// occurs, which is then available from Err.
+func (i *DirectoryIterator) Next() bool {
+	for {
+		if i.done || i.err != nil {
+			return false
+		}
+
+		if i.body == nil {
+			if i.err = i.requestPage(); i.err != nil {
+				return false
+			}
+		}
+
+		entry := &DirectoryEntry{}
+		if err := i.decoder.Decode(entry); err != nil {
+			i.closeBody()
+			if err != io.EOF {
+				i.err = errwrap.Wrapf("Error decoding ListDirectory response: {{err}}", err)
+				return false
+			}
+			if i.pageCount < listDirectoryPageSize {
+				i.done = true
+			}
+			continue
+		}
+
+		i.pageCount++
+		i.marker = entry.Name
+		if i.pageCount == 1 && i.pageMarker != "" && entry.Name == i.pageMarker {
+			// Each page after the first starts with the last entry
+			// of the previous page.
+			continue
+		}
+
+		i.entry = entry
+		return true
+	}
+}
+
+// requestPage requests the page of entries which follows the last entry
+// returned.
+func (i *DirectoryIterator) requestPage() error {
+	path := fmt.Sprintf("/%s/stor/%s", i.client.accountName, i.directoryName)
+	query := listDirectoryQuery(&ListDirectoryInput{
+		DirectoryName: i.directoryName,
+		Limit:         listDirectoryPageSize,
+		Marker:        i.marker,
+		Reverse:       i.reverse,
+	})
+
+	respBody, respHeader, err := i.client.executeRequest(http.MethodGet, path, query, nil, nil)
+	if err != nil {
+		if respBody != nil {
+			respBody.Close()
+		}
+		return errwrap.Wrapf("Error executing ListDirectory request: {{err}}", err)
+	}
+
+	resultSetSize, err := strconv.ParseUint(respHeader.Get("Result-Set-Size"), 10, 64)
+	if err == nil {
+		i.resultSetSize = resultSetSize
+	}
+
+	i.body = respBody
+	i.decoder = json.NewDecoder(respBody)
+	if i.requested {
+		i.pageMarker = i.marker
+	}
+	i.requested = true
+	i.pageCount = 0
+	return nil
+}
+
+func (i *DirectoryIterator) closeBody() {
+	if i.body != nil {
+		i.body.Close()
+		i.body = nil
+		i.decoder = nil
+	}
+}
+
+// Entry returns the entry most recently returned by Next.
+func (i *DirectoryIterator) Entry() *DirectoryEntry {
+	return i.entry
+}
+
+// Err returns the error which stopped iteration, if any.
+func (i *DirectoryIterator) Err() error {
+	return i.err
+}
+
+// ResultSetSize returns the total number of entries in the directory, as
+// reported by Manta with the most recent page of entries.
+func (i *DirectoryIterator) ResultSetSize() uint64 {
+	return i.resultSetSize
+}
+
+// Close releases the response currently being read. It need not be called
+// if Next has returned false.
+func (i *DirectoryIterator) Close() error {
+	i.closeBody()
+	i.done = true
+	return nil
+}
@@ -0,0 +1,30 @@
+package manta
+
+import (
+	"testing"
+)
+
+func TestPutDirectoryRecursiveRoleTags(t *testing.T) {
+	s, client := newTestServer(t)
+
+	err := client.PutDirectoryRecursive(&PutDirectoryInput{
+		DirectoryName: "a/b/c",
+		RoleTags:      []string{"readers"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for directory, expected := range map[string]string{"a": "", "a/b": "", "a/b/c": "readers"} {
+		object := s.objects[directory]
+		if object == nil || !object.directory {
+			t.Errorf("directory %s not created", directory)
+			continue
+		}
+		if roleTags := object.headers.Get("Role-Tag"); roleTags != expected {
+			t.Errorf("expected %s to have role tags %q, got %q", directory, expected, roleTags)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package manta
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	DefaultDirectoryTransferConcurrency = 5
+	DefaultDirectoryTransferRetries     = 3
+)
+
+// DirectoryTransferProgress describes the progress of an UploadDirectory or
+// DownloadDirectory operation after a file has been transferred, skipped or
+// has failed. Path is the slash separated path of the file relative to the
+// root of the transfer, and Error is set if it failed.
+type DirectoryTransferProgress struct {
+	Path  string
+	Error error
+
+	CompletedFiles uint64
+	TotalFiles     uint64
+	CompletedBytes uint64
+	TotalBytes     uint64
+}
+
+// DirectoryTransferProgressFunc is called as each file of a directory
+// transfer completes. Calls are never made concurrently, but may come from
+// any goroutine.
+type DirectoryTransferProgressFunc func(progress *DirectoryTransferProgress)
+
+// directoryTransferTracker reports the progress of a directory transfer.
+type directoryTransferTracker struct {
+	lock     sync.Mutex
+	fn       DirectoryTransferProgressFunc
+	progress DirectoryTransferProgress
+}
+
+func newDirectoryTransferTracker(fn DirectoryTransferProgressFunc, totalFiles, totalBytes uint64) *directoryTransferTracker {
+	return &directoryTransferTracker{
+		fn: fn,
+		progress: DirectoryTransferProgress{
+			TotalFiles: totalFiles,
+			TotalBytes: totalBytes,
+		},
+	}
+}
+
+// complete records that the file at relativePath, of size bytes, has been
+// dealt with.
+func (t *directoryTransferTracker) complete(relativePath string, size uint64, err error) {
+	if t.fn == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.progress.CompletedFiles++
+	t.progress.CompletedBytes += size
+	progress := t.progress
+	progress.Path = relativePath
+	progress.Error = err
+	t.fn(&progress)
+}
+
+// withRetries calls fn until it succeeds or has failed retries+1 times,
+// waiting a little longer after each failure, and returns the last error.
+func withRetries(retries int, fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// UploadDirectoryInput represents parameters to an UploadDirectory
+// operation.
+type UploadDirectoryInput struct {
+	// LocalPath is the local directory to upload.
+	LocalPath string
+
+	// Prefix is the directory to upload into, relative to the account's
+	// /stor directory. It is created if it does not exist.
+	Prefix string
+
+	// Concurrency is the number of files uploaded at once. If it is zero,
+	// DefaultDirectoryTransferConcurrency is used.
+	Concurrency int
+
+	// Retries is the number of times the upload of a file is retried after
+	// it fails. If it is zero, DefaultDirectoryTransferRetries is used; to
+	// disable retries, set it to a negative number.
+	Retries int
+
+	// Progress, if set, is called as each file is uploaded.
+	Progress DirectoryTransferProgressFunc
+}
+
+// UploadDirectory uploads every file below LocalPath to the same relative
+// path below Prefix, using a pool of concurrent workers. Directories are
+// created first, from the top down, and each file is then uploaded with
+// PutFile, so that its modification time is recorded. Unlike SyncUp, every
+// file is uploaded whether or not it has changed.
+//
+// A failure to upload one file does not prevent the others from being
+// uploaded. Failures are recorded in the output, and an error summarising
+// them is returned if any path failed.
+func (c *Client) UploadDirectory(input *UploadDirectoryInput) (*SyncOutput, error) {
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDirectoryTransferConcurrency
+	}
+	retries := input.Retries
+	if retries == 0 {
+		retries = DefaultDirectoryTransferRetries
+	}
+	prefix := strings.Trim(path.Clean("/"+input.Prefix), "/")
+
+	var files, directories []string
+	sizes := map[string]uint64{}
+	var totalBytes uint64
+	err := filepath.Walk(input.LocalPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(input.LocalPath, filePath)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			return nil
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		switch {
+		case info.IsDir():
+			directories = append(directories, relativePath)
+		case info.Mode().IsRegular():
+			files = append(files, relativePath)
+			sizes[relativePath] = uint64(info.Size())
+			totalBytes += uint64(info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error reading local directory for UploadDirectory: {{err}}", err)
+	}
+
+	if prefix != "" {
+		if err := c.PutDirectoryRecursive(&PutDirectoryInput{
+			DirectoryName: prefix,
+		}); err != nil {
+			return nil, errwrap.Wrapf("Error creating remote directory for UploadDirectory: {{err}}", err)
+		}
+	}
+
+	recorder := &syncRecorder{}
+	tracker := newDirectoryTransferTracker(input.Progress, uint64(len(files)), totalBytes)
+
+	// Sorting puts each directory after its parent, so that directories
+	// are created from the top down. Files below a directory which could
+	// not be created are failed without being attempted.
+	sort.Strings(directories)
+	failedDirectories := map[string]bool{}
+	for _, relativePath := range directories {
+		if failedDirectories[path.Dir(relativePath)] {
+			failedDirectories[relativePath] = true
+			continue
+		}
+		err := withRetries(retries, func() error {
+			return c.PutDirectory(&PutDirectoryInput{
+				DirectoryName: path.Join(prefix, relativePath),
+			})
+		})
+		if err != nil {
+			failedDirectories[relativePath] = true
+			recorder.fail(relativePath, err)
+		}
+	}
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for relativePath := range work {
+				err := withRetries(retries, func() error {
+					return c.PutFile(&PutFileInput{
+						ObjectPath: path.Join(prefix, relativePath),
+						FilePath:   filepath.Join(input.LocalPath, filepath.FromSlash(relativePath)),
+					})
+				})
+				if err != nil {
+					recorder.fail(relativePath, err)
+				} else {
+					recorder.record(&recorder.output.Transferred, relativePath)
+				}
+				tracker.complete(relativePath, sizes[relativePath], err)
+			}
+		}()
+	}
+	for _, relativePath := range files {
+		if failedDirectories[path.Dir(relativePath)] {
+			err := errors.New("parent directory could not be created")
+			recorder.fail(relativePath, err)
+			tracker.complete(relativePath, sizes[relativePath], err)
+			continue
+		}
+		work <- relativePath
+	}
+	close(work)
+	wg.Wait()
+
+	return recorder.result("UploadDirectory")
+}
+
+// DownloadDirectoryInput represents parameters to a DownloadDirectory
+// operation. The fields have the same meaning as for UploadDirectoryInput,
+// with the roles of the local and remote trees exchanged.
+type DownloadDirectoryInput struct {
+	Prefix      string
+	LocalPath   string
+	Concurrency int
+	Retries     int
+	Progress    DirectoryTransferProgressFunc
+}
+
+// DownloadDirectory downloads every object below Prefix to the same relative
+// path below LocalPath, using a pool of concurrent workers. Local directories
+// are created as needed, and the modification time of each file is set to
+// that recorded for the object by PutFile, or otherwise the time the object
+// was last modified.
+//
+// DownloadDirectory may be run again after it is interrupted or fails, and
+// resumes where it left off. Files which are already complete, judged as for
+// SyncDown, are skipped and recorded as such in the output. Each object is
+// downloaded to a partial file alongside its destination, named for the ETag
+// of the object, which is renamed into place once complete; if a partial
+// file for the same version of the object exists, only the remainder of the
+// object is requested. Objects encrypted by the client cannot be resumed and
+// are downloaded again from the start.
+//
+// Failures are recorded in the output, and an error summarising them is
+// returned if any path failed.
+func (c *Client) DownloadDirectory(input *DownloadDirectoryInput) (*SyncOutput, error) {
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDirectoryTransferConcurrency
+	}
+	retries := input.Retries
+	if retries == 0 {
+		retries = DefaultDirectoryTransferRetries
+	}
+	prefix := strings.Trim(path.Clean("/"+input.Prefix), "/")
+
+	remoteObjects, remoteDirectories, err := c.remoteTree(prefix, &syncFilter{})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error listing remote directory for DownloadDirectory: {{err}}", err)
+	}
+
+	if err := os.MkdirAll(input.LocalPath, 0755); err != nil {
+		return nil, errwrap.Wrapf("Error creating local directory for DownloadDirectory: {{err}}", err)
+	}
+
+	recorder := &syncRecorder{}
+
+	var relativePaths []string
+	var totalBytes uint64
+	for relativePath, entry := range remoteObjects {
+		relativePaths = append(relativePaths, relativePath)
+		totalBytes += entry.Size
+	}
+	sort.Strings(relativePaths)
+	tracker := newDirectoryTransferTracker(input.Progress, uint64(len(relativePaths)), totalBytes)
+
+	for relativePath := range remoteDirectories {
+		if err := os.MkdirAll(filepath.Join(input.LocalPath, filepath.FromSlash(relativePath)), 0755); err != nil {
+			recorder.fail(relativePath, err)
+		}
+	}
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for relativePath := range work {
+				entry := remoteObjects[relativePath]
+				objectPath := path.Join(prefix, relativePath)
+				filePath := filepath.Join(input.LocalPath, filepath.FromSlash(relativePath))
+
+				skipped := false
+				err := withRetries(retries, func() error {
+					info, err := os.Stat(filePath)
+					if err != nil && !os.IsNotExist(err) {
+						return err
+					}
+					if err == nil {
+						upToDate, err := c.syncDownToDate(objectPath, info)
+						if err != nil {
+							return err
+						}
+						if upToDate {
+							skipped = true
+							return nil
+						}
+					}
+					return c.downloadFile(objectPath, filePath, entry)
+				})
+
+				switch {
+				case err != nil:
+					recorder.fail(relativePath, err)
+				case skipped:
+					recorder.record(&recorder.output.Skipped, relativePath)
+				default:
+					recorder.record(&recorder.output.Transferred, relativePath)
+				}
+				tracker.complete(relativePath, entry.Size, err)
+			}
+		}()
+	}
+	for _, relativePath := range relativePaths {
+		work <- relativePath
+	}
+	close(work)
+	wg.Wait()
+
+	return recorder.result("DownloadDirectory")
+}
+
+// downloadFile downloads the version of the object at objectPath described
+// by entry to filePath, resuming from a partial file left by an earlier
+// attempt if there is one.
+func (c *Client) downloadFile(objectPath, filePath string, entry *DirectoryEntry) error {
+	dir, name := filepath.Split(filePath)
+	partialPath := filepath.Join(dir, "."+name+"."+entry.ETag+".partial")
+
+	// Partial files for other versions of the object cannot be resumed.
+	// ETags contain no dots, which distinguishes the partial files of this
+	// file from those of others whose names it prefixes. The directory is
+	// listed rather than globbed, since name may contain glob syntax.
+	listDir := dir
+	if listDir == "" {
+		listDir = "."
+	}
+	if files, err := ioutil.ReadDir(listDir); err == nil {
+		for _, file := range files {
+			stale := file.Name()
+			if !strings.HasPrefix(stale, "."+name+".") || !strings.HasSuffix(stale, ".partial") {
+				continue
+			}
+			etag := strings.TrimSuffix(strings.TrimPrefix(stale, "."+name+"."), ".partial")
+			if etag != entry.ETag && etag != "" && !strings.Contains(etag, ".") {
+				os.Remove(filepath.Join(dir, stale))
+			}
+		}
+	}
+
+	file, err := os.OpenFile(partialPath, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	object, err := c.getObjectFrom(objectPath, entry, uint64(offset))
+	if offset != 0 && partialNotResumable(err) {
+		// The partial file could not be resumed, so start again. Other
+		// errors are returned, keeping the partial file for a retry.
+		if err = file.Truncate(0); err != nil {
+			return err
+		}
+		if offset, err = file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		object, err = c.getObjectFrom(objectPath, entry, 0)
+	}
+	if err != nil {
+		return err
+	}
+	defer object.ObjectReader.Close()
+
+	if _, err := io.Copy(file, object.ObjectReader); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	modifiedTime := object.LastModified
+	if value, ok := object.Metadata[fileModifiedTimeMetadataKey]; ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			modifiedTime = parsed
+		}
+	}
+	if !modifiedTime.IsZero() {
+		if err := os.Chtimes(partialPath, modifiedTime, modifiedTime); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(partialPath, filePath)
+}
+
+// errPartialFileTooLong is returned by getObjectFrom when a partial file
+// is at least as long as the object it is a download of.
+var errPartialFileTooLong = errors.New("Partial file is not shorter than the object")
+
+// partialNotResumable returns true if err, returned by getObjectFrom, shows
+// that the partial file cannot be resumed: the object has been replaced,
+// the partial file is too long, or the object cannot be read by range.
+func partialNotResumable(err error) bool {
+	return err == errPartialFileTooLong ||
+		IsPreconditionFailedError(err) ||
+		hasStatusCode(err, http.StatusRequestedRangeNotSatisfiable) ||
+		errwrap.Contains(err, ErrObjectNotSeekable.Error())
+}
+
+// getObjectFrom gets the version of the object at objectPath described by
+// entry, starting from offset.
+func (c *Client) getObjectFrom(objectPath string, entry *DirectoryEntry, offset uint64) (*GetObjectOutput, error) {
+	if offset != 0 && offset >= entry.Size {
+		return nil, errPartialFileTooLong
+	}
+	return c.GetObject(&GetObjectInput{
+		ObjectPath:  objectPath,
+		IfMatch:     entry.ETag,
+		RangeOffset: offset,
+	})
+}
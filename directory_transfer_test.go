@@ -0,0 +1,91 @@
+package manta
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadFilePartials(t *testing.T) {
+	data := "0123456789"
+
+	cases := []struct {
+		name     string
+		partial  string
+		stale    bool
+		broken   bool
+		expected string
+		kept     bool
+	}{
+		{name: "fresh", expected: data},
+		{name: "resumed", partial: "01234", expected: data},
+		{name: "partial too long", partial: data + "extra", expected: data},
+		{name: "stale partial removed", stale: true, expected: data},
+		{name: "partial kept on error", partial: "01234", broken: true, kept: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			etag := s.put("a[1]", data, nil)
+			if tc.broken {
+				s.handle("stor/a[1]", func(w http.ResponseWriter, r *http.Request) {
+					writeTestError(w, http.StatusForbidden, "AuthorizationFailed")
+				})
+			}
+
+			dir, err := ioutil.TempDir("", "manta-download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			filePath := filepath.Join(dir, "a[1]")
+			partialPath := filepath.Join(dir, ".a[1]."+etag+".partial")
+			stalePath := filepath.Join(dir, ".a[1].etag-old.partial")
+			otherPath := filepath.Join(dir, ".a[1].b.etag-old.partial")
+			if tc.partial != "" {
+				ioutil.WriteFile(partialPath, []byte(tc.partial), 0644)
+			}
+			if tc.stale {
+				ioutil.WriteFile(stalePath, []byte("old"), 0644)
+				ioutil.WriteFile(otherPath, []byte("other"), 0644)
+			}
+
+			err = client.downloadFile("a[1]", filePath, &DirectoryEntry{
+				Name: "a[1]",
+				ETag: etag,
+				Size: uint64(len(data)),
+			})
+			if tc.broken {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expected != "" {
+				contents, err := ioutil.ReadFile(filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(contents) != tc.expected {
+					t.Errorf("expected %q, got %q", tc.expected, contents)
+				}
+			}
+			if _, err := os.Stat(partialPath); (err == nil) != tc.kept {
+				t.Errorf("expected partial file kept to be %t", tc.kept)
+			}
+			if _, err := os.Stat(stalePath); err == nil {
+				t.Errorf("expected stale partial file to be removed")
+			}
+			if tc.stale {
+				if _, err := os.Stat(otherPath); err != nil {
+					t.Errorf("expected partial file of another file to be kept")
+				}
+			}
+		})
+	}
+}
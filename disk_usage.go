@@ -0,0 +1,130 @@
+package manta
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultDiskUsageConcurrency = 5
+
+// DiskUsageInput represents parameters to a DiskUsage operation. Path is
+// relative to the account's /stor directory. Concurrency is the number of
+// immediate subdirectories of Path walked at once; if it is zero,
+// DefaultDiskUsageConcurrency is used.
+type DiskUsageInput struct {
+	Path        string
+	Concurrency int
+}
+
+// DiskUsageSummary describes the storage consumed by a tree. Bytes is the
+// total size of the objects, and StoredBytes is the space consumed once the
+// durability level of each object is taken into account.
+type DiskUsageSummary struct {
+	Bytes       uint64
+	StoredBytes uint64
+	Objects     uint64
+	Directories uint64
+}
+
+// DiskUsageOutput contains the outputs of a DiskUsage operation. The
+// embedded totals cover the whole tree below Path, and Children holds the
+// totals for each immediate child of Path, keyed by name.
+type DiskUsageOutput struct {
+	DiskUsageSummary
+	Children map[string]*DiskUsageSummary
+}
+
+// DiskUsage calculates the storage consumed by the tree below Path, like
+// `du`, walking each of its immediate subdirectories concurrently.
+func (c *Client) DiskUsage(input *DiskUsageInput) (*DiskUsageOutput, error) {
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDiskUsageConcurrency
+	}
+	root := strings.Trim(path.Clean("/"+input.Path), "/")
+
+	output := &DiskUsageOutput{
+		Children: map[string]*DiskUsageSummary{},
+	}
+
+	var lock sync.Mutex
+	var firstErr error
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				summary := &DiskUsageSummary{}
+				err := c.Walk(path.Join(root, name), func(entryPath string, entry *DirectoryEntry, err error) error {
+					if err != nil {
+						return err
+					}
+					summary.add(entry)
+					return nil
+				})
+
+				lock.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				output.Children[name] = summary
+				lock.Unlock()
+			}
+		}()
+	}
+
+	listErr := c.listDirectoryAll(root, func(entry *DirectoryEntry) error {
+		if entry.Type == EntryTypeDirectory {
+			work <- entry.Name
+			return nil
+		}
+
+		summary := &DiskUsageSummary{}
+		summary.add(entry)
+
+		lock.Lock()
+		output.Children[entry.Name] = summary
+		lock.Unlock()
+		return nil
+	})
+	close(work)
+	wg.Wait()
+
+	if listErr != nil {
+		return nil, errwrap.Wrapf("Error executing DiskUsage request: {{err}}", listErr)
+	}
+	if firstErr != nil {
+		return nil, errwrap.Wrapf("Error executing DiskUsage request: {{err}}", firstErr)
+	}
+
+	for _, summary := range output.Children {
+		output.Bytes += summary.Bytes
+		output.StoredBytes += summary.StoredBytes
+		output.Objects += summary.Objects
+		output.Directories += summary.Directories
+	}
+
+	return output, nil
+}
+
+// add counts entry in the summary.
+func (s *DiskUsageSummary) add(entry *DirectoryEntry) {
+	if entry.Type == EntryTypeDirectory {
+		s.Directories++
+		return
+	}
+
+	durability := entry.Durability
+	if durability == 0 {
+		durability = DefaultDurabilityLevel
+	}
+	s.Bytes += entry.Size
+	s.StoredBytes += entry.Size * durability
+	s.Objects++
+}
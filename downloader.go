@@ -0,0 +1,358 @@
+package manta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	DefaultDownloadPartSize    = 5 * 1024 * 1024
+	DefaultDownloadConcurrency = 5
+	DefaultDownloadPartRetries = 3
+)
+
+// DownloaderOptions represents the configuration of a Downloader. Any field
+// left as zero takes the corresponding default value.
+type DownloaderOptions struct {
+	// PartSize is the size in bytes of each range requested.
+	PartSize uint64
+
+	// Concurrency is the number of ranges requested at once.
+	Concurrency int
+
+	// PartRetries is the number of times the download of a range is
+	// retried after failing, in addition to any retries made by the client.
+	PartRetries int
+
+	// RateLimiter, if set, limits the rate at which data is downloaded.
+	RateLimiter *RateLimiter
+}
+
+// Downloader downloads objects from Manta by splitting them into ranges which
+// are fetched concurrently. A Downloader is safe for concurrent use.
+type Downloader struct {
+	client  *Client
+	options DownloaderOptions
+}
+
+// NewDownloader is used to construct a Downloader which downloads objects
+// using client. If options is nil, default options are used.
+func NewDownloader(client *Client, options *DownloaderOptions) *Downloader {
+	downloader := &Downloader{
+		client: client,
+	}
+	if options != nil {
+		downloader.options = *options
+	}
+
+	if downloader.options.PartSize == 0 {
+		downloader.options.PartSize = DefaultDownloadPartSize
+	}
+	if downloader.options.Concurrency <= 0 {
+		downloader.options.Concurrency = DefaultDownloadConcurrency
+	}
+	if downloader.options.PartRetries == 0 {
+		downloader.options.PartRetries = DefaultDownloadPartRetries
+	}
+
+	return downloader
+}
+
+// DownloadInput represents parameters to a Download operation.
+//
+// If CheckpointPath is set, the progress of the download is recorded in a
+// local file at that path as each range completes. If the download is
+// interrupted, calling Download again with the same CheckpointPath and a
+// writer over the same destination fetches only the ranges which had not
+// been completed. The checkpoint file is removed once the download succeeds.
+//
+// If Progress is set, it is called as data is downloaded, with the number of
+// each range counting from zero. Ranges completed before a download was
+// resumed are reported as transferred.
+//
+// Objects compressed or encrypted by PutObject cannot be decoded from an
+// arbitrary offset, so they are downloaded with a single request, decoded as
+// by GetObject, and CheckpointPath is not used.
+type DownloadInput struct {
+	ObjectPath     string
+	CheckpointPath string
+	Progress       ProgressFunc
+}
+
+// DownloadOutput contains the outputs of a Download operation.
+type DownloadOutput struct {
+	ContentLength uint64
+	ContentType   string
+	ETag          string
+	Metadata      map[string]string
+}
+
+// Download downloads an object into writer, which may be an *os.File. Each
+// range is requested with the ETag of the object when the download started,
+// so the download fails rather than mixing data from different versions if
+// the object is replaced part way through.
+func (d *Downloader) Download(writer io.WriterAt, input *DownloadInput) (*DownloadOutput, error) {
+	head, err := d.client.HeadObject(&HeadObjectInput{
+		ObjectPath: input.ObjectPath,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing Download request: {{err}}", err)
+	}
+
+	if isEncodedObject(head.Metadata) {
+		return d.downloadWhole(writer, input, head)
+	}
+
+	output := &DownloadOutput{
+		ContentLength: head.ContentLength,
+		ContentType:   head.ContentType,
+		ETag:          head.ETag,
+		Metadata:      head.Metadata,
+	}
+
+	checkpoint := &DownloadCheckpoint{
+		ObjectPath:    input.ObjectPath,
+		ETag:          head.ETag,
+		ContentLength: head.ContentLength,
+		PartSize:      d.options.PartSize,
+	}
+	if input.CheckpointPath != "" {
+		existing, err := readDownloadCheckpoint(input.CheckpointPath)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error reading download checkpoint: {{err}}", err)
+		}
+		if existing != nil {
+			if existing.ObjectPath != checkpoint.ObjectPath || existing.ETag != checkpoint.ETag ||
+				existing.ContentLength != checkpoint.ContentLength {
+				return nil, ErrDownloadCheckpointMismatch
+			}
+			checkpoint = existing
+		}
+	}
+
+	tracker := newProgressTracker(input.Progress, head.ContentLength)
+
+	completed := map[uint64]bool{}
+	for _, offset := range checkpoint.Completed {
+		completed[offset] = true
+	}
+
+	var ranges []*downloadRange
+	for offset := uint64(0); offset < head.ContentLength; offset += checkpoint.PartSize {
+		length := checkpoint.PartSize
+		if offset+length > head.ContentLength {
+			length = head.ContentLength - offset
+		}
+		if completed[offset] {
+			tracker.add(int64(length), offset/checkpoint.PartSize)
+			continue
+		}
+		ranges = append(ranges, &downloadRange{
+			number: offset / checkpoint.PartSize,
+			offset: offset,
+			length: length,
+		})
+	}
+
+	onComplete := func(r *downloadRange) error {
+		if input.CheckpointPath == "" {
+			return nil
+		}
+		checkpoint.Completed = append(checkpoint.Completed, r.offset)
+		return writeDownloadCheckpoint(input.CheckpointPath, checkpoint)
+	}
+
+	if err := d.downloadRanges(writer, input.ObjectPath, head.ETag, ranges, onComplete, tracker); err != nil {
+		return nil, err
+	}
+
+	if input.CheckpointPath != "" {
+		if err := os.Remove(input.CheckpointPath); err != nil && !os.IsNotExist(err) {
+			return nil, errwrap.Wrapf("Error removing download checkpoint: {{err}}", err)
+		}
+	}
+
+	return output, nil
+}
+
+// downloadWhole downloads an object which cannot be split into ranges into
+// writer with a single request, on the condition that it is still the
+// version described by head.
+func (d *Downloader) downloadWhole(writer io.WriterAt, input *DownloadInput, head *HeadObjectOutput) (*DownloadOutput, error) {
+	object, err := d.client.GetObject(&GetObjectInput{
+		ObjectPath: input.ObjectPath,
+		IfMatch:    head.ETag,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing Download request: {{err}}", err)
+	}
+	defer object.ObjectReader.Close()
+
+	reader := &progressReader{
+		reader:  d.options.RateLimiter.Reader(object.ObjectReader),
+		tracker: newProgressTracker(input.Progress, object.ContentLength),
+	}
+	n, err := io.Copy(io.NewOffsetWriter(writer, 0), reader)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error downloading object: {{err}}", err)
+	}
+
+	return &DownloadOutput{
+		ContentLength: uint64(n),
+		ContentType:   object.ContentType,
+		ETag:          object.ETag,
+		Metadata:      object.Metadata,
+	}, nil
+}
+
+// ErrDownloadCheckpointMismatch is returned by Download when a checkpoint
+// exists for a different object, or the object has changed since the
+// checkpoint was written. The partially downloaded data cannot be reused,
+// so the checkpoint file should be removed and the download restarted.
+var ErrDownloadCheckpointMismatch = errors.New("Download checkpoint does not match the object being downloaded")
+
+// DownloadCheckpoint records the progress of a download, as stored in the
+// file at DownloadInput.CheckpointPath. Completed holds the offsets of the
+// ranges of PartSize bytes which have been written.
+type DownloadCheckpoint struct {
+	ObjectPath    string   `json:"objectPath"`
+	ETag          string   `json:"etag"`
+	ContentLength uint64   `json:"contentLength"`
+	PartSize      uint64   `json:"partSize"`
+	Completed     []uint64 `json:"completed"`
+}
+
+// readDownloadCheckpoint reads the checkpoint at path, returning nil if no
+// checkpoint file exists.
+func readDownloadCheckpoint(path string) (*DownloadCheckpoint, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	checkpoint := &DownloadCheckpoint{}
+	if err := json.Unmarshal(contents, checkpoint); err != nil {
+		return nil, err
+	}
+	if checkpoint.PartSize == 0 {
+		return nil, errors.New("Download checkpoint has no part size")
+	}
+
+	return checkpoint, nil
+}
+
+// writeDownloadCheckpoint replaces the checkpoint at path, writing to a
+// temporary file first so that a crash cannot leave it truncated.
+func writeDownloadCheckpoint(path string, checkpoint *DownloadCheckpoint) error {
+	contents, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tempPath := path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, path)
+}
+
+// downloadRange describes a range of an object waiting to be downloaded.
+type downloadRange struct {
+	number uint64
+	offset uint64
+	length uint64
+}
+
+// downloadRanges downloads each of ranges of the object at objectPath into
+// writer, using the configured number of workers. onComplete is called for
+// each range once it has been written, with no other call in progress.
+func (d *Downloader) downloadRanges(writer io.WriterAt, objectPath, etag string, ranges []*downloadRange, onComplete func(*downloadRange) error, tracker *progressTracker) error {
+	var lock sync.Mutex
+	var firstErr error
+
+	work := make(chan *downloadRange)
+	var wg sync.WaitGroup
+	for i := 0; i < d.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				err := d.downloadRange(writer, objectPath, etag, r, tracker)
+
+				lock.Lock()
+				if err == nil {
+					err = onComplete(r)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, r := range ranges {
+		lock.Lock()
+		failed := firstErr != nil
+		lock.Unlock()
+		if failed {
+			break
+		}
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+
+	return firstErr
+}
+
+// downloadRange downloads a single range into writer, retrying up to the
+// configured number of times.
+func (d *Downloader) downloadRange(writer io.WriterAt, objectPath, etag string, r *downloadRange, tracker *progressTracker) error {
+	var err error
+	for attempt := 0; attempt <= d.options.PartRetries; attempt++ {
+		var output *GetObjectOutput
+		output, err = d.client.GetObject(&GetObjectInput{
+			ObjectPath:  objectPath,
+			IfMatch:     etag,
+			RangeOffset: r.offset,
+			RangeLength: r.length,
+		})
+		if err != nil {
+			if IsPreconditionFailedError(err) {
+				break
+			}
+			continue
+		}
+
+		reader := &progressReader{
+			reader:     d.options.RateLimiter.Reader(output.ObjectReader),
+			tracker:    tracker,
+			partNumber: r.number,
+		}
+
+		var n int64
+		n, err = io.Copy(io.NewOffsetWriter(writer, int64(r.offset)), reader)
+		output.ObjectReader.Close()
+		if err == nil && uint64(n) != r.length {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			return nil
+		}
+		reader.discard()
+	}
+
+	return errwrap.Wrapf(fmt.Sprintf("Error downloading bytes %d-%d: {{err}}", r.offset, r.offset+r.length-1), err)
+}
@@ -0,0 +1,70 @@
+package manta
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testWriterAt is an in-memory io.WriterAt.
+type testWriterAt struct {
+	lock sync.Mutex
+	data []byte
+}
+
+func (w *testWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if end := int(off) + len(p); end > len(w.data) {
+		w.data = append(w.data, make([]byte, end-len(w.data))...)
+	}
+	return copy(w.data[off:], p), nil
+}
+
+func TestDownload(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+
+	cases := []struct {
+		name     string
+		compress bool
+		requests int
+	}{
+		{name: "ranges", requests: 4},
+		{name: "compressed", compress: true, requests: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			err := client.PutObject(&PutObjectInput{
+				ObjectPath:   "object",
+				Compress:     tc.compress,
+				ObjectReader: strings.NewReader(data),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writer := &testWriterAt{}
+			output, err := NewDownloader(client, &DownloaderOptions{
+				PartSize: 256,
+			}).Download(writer, &DownloadInput{
+				ObjectPath: "object",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(writer.data, []byte(data)) {
+				t.Errorf("downloaded data does not match the object")
+			}
+			if output.ContentLength != uint64(len(data)) {
+				t.Errorf("expected ContentLength %d, got %d", len(data), output.ContentLength)
+			}
+			if requests := s.countRequests("GET stor/object"); requests != tc.requests {
+				t.Errorf("expected %d requests, got %d", tc.requests, requests)
+			}
+		})
+	}
+}
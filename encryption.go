@@ -0,0 +1,292 @@
+package manta
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// The headers below are those written by the client-side encryption support
+// in java-manta, so that objects encrypted by either SDK can be decrypted by
+// the other.
+const (
+	encryptTypeHeader            = "m-encrypt-type"
+	encryptKeyIDHeader           = "m-encrypt-key-id"
+	encryptIVHeader              = "m-encrypt-iv"
+	encryptCipherHeader          = "m-encrypt-cipher"
+	encryptHMACTypeHeader        = "m-encrypt-hmac-type"
+	encryptPlaintextLengthHeader = "m-encrypt-plaintext-content-length"
+
+	encryptTypeClient = "client/1"
+	encryptHMACType   = "HmacSHA256"
+
+	// encryptionOverhead is the number of bytes added to an object by
+	// encryption, for the HMAC appended to the ciphertext.
+	encryptionOverhead = sha256.Size
+)
+
+// EncryptionKeyProvider supplies the secret keys used for client-side
+// encryption. Keys must be 16, 24 or 32 bytes long, selecting AES-128,
+// AES-192 or AES-256 respectively.
+type EncryptionKeyProvider interface {
+	// EncryptionKey returns the key with which new objects are encrypted,
+	// along with an identifier which is stored with each object.
+	EncryptionKey() (keyID string, key []byte, err error)
+
+	// DecryptionKey returns the key with the given identifier, as stored
+	// with an object being decrypted.
+	DecryptionKey(keyID string) ([]byte, error)
+}
+
+// StaticKeyProvider is an EncryptionKeyProvider holding a single key.
+type StaticKeyProvider struct {
+	KeyID string
+	Key   []byte
+}
+
+func (p *StaticKeyProvider) EncryptionKey() (string, []byte, error) {
+	return p.KeyID, p.Key, nil
+}
+
+func (p *StaticKeyProvider) DecryptionKey(keyID string) ([]byte, error) {
+	if keyID != p.KeyID {
+		return nil, fmt.Errorf("No encryption key with ID %q", keyID)
+	}
+	return p.Key, nil
+}
+
+// encryptionCipherName returns the java-manta name of the AES/CTR cipher
+// used with a key of the given length.
+func encryptionCipherName(key []byte) (string, error) {
+	switch len(key) {
+	case 16, 24, 32:
+		return fmt.Sprintf("AES%d/CTR/NoPadding", len(key)*8), nil
+	default:
+		return "", fmt.Errorf("Encryption key must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+}
+
+// encryptObject returns a reader of the encrypted form of plaintext, which
+// consists of the AES/CTR ciphertext followed by an HMAC-SHA256 of the IV and
+// ciphertext, and sets the headers describing the encryption. If the length
+// of the plaintext is known, the length of the encrypted data is returned.
+//
+// If plaintext is an io.ReadSeeker, so is the returned reader, so that the
+// upload may be retried.
+func (c *Client) encryptObject(plaintext io.Reader, contentLength uint64, headers *http.Header) (io.Reader, uint64, error) {
+	keyID, key, err := c.encryptionKeys.EncryptionKey()
+	if err != nil {
+		return nil, 0, err
+	}
+	cipherName, err := encryptionCipherName(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, 0, err
+	}
+
+	headers.Set(encryptTypeHeader, encryptTypeClient)
+	headers.Set(encryptKeyIDHeader, keyID)
+	headers.Set(encryptIVHeader, base64.StdEncoding.EncodeToString(iv))
+	headers.Set(encryptCipherHeader, cipherName)
+	headers.Set(encryptHMACTypeHeader, encryptHMACType)
+
+	var encryptedLength uint64
+	if contentLength != 0 {
+		headers.Set(encryptPlaintextLengthHeader, strconv.FormatUint(contentLength, 10))
+		encryptedLength = contentLength + encryptionOverhead
+	}
+
+	seeker, ok := plaintext.(io.ReadSeeker)
+	if !ok {
+		reader, err := newEncryptingReader(plaintext, key, iv)
+		return reader, encryptedLength, err
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := newEncryptingReader(seeker, key, iv); err != nil {
+		return nil, 0, err
+	}
+	reader := &bodyFactoryReader{
+		getBody: func() (io.Reader, error) {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return newEncryptingReader(seeker, key, iv)
+		},
+	}
+
+	return reader, encryptedLength, nil
+}
+
+// decryptObject replaces the ObjectReader of output with a reader which
+// decrypts it, using the encryption details in headers.
+func (c *Client) decryptObject(output *GetObjectOutput, headers http.Header, ranged bool) error {
+	if encryptType := headers.Get(encryptTypeHeader); encryptType != encryptTypeClient {
+		return fmt.Errorf("Unsupported encryption type %q", encryptType)
+	}
+	if ranged {
+		return ErrObjectNotSeekable
+	}
+	if hmacType := headers.Get(encryptHMACTypeHeader); hmacType != encryptHMACType {
+		return fmt.Errorf("Unsupported encryption HMAC type %q", hmacType)
+	}
+
+	key, err := c.encryptionKeys.DecryptionKey(headers.Get(encryptKeyIDHeader))
+	if err != nil {
+		return err
+	}
+	cipherName, err := encryptionCipherName(key)
+	if err != nil {
+		return err
+	}
+	if headerCipher := headers.Get(encryptCipherHeader); headerCipher != cipherName {
+		return fmt.Errorf("Unsupported encryption cipher %q for a %d byte key", headerCipher, len(key))
+	}
+
+	iv, err := base64.StdEncoding.DecodeString(headers.Get(encryptIVHeader))
+	if err != nil {
+		return err
+	}
+	if len(iv) != aes.BlockSize {
+		return fmt.Errorf("Encryption IV must be %d bytes, got %d", aes.BlockSize, len(iv))
+	}
+
+	if output.ContentLength < encryptionOverhead {
+		return errors.New("Encrypted object is too short to contain an HMAC")
+	}
+	ciphertextLength := output.ContentLength - encryptionOverhead
+
+	reader, err := newDecryptingReader(output.ObjectReader, key, iv, ciphertextLength)
+	if err != nil {
+		return err
+	}
+
+	output.ObjectReader = reader
+	output.ContentLength = ciphertextLength
+	output.ContentMD5 = ""
+	for key := range output.Metadata {
+		if strings.HasPrefix(key, "encrypt-") {
+			delete(output.Metadata, key)
+		}
+	}
+
+	return nil
+}
+
+// encryptingReader encrypts the data read from reader, followed by the HMAC
+// of the IV and ciphertext once reader is exhausted.
+type encryptingReader struct {
+	reader  io.Reader
+	stream  cipher.Stream
+	mac     hash.Hash
+	trailer []byte
+	done    bool
+}
+
+func newEncryptingReader(reader io.Reader, key, iv []byte) (*encryptingReader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(iv)
+
+	return &encryptingReader{
+		reader: reader,
+		stream: cipher.NewCTR(block, iv),
+		mac:    mac,
+	}, nil
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	if r.done {
+		if len(r.trailer) == 0 {
+			return 0, io.EOF
+		}
+		n := copy(p, r.trailer)
+		r.trailer = r.trailer[n:]
+		return n, nil
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.stream.XORKeyStream(p[:n], p[:n])
+		r.mac.Write(p[:n])
+	}
+	if err == io.EOF {
+		r.done = true
+		r.trailer = r.mac.Sum(nil)
+		err = nil
+	}
+	return n, err
+}
+
+// decryptingReader decrypts ciphertextLength bytes read from reader, then
+// verifies the HMAC which follows them.
+type decryptingReader struct {
+	body       io.ReadCloser
+	ciphertext io.Reader
+	stream     cipher.Stream
+	mac        hash.Hash
+	verified   bool
+}
+
+func newDecryptingReader(body io.ReadCloser, key, iv []byte, ciphertextLength uint64) (*decryptingReader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(iv)
+
+	return &decryptingReader{
+		body:       body,
+		ciphertext: io.LimitReader(body, int64(ciphertextLength)),
+		stream:     cipher.NewCTR(block, iv),
+		mac:        mac,
+	}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	n, err := r.ciphertext.Read(p)
+	if n > 0 {
+		r.mac.Write(p[:n])
+		r.stream.XORKeyStream(p[:n], p[:n])
+	}
+	if err == io.EOF && !r.verified {
+		trailer := make([]byte, sha256.Size)
+		if _, err := io.ReadFull(r.body, trailer); err != nil {
+			return n, errwrap.Wrapf("Error reading encryption HMAC: {{err}}", err)
+		}
+		if !hmac.Equal(trailer, r.mac.Sum(nil)) {
+			return n, errors.New("Encryption HMAC does not match object contents")
+		}
+		r.verified = true
+	}
+	return n, err
+}
+
+func (r *decryptingReader) Close() error {
+	return r.body.Close()
+}
@@ -2,6 +2,8 @@ package manta
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/hashicorp/errwrap"
 )
@@ -21,106 +23,316 @@ func (e MantaError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// ChecksumMismatchError is returned by PutObject and by reads of the
+// ObjectReader returned by GetObject when the digest of the data of an
+// object computed by the client does not match the digest expected for it.
+// Algorithm is "MD5" or "SHA-256", and the digests are in the encoding in
+// which the digest is stored by Manta.
+type ChecksumMismatchError struct {
+	ObjectPath string
+	Algorithm  string
+	Expected   string
+	Actual     string
+}
+
+// Error implements interface Error on the ChecksumMismatchError type.
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s mismatch for %s: expected %s, computed %s", e.Algorithm, e.ObjectPath, e.Expected, e.Actual)
+}
+
+// IsChecksumMismatchError returns true if err is or wraps a
+// *ChecksumMismatchError.
+func IsChecksumMismatchError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errwrap.GetType(err, &ChecksumMismatchError{}) != nil
+}
+
+// AlreadyExistsError is returned by PutObject when CreateOnly is set and an
+// object already exists at ObjectPath.
+type AlreadyExistsError struct {
+	ObjectPath string
+}
+
+// Error implements interface Error on the AlreadyExistsError type.
+func (e AlreadyExistsError) Error() string {
+	return fmt.Sprintf("Object %s already exists", e.ObjectPath)
+}
+
+// IsAlreadyExistsError returns true if err is or wraps an
+// *AlreadyExistsError.
+func IsAlreadyExistsError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errwrap.GetType(err, &AlreadyExistsError{}) != nil
+}
+
+// DirectoryNotEmptyError is returned by DeleteDirectory when the directory
+// still has entries, so that callers may decide whether to delete them.
+type DirectoryNotEmptyError struct {
+	DirectoryName string
+}
+
+// Error implements interface Error on the DirectoryNotEmptyError type.
+func (e DirectoryNotEmptyError) Error() string {
+	return fmt.Sprintf("Directory %s is not empty", e.DirectoryName)
+}
+
+// NotDirectoryError is returned by EnsureDirectory when an object exists at
+// the path at which a directory is required.
+type NotDirectoryError struct {
+	Path string
+}
+
+// Error implements interface Error on the NotDirectoryError type.
+func (e NotDirectoryError) Error() string {
+	return fmt.Sprintf("%s exists and is not a directory", e.Path)
+}
+
+// IsNotDirectoryError returns true if err is or wraps a *NotDirectoryError.
+func IsNotDirectoryError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errwrap.GetType(err, &NotDirectoryError{}) != nil
+}
+
 func IsAuthSchemeError(err error) bool {
 	return isSpecificError(err, "AuthSchemeError")
 }
 
 func IsAuthorizationError(err error) bool {
+	return isSpecificError(err, "AuthorizationError")
+}
+
+func IsBadRequestError(err error) bool {
 	return isSpecificError(err, "BadRequestError")
 }
 
 func IsChecksumError(err error) bool {
+	return isSpecificError(err, "ChecksumError")
+}
+
+func IsConcurrentRequestError(err error) bool {
 	return isSpecificError(err, "ConcurrentRequestError")
 }
 
 func IsContentLengthError(err error) bool {
+	return isSpecificError(err, "ContentLengthError")
+}
+
+func IsContentMD5MismatchError(err error) bool {
 	return isSpecificError(err, "ContentMD5MismatchError")
 }
 
 func IsEntityExistsError(err error) bool {
+	return isSpecificError(err, "EntityExistsError")
+}
+
+func IsInvalidArgumentError(err error) bool {
 	return isSpecificError(err, "InvalidArgumentError")
 }
 
 func IsInvalidAuthTokenError(err error) bool {
+	return isSpecificError(err, "InvalidAuthTokenError")
+}
+
+func IsInvalidCredentialsError(err error) bool {
 	return isSpecificError(err, "InvalidCredentialsError")
 }
 
 func IsInvalidDurabilityLevelError(err error) bool {
+	return isSpecificError(err, "InvalidDurabilityLevelError")
+}
+
+func IsInvalidKeyIdError(err error) bool {
 	return isSpecificError(err, "InvalidKeyIdError")
 }
 
 func IsInvalidJobError(err error) bool {
+	return isSpecificError(err, "InvalidJobError")
+}
+
+func IsInvalidLinkError(err error) bool {
 	return isSpecificError(err, "InvalidLinkError")
 }
 
 func IsInvalidLimitError(err error) bool {
+	return isSpecificError(err, "InvalidLimitError")
+}
+
+func IsInvalidSignatureError(err error) bool {
 	return isSpecificError(err, "InvalidSignatureError")
 }
 
 func IsInvalidUpdateError(err error) bool {
+	return isSpecificError(err, "InvalidUpdateError")
+}
+
+func IsDirectoryDoesNotExistError(err error) bool {
 	return isSpecificError(err, "DirectoryDoesNotExistError")
 }
 
 func IsDirectoryExistsError(err error) bool {
+	return isSpecificError(err, "DirectoryExistsError")
+}
+
+// IsDirectoryNotEmptyError returns true if err is or wraps a
+// *DirectoryNotEmptyError, or a MantaError with the DirectoryNotEmpty code.
+func IsDirectoryNotEmptyError(err error) bool {
+	if err != nil && errwrap.GetType(err, &DirectoryNotEmptyError{}) != nil {
+		return true
+	}
 	return isSpecificError(err, "DirectoryNotEmptyError")
 }
 
 func IsDirectoryOperationError(err error) bool {
+	return isSpecificError(err, "DirectoryOperationError")
+}
+
+func IsInternalError(err error) bool {
 	return isSpecificError(err, "InternalError")
 }
 
 func IsJobNotFoundError(err error) bool {
+	return isSpecificError(err, "JobNotFoundError")
+}
+
+func IsJobStateError(err error) bool {
 	return isSpecificError(err, "JobStateError")
 }
 
 func IsKeyDoesNotExistError(err error) bool {
+	return isSpecificError(err, "KeyDoesNotExistError")
+}
+
+func IsNotAcceptableError(err error) bool {
 	return isSpecificError(err, "NotAcceptableError")
 }
 
 func IsNotEnoughSpaceError(err error) bool {
+	return isSpecificError(err, "NotEnoughSpaceError")
+}
+
+func IsLinkNotFoundError(err error) bool {
 	return isSpecificError(err, "LinkNotFoundError")
 }
 
 func IsLinkNotObjectError(err error) bool {
+	return isSpecificError(err, "LinkNotObjectError")
+}
+
+func IsLinkRequiredError(err error) bool {
 	return isSpecificError(err, "LinkRequiredError")
 }
 
 func IsParentNotDirectoryError(err error) bool {
-	return isSpecificError(err, "PreconditionFailedError")
+	return isSpecificError(err, "ParentNotDirectoryError")
+}
+
+// IsPreconditionFailedError returns true if err resulted from a conditional
+// request whose condition did not hold. Responses with no body, such as those
+// to HEAD requests, are matched by their 412 status code.
+func IsPreconditionFailedError(err error) bool {
+	return isSpecificError(err, "PreconditionFailedError") || hasStatusCode(err, http.StatusPreconditionFailed)
 }
 
 func IsPreSignedRequestError(err error) bool {
+	return isSpecificError(err, "PreSignedRequestError")
+}
+
+func IsRequestEntityTooLargeError(err error) bool {
 	return isSpecificError(err, "RequestEntityTooLargeError")
 }
 
 func IsResourceNotFoundError(err error) bool {
+	return isSpecificError(err, "ResourceNotFoundError")
+}
+
+func IsRootDirectoryError(err error) bool {
 	return isSpecificError(err, "RootDirectoryError")
 }
 
 func IsServiceUnavailableError(err error) bool {
+	return isSpecificError(err, "ServiceUnavailableError")
+}
+
+func IsSnaplinksDisabledError(err error) bool {
+	return isSpecificError(err, "SnaplinksDisabledError")
+}
+
+func IsSSLRequiredError(err error) bool {
 	return isSpecificError(err, "SSLRequiredError")
 }
 
 func IsUploadTimeoutError(err error) bool {
+	return isSpecificError(err, "UploadTimeoutError")
+}
+
+func IsUserDoesNotExistError(err error) bool {
 	return isSpecificError(err, "UserDoesNotExistError")
 }
 
-func IsTaskInitError(err error) bool {
+func IsUserTaskError(err error) bool {
 	return isSpecificError(err, "UserTaskError")
 }
 
+func IsTaskInitError(err error) bool {
+	return isSpecificError(err, "TaskInitError")
+}
+
+func IsTaskKilledError(err error) bool {
+	return isSpecificError(err, "TaskKilledError")
+}
+
+func IsAuthorizationFailedError(err error) bool {
+	return isSpecificError(err, "AuthorizationFailedError")
+}
+
+func IsJobCancelledError(err error) bool {
+	return isSpecificError(err, "JobCancelledError")
+}
+
 // isSpecificError checks whether the error represented by err wraps
-// an underlying MantaError with code errorCode.
+// an underlying MantaError or JobError with code errorCode. Manta reports
+// codes both with and without the "Error" suffix used in its
+// documentation, so either form matches.
 func isSpecificError(err error, errorCode string) bool {
-	tritonErrorInterface := errwrap.GetType(err.(error), &MantaError{})
-	if tritonErrorInterface == nil {
+	if err == nil {
 		return false
 	}
 
-	tritonErr := tritonErrorInterface.(*MantaError)
-	if tritonErr.Code == errorCode {
+	var code string
+	if tritonErrorInterface := errwrap.GetType(err, &MantaError{}); tritonErrorInterface != nil {
+		code = tritonErrorInterface.(*MantaError).Code
+	} else if jobErrorInterface := errwrap.GetType(err, &JobError{}); jobErrorInterface != nil {
+		code = jobErrorInterface.(*JobError).Code
+	} else {
+		return false
+	}
+
+	if strings.TrimSuffix(code, "Error") == strings.TrimSuffix(errorCode, "Error") {
 		return true
 	}
 
 	return false
 }
+
+// hasStatusCode checks whether the error represented by err wraps an
+// underlying MantaError resulting from a response with status code
+// statusCode. This is useful for responses to HEAD requests, which
+// carry no error code.
+func hasStatusCode(err error, statusCode int) bool {
+	if err == nil {
+		return false
+	}
+
+	tritonErrorInterface := errwrap.GetType(err, &MantaError{})
+	if tritonErrorInterface == nil {
+		return false
+	}
+
+	return tritonErrorInterface.(*MantaError).StatusCode == statusCode
+}
@@ -2,8 +2,9 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
+	"os"
 
 	"github.com/jen20/manta-go"
 	"github.com/jen20/manta-go/authentication"
@@ -33,15 +34,16 @@ func main() {
 	}
 
 	defer output.ObjectReader.Close()
-	body, err := ioutil.ReadAll(output.ObjectReader)
-	if err != nil {
-		log.Fatalf("Reading Object: %s", err)
-	}
 
 	fmt.Printf("Content-Length: %d\n", output.ContentLength)
 	fmt.Printf("Content-MD5: %s\n", output.ContentMD5)
 	fmt.Printf("Content-Type: %s\n", output.ContentType)
 	fmt.Printf("ETag: %s\n", output.ETag)
+	fmt.Printf("Durability-Level: %d\n", output.DurabilityLevel)
 	fmt.Printf("Date-Modified: %s\n", output.LastModified.String())
-	fmt.Printf("Object:\n\n%s", string(body))
+	fmt.Printf("Object:\n\n")
+
+	if _, err := io.Copy(os.Stdout, output.ObjectReader); err != nil {
+		log.Fatalf("Reading Object: %s", err)
+	}
 }
@@ -30,11 +30,18 @@ func main() {
 	}
 	defer reader.Close()
 
+	err = client.PutDirectory(&manta.PutDirectoryInput{
+		DirectoryName: "examples",
+	})
+	if err != nil {
+		log.Fatalf("PutDirectory(): %s", err)
+	}
+
 	err = client.PutObject(&manta.PutObjectInput{
-		ObjectPath:   "foo.txt",
+		ObjectPath:   "examples/foo.txt",
 		ObjectReader: reader,
 	})
 	if err != nil {
-		log.Fatalf("GetObject(): %s", err)
+		log.Fatalf("PutObject(): %s", err)
 	}
 }
@@ -0,0 +1,172 @@
+package manta
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// fileModifiedTimeMetadataKey is the metadata item in which PutFile records
+// the modification time of the local file, which GetFile restores.
+const fileModifiedTimeMetadataKey = "mtime"
+
+// PutFileInput represents parameters to a PutFile operation. ContentType,
+// DurabilityLevel and Metadata have the same meaning as for PutObjectInput.
+type PutFileInput struct {
+	ObjectPath      string
+	FilePath        string
+	ContentType     string
+	DurabilityLevel uint64
+	Metadata        map[string]string
+}
+
+// PutFile uploads the local file at FilePath to ObjectPath. The size of the
+// file is sent as the Content-Length, the content type is inferred from the
+// file name or contents if not given, and the modification time of the file
+// is stored as the "mtime" metadata item so that GetFile can restore it.
+// Since the file can be re-read, the upload is retried on failure.
+func (c *Client) PutFile(input *PutFileInput) error {
+	file, err := os.Open(input.FilePath)
+	if err != nil {
+		return errwrap.Wrapf("Error opening file for PutFile: {{err}}", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return errwrap.Wrapf("Error reading file information for PutFile: {{err}}", err)
+	}
+
+	metadata := map[string]string{}
+	for key, value := range input.Metadata {
+		metadata[key] = value
+	}
+	metadata[fileModifiedTimeMetadataKey] = info.ModTime().UTC().Format(time.RFC3339Nano)
+
+	contentType := input.ContentType
+	if contentType == "" {
+		contentType, _, err = detectContentType(input.FilePath, file, true)
+		if err != nil {
+			return errwrap.Wrapf("Error detecting Content-Type for PutFile: {{err}}", err)
+		}
+	}
+
+	err = c.PutObject(&PutObjectInput{
+		ObjectPath:      input.ObjectPath,
+		ContentType:     contentType,
+		DurabilityLevel: input.DurabilityLevel,
+		ContentLength:   uint64(info.Size()),
+		Metadata:        metadata,
+		ObjectReader:    file,
+	})
+	if err != nil {
+		return errwrap.Wrapf("Error executing PutFile request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// GetFileInput represents parameters to a GetFile operation.
+type GetFileInput struct {
+	ObjectPath string
+	FilePath   string
+}
+
+// GetFileOutput contains the outputs of a GetFile operation.
+type GetFileOutput struct {
+	ContentLength uint64
+	ContentType   string
+	ETag          string
+	Metadata      map[string]string
+}
+
+// GetFile downloads the object at ObjectPath to the local file at FilePath.
+// The object is written to a temporary file in the same directory, which
+// replaces FilePath only once the download is complete, so FilePath never
+// holds a partial object. If FilePath already exists, the new file takes its
+// permissions; otherwise it is created with mode 0666 less the umask, as by
+// os.Create. The modification time of the file is set to the time recorded
+// by PutFile if present, and otherwise to the time the object was last
+// modified.
+func (c *Client) GetFile(input *GetFileInput) (*GetFileOutput, error) {
+	object, err := c.GetObject(&GetObjectInput{
+		ObjectPath: input.ObjectPath,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing GetFile request: {{err}}", err)
+	}
+	defer object.ObjectReader.Close()
+
+	dir, name := filepath.Split(input.FilePath)
+	if dir == "" {
+		dir = "."
+	}
+	tempFile, err := createTempFile(dir, "."+name+".tmp", 0666)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error creating temporary file for GetFile: {{err}}", err)
+	}
+	tempPath := tempFile.Name()
+
+	if info, statErr := os.Stat(input.FilePath); statErr == nil {
+		err = tempFile.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		_, err = io.Copy(tempFile, object.ObjectReader)
+	}
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, errwrap.Wrapf("Error writing file for GetFile: {{err}}", err)
+	}
+
+	modifiedTime := object.LastModified
+	if value, ok := object.Metadata[fileModifiedTimeMetadataKey]; ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			modifiedTime = parsed
+		}
+	}
+	if !modifiedTime.IsZero() {
+		if err := os.Chtimes(tempPath, modifiedTime, modifiedTime); err != nil {
+			os.Remove(tempPath)
+			return nil, errwrap.Wrapf("Error setting file modification time for GetFile: {{err}}", err)
+		}
+	}
+
+	if err := os.Rename(tempPath, input.FilePath); err != nil {
+		os.Remove(tempPath)
+		return nil, errwrap.Wrapf("Error renaming file for GetFile: {{err}}", err)
+	}
+
+	return &GetFileOutput{
+		ContentLength: object.ContentLength,
+		ContentType:   object.ContentType,
+		ETag:          object.ETag,
+		Metadata:      object.Metadata,
+	}, nil
+}
+
+// createTempFile creates a new file in dir whose name begins with prefix,
+// as ioutil.TempFile does, but with the permissions perm less the umask
+// rather than 0600.
+func createTempFile(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for attempt := 0; attempt < 10000; attempt++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		return file, err
+	}
+	return nil, errors.New("Unable to find an unused temporary file name")
+}
@@ -0,0 +1,118 @@
+package manta
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FindInput represents parameters to a Find operation. Every filter which is
+// set must match for an entry to be returned.
+type FindInput struct {
+	// Root is the path below which to search, relative to the account's
+	// /stor directory.
+	Root string
+
+	// Name is a glob pattern, in the syntax of path.Match, which the name
+	// of an entry must match.
+	Name string
+
+	// NameRegexp is a regular expression which the name of an entry must
+	// match.
+	NameRegexp *regexp.Regexp
+
+	// Type restricts the results to EntryTypeObject or EntryTypeDirectory.
+	Type string
+
+	// MinSize and MaxSize bound the size in bytes of objects. If either is
+	// non-zero, directories do not match.
+	MinSize uint64
+	MaxSize uint64
+
+	// ModifiedAfter and ModifiedBefore bound the modification time of
+	// entries.
+	ModifiedAfter  *time.Time
+	ModifiedBefore *time.Time
+
+	// MaxDepth limits the depth of the search below Root, where entries
+	// directly within Root are at depth 1. Zero means no limit.
+	MaxDepth int
+}
+
+// FindFunc is the type of the function called by Find for each matching
+// entry. entryPath is relative to the account's /stor directory. Returning
+// an error stops the search, and the error is returned by Find.
+type FindFunc func(entryPath string, entry *DirectoryEntry) error
+
+// Find searches the tree below Root for entries matching the filters of
+// input, like `mfind`, calling fn for each match as it is found. Directories
+// are searched depth-first as for Walk, and Root itself is not matched.
+func (c *Client) Find(input *FindInput, fn FindFunc) error {
+	if input.Name != "" {
+		if _, err := path.Match(input.Name, ""); err != nil {
+			return err
+		}
+	}
+
+	root := strings.Trim(path.Clean("/"+input.Root), "/")
+
+	return c.Walk(root, func(entryPath string, entry *DirectoryEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entryPath == root {
+			return nil
+		}
+
+		if findMatches(input, entry) {
+			if err := fn(entryPath, entry); err != nil {
+				return err
+			}
+		}
+
+		if input.MaxDepth > 0 && entry.Type == EntryTypeDirectory {
+			relativePath := entryPath
+			if root != "" {
+				relativePath = strings.TrimPrefix(entryPath, root+"/")
+			}
+			if strings.Count(relativePath, "/")+1 >= input.MaxDepth {
+				return SkipDir
+			}
+		}
+		return nil
+	})
+}
+
+// findMatches checks entry against the filters of input.
+func findMatches(input *FindInput, entry *DirectoryEntry) bool {
+	if input.Type != "" && entry.Type != input.Type {
+		return false
+	}
+	if input.Name != "" {
+		if matched, _ := path.Match(input.Name, entry.Name); !matched {
+			return false
+		}
+	}
+	if input.NameRegexp != nil && !input.NameRegexp.MatchString(entry.Name) {
+		return false
+	}
+	if input.MinSize != 0 || input.MaxSize != 0 {
+		if entry.Type != EntryTypeObject {
+			return false
+		}
+		if entry.Size < input.MinSize {
+			return false
+		}
+		if input.MaxSize != 0 && entry.Size > input.MaxSize {
+			return false
+		}
+	}
+	if input.ModifiedAfter != nil && !entry.ModifiedTime.After(*input.ModifiedAfter) {
+		return false
+	}
+	if input.ModifiedBefore != nil && !entry.ModifiedTime.Before(*input.ModifiedBefore) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,474 @@
+package manta
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// FS provides read-only access to a tree in Manta through the io/fs
+// interfaces, so that it may be used with fs.WalkDir, http.FS,
+// template.ParseFS and the like. It implements fs.FS, fs.ReadDirFS and
+// fs.StatFS.
+//
+// Names are slash separated paths relative to the root of the FS, as
+// required by fs.ValidPath.
+type FS struct {
+	client *Client
+	root   string
+}
+
+// NewFS is used to construct an FS over the tree at root, relative to the
+// account's /stor directory.
+func NewFS(client *Client, root string) *FS {
+	return &FS{
+		client: client,
+		root:   strings.Trim(path.Clean("/"+root), "/"),
+	}
+}
+
+// objectPath returns the path relative to /stor of the entry with the given
+// name. The name "." is the root itself, which path.Join would otherwise
+// turn into "." when the root is /stor.
+func (f *FS) objectPath(name string) string {
+	if name == "." {
+		return f.root
+	}
+	return strings.TrimPrefix(path.Join(f.root, name), "/")
+}
+
+// fsPathError returns a *fs.PathError for err, translating Manta's not found
+// errors to fs.ErrNotExist.
+func fsPathError(op, name string, err error) error {
+	if IsResourceNotFoundError(err) || IsDirectoryDoesNotExistError(err) || hasStatusCode(err, http.StatusNotFound) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{
+		Op:   op,
+		Path: name,
+		Err:  err,
+	}
+}
+
+// Stat implements fs.StatFS.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, fsPathError("stat", name, err)
+	}
+	return info, nil
+}
+
+func (f *FS) stat(name string) (*fsFileInfo, error) {
+	if f.objectPath(name) == "" {
+		return &fsFileInfo{
+			entry: &DirectoryEntry{
+				Name: ".",
+				Type: EntryTypeDirectory,
+			},
+		}, nil
+	}
+
+	head, err := f.client.HeadObject(&HeadObjectInput{
+		ObjectPath: f.objectPath(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &DirectoryEntry{
+		ETag:         head.ETag,
+		ModifiedTime: head.LastModified,
+		Name:         path.Base(name),
+		Size:         head.ContentLength,
+		Type:         EntryTypeObject,
+		Durability:   head.DurabilityLevel,
+	}
+	if isDirectoryContentType(head.ContentType) {
+		entry.ETag = ""
+		entry.Size = 0
+		entry.Type = EntryTypeDirectory
+	}
+
+	return &fsFileInfo{
+		entry:  entry,
+		client: f.client,
+		head:   head,
+	}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. Entries are sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	directoryPath := f.objectPath(name)
+	var entries []fs.DirEntry
+	err := f.client.listDirectoryAll(directoryPath, func(entry *DirectoryEntry) error {
+		entries = append(entries, &fsFileInfo{
+			entry:      entry,
+			client:     f.client,
+			objectPath: path.Join(directoryPath, entry.Name),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fsPathError("readdir", name, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// Open implements fs.FS. Directories are returned as fs.ReadDirFile.
+// Objects are returned as fs.File which also implements io.Seeker and
+// io.ReaderAt; their data is requested when it is first read.
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, fsPathError("open", name, err)
+	}
+
+	if info.IsDir() {
+		return &fsDirectory{
+			fs:   f,
+			name: name,
+			info: info,
+		}, nil
+	}
+	return &fsObject{
+		fs:   f,
+		name: name,
+		path: name,
+		info: info,
+	}, nil
+}
+
+// fsFileInfo implements fs.FileInfo and fs.DirEntry for a DirectoryEntry.
+//
+// The size of an object is that of the data GetObject returns, which for
+// objects compressed or encrypted by PutObject differs from the size stored
+// in the DirectoryEntry. Entries read from a directory listing carry no
+// metadata, so such an object is examined with HeadObject the first time
+// its size is needed; if that fails, the stored size is used. The size of
+// an object compressed without its length being recorded is not known, and
+// is reported as 0.
+type fsFileInfo struct {
+	entry  *DirectoryEntry
+	client *Client
+
+	// head is the metadata of the object, or nil if it is yet to be
+	// retrieved from objectPath.
+	head       *HeadObjectOutput
+	objectPath string
+
+	sizeOnce  sync.Once
+	size      int64
+	sizeKnown bool
+	encoded   bool
+}
+
+func (i *fsFileInfo) Name() string               { return i.entry.Name }
+func (i *fsFileInfo) ModTime() time.Time         { return i.entry.ModifiedTime }
+func (i *fsFileInfo) IsDir() bool                { return i.entry.Type == EntryTypeDirectory }
+func (i *fsFileInfo) Sys() interface{}           { return i.entry }
+func (i *fsFileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i *fsFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+func (i *fsFileInfo) Size() int64 {
+	size, _ := i.decodedSize()
+	return size
+}
+
+// decodedSize returns the size of the data GetObject returns for the entry,
+// and whether it is known. It also records whether the object is encoded.
+func (i *fsFileInfo) decodedSize() (int64, bool) {
+	i.sizeOnce.Do(func() {
+		i.size, i.sizeKnown = int64(i.entry.Size), true
+		if i.IsDir() {
+			return
+		}
+
+		head := i.head
+		if head == nil {
+			var err error
+			head, err = i.client.HeadObject(&HeadObjectInput{
+				ObjectPath: i.objectPath,
+			})
+			if err != nil || head.ETag != i.entry.ETag {
+				return
+			}
+		}
+
+		size, known := i.client.decodedContentLength(head.ContentLength, head.Metadata)
+		i.size, i.sizeKnown = int64(size), known
+		i.encoded = isEncodedObject(head.Metadata)
+	})
+	return i.size, i.sizeKnown
+}
+
+func (i *fsFileInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// fsObject is an open object, implementing fs.File, io.Seeker and
+// io.ReaderAt. The data is requested when it is first read, and again from
+// the new offset after a seek, so that seeking does not download the
+// object. Objects compressed or encrypted by PutObject cannot be read from
+// an arbitrary offset, so for those the data before the offset is read and
+// discarded. If the size of an object is not known, Stat and seeking
+// relative to the end read the object through once to find it.
+type fsObject struct {
+	fs     *FS
+	name   string
+	path   string
+	info   *fsFileInfo
+	reader io.ReadCloser
+	offset int64
+	closed bool
+}
+
+func (o *fsObject) Stat() (fs.FileInfo, error) {
+	if _, err := o.size(); err != nil {
+		return nil, fsPathError("stat", o.name, err)
+	}
+	return o.info, nil
+}
+
+// size returns the size of the data of the object, reading it through to
+// find it if it is not known.
+func (o *fsObject) size() (int64, error) {
+	if size, known := o.info.decodedSize(); known {
+		return size, nil
+	}
+
+	reader, err := o.open(0, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	size, err := io.Copy(ioutil.Discard, reader)
+	if err != nil {
+		return 0, err
+	}
+	o.info.size, o.info.sizeKnown = size, true
+	return size, nil
+}
+
+// open requests length bytes of the data of the object from offset, or the
+// rest of the data if length is zero. It returns io.EOF if offset is at or
+// beyond the end of the object.
+func (o *fsObject) open(offset, length int64) (io.ReadCloser, error) {
+	input := &GetObjectInput{
+		ObjectPath: o.fs.objectPath(o.path),
+		IfMatch:    o.info.entry.ETag,
+	}
+
+	// A range of an encoded object is a range of the stored data, which
+	// Manta may reject as beyond its end, so such objects are read from
+	// the start without one. decodedSize finds whether the object is
+	// encoded.
+	o.info.decodedSize()
+	var output *GetObjectOutput
+	var err error
+	if !o.info.encoded {
+		input.RangeOffset = uint64(offset)
+		input.RangeLength = uint64(length)
+		output, err = o.fs.client.GetObject(input)
+	}
+	if o.info.encoded || errwrap.Contains(err, ErrObjectNotSeekable.Error()) {
+		input.RangeOffset = 0
+		input.RangeLength = 0
+		output, err = o.fs.client.GetObject(input)
+		if err == nil {
+			if _, err := io.CopyN(ioutil.Discard, output.ObjectReader, offset); err != nil {
+				output.ObjectReader.Close()
+				return nil, err
+			}
+		}
+	}
+	if hasStatusCode(err, http.StatusRequestedRangeNotSatisfiable) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return output.ObjectReader, nil
+}
+
+func (o *fsObject) Read(p []byte) (int, error) {
+	if o.closed {
+		return 0, &fs.PathError{Op: "read", Path: o.name, Err: fs.ErrClosed}
+	}
+	if size, known := o.info.decodedSize(); known && o.offset >= size {
+		return 0, io.EOF
+	}
+
+	if o.reader == nil {
+		reader, err := o.open(o.offset, 0)
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fsPathError("read", o.name, err)
+		}
+		o.reader = reader
+	}
+
+	n, err := o.reader.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt. Each call makes a separate request, so
+// calls may be made concurrently.
+func (o *fsObject) ReadAt(p []byte, off int64) (int, error) {
+	if o.closed {
+		return 0, &fs.PathError{Op: "read", Path: o.name, Err: fs.ErrClosed}
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: o.name, Err: errors.New("negative offset")}
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	length := int64(len(p))
+	if size, known := o.info.decodedSize(); known {
+		if off >= size {
+			return 0, io.EOF
+		}
+		if remaining := size - off; length > remaining {
+			length = remaining
+		}
+	}
+
+	reader, err := o.open(off, length)
+	if err == io.EOF {
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, fsPathError("read", o.name, err)
+	}
+	defer reader.Close()
+
+	n, err := io.ReadFull(reader, p[:length])
+	if err == io.ErrUnexpectedEOF || (err == nil && n < len(p)) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker. If the offset changes, the data being read is
+// discarded, and requested again from the new offset when next read.
+func (o *fsObject) Seek(offset int64, whence int) (int64, error) {
+	if o.closed {
+		return 0, &fs.PathError{Op: "seek", Path: o.name, Err: fs.ErrClosed}
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		size, err := o.size()
+		if err != nil {
+			return 0, fsPathError("seek", o.name, err)
+		}
+		offset += size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: o.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: o.name, Err: errors.New("negative position")}
+	}
+
+	if offset != o.offset && o.reader != nil {
+		o.reader.Close()
+		o.reader = nil
+	}
+	o.offset = offset
+	return offset, nil
+}
+
+func (o *fsObject) Close() error {
+	if o.closed {
+		return &fs.PathError{Op: "close", Path: o.name, Err: fs.ErrClosed}
+	}
+	o.closed = true
+	if o.reader != nil {
+		return o.reader.Close()
+	}
+	return nil
+}
+
+// fsDirectory is an open directory, implementing fs.ReadDirFile.
+type fsDirectory struct {
+	fs      *FS
+	name    string
+	info    *fsFileInfo
+	entries []fs.DirEntry
+	read    bool
+	offset  int
+}
+
+func (d *fsDirectory) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *fsDirectory) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *fsDirectory) Close() error {
+	return nil
+}
+
+func (d *fsDirectory) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
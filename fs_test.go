@@ -0,0 +1,186 @@
+package manta
+
+import (
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFSRoot(t *testing.T) {
+	cases := []struct {
+		name string
+		root string
+	}{
+		{name: "stor", root: ""},
+		{name: "directory", root: "tree"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			prefix := ""
+			if tc.root != "" {
+				prefix = tc.root + "/"
+			}
+			s.put(prefix+"a", "a", nil)
+			s.put(prefix+"b/c", "c", nil)
+			fsys := NewFS(client, tc.root)
+
+			info, err := fsys.Stat(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.IsDir() {
+				t.Errorf("root is not a directory")
+			}
+
+			var walked []string
+			err = fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				walked = append(walked, name)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := []string{".", "a", "b", "b/c"}; !reflect.DeepEqual(walked, expected) {
+				t.Errorf("expected to walk %v, walked %v", expected, walked)
+			}
+
+			for _, request := range s.requestLog() {
+				if strings.Contains(request, "stor/.") || strings.HasSuffix(request, "/.") {
+					t.Errorf("unexpected request %q", request)
+				}
+			}
+		})
+	}
+}
+
+// testEncodedData is the data of the objects stored by putTestEncodedObjects.
+var testEncodedData = strings.Repeat("0123456789", 100)
+
+// putTestEncodedObjects stores objects holding testEncodedData below
+// "objects": "plain" as is, "compressed" compressed, "streamed" compressed
+// without its length being recorded, and "encrypted" encrypted with a key
+// which the client is then left holding.
+func putTestEncodedObjects(t *testing.T, client *Client) {
+	t.Helper()
+
+	if err := client.PutDirectory(&PutDirectoryInput{DirectoryName: "objects"}); err != nil {
+		t.Fatal(err)
+	}
+	inputs := []*PutObjectInput{
+		{ObjectPath: "objects/plain", ObjectReader: strings.NewReader(testEncodedData)},
+		{ObjectPath: "objects/compressed", Compress: true, ObjectReader: strings.NewReader(testEncodedData)},
+		{ObjectPath: "objects/streamed", Compress: true, ObjectReader: io.MultiReader(strings.NewReader(testEncodedData))},
+	}
+	for _, input := range inputs {
+		if err := client.PutObject(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client.encryptionKeys = &StaticKeyProvider{
+		KeyID: "test",
+		Key:   []byte("0123456789abcdef"),
+	}
+	err := client.PutObject(&PutObjectInput{
+		ObjectPath:   "objects/encrypted",
+		ObjectReader: strings.NewReader(testEncodedData),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSSizes(t *testing.T) {
+	_, client := newTestServer(t)
+	putTestEncodedObjects(t, client)
+	fsys := NewFS(client, "objects")
+
+	expected := map[string]int64{
+		"plain":      int64(len(testEncodedData)),
+		"compressed": int64(len(testEncodedData)),
+		"streamed":   0,
+		"encrypted":  int64(len(testEncodedData)),
+	}
+
+	entries, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(entries))
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != expected[entry.Name()] {
+			t.Errorf("ReadDir: expected %s to have size %d, got %d", entry.Name(), expected[entry.Name()], info.Size())
+		}
+	}
+
+	for name, size := range expected {
+		info, err := fsys.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != size {
+			t.Errorf("Stat: expected %s to have size %d, got %d", name, size, info.Size())
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != testEncodedData {
+			t.Errorf("ReadFile: data of %s does not match", name)
+		}
+	}
+}
+
+func TestFSObjectSeekAndReadAt(t *testing.T) {
+	_, client := newTestServer(t)
+	putTestEncodedObjects(t, client)
+	fsys := NewFS(client, "objects")
+
+	for _, name := range []string{"plain", "compressed", "streamed", "encrypted"} {
+		t.Run(name, func(t *testing.T) {
+			file, err := fsys.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			end, err := file.(io.Seeker).Seek(0, io.SeekEnd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if end != int64(len(testEncodedData)) {
+				t.Errorf("expected to seek to %d, got %d", len(testEncodedData), end)
+			}
+
+			if _, err := file.(io.Seeker).Seek(500, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			if data := readTestBody(t, file); data != testEncodedData[500:] {
+				t.Errorf("data read after seeking does not match")
+			}
+
+			p := make([]byte, 100)
+			n, err := file.(io.ReaderAt).ReadAt(p, 950)
+			if n != 50 || err != io.EOF {
+				t.Errorf("expected ReadAt to return (50, EOF), got (%d, %v)", n, err)
+			}
+			if string(p[:n]) != testEncodedData[950:] {
+				t.Errorf("data read by ReadAt does not match")
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package manta
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// ObjectHandlerOptions represents options for an ObjectHandler.
+type ObjectHandlerOptions struct {
+	// Prefix is the directory, relative to the account's /stor directory,
+	// to which the paths of requests are relative.
+	Prefix string
+
+	// IndexName is the name of the object served for requests for a
+	// directory, such as "index.html". If it is empty, requests for
+	// directories are answered with 404 Not Found.
+	IndexName string
+}
+
+// ObjectHandler is an http.Handler which serves the objects below a prefix
+// in Manta, so that a site stored in Manta may be fronted by a Go service.
+// The path of each request, which may be stripped with http.StripPrefix
+// first, names an object relative to the prefix.
+//
+// GET and HEAD requests are supported. The Content-Type, ETag,
+// Last-Modified, caching and CORS headers stored with each object are
+// served with it, and conditional requests are passed on to Manta, so that
+// 304 Not Modified and 412 Precondition Failed are answered without data.
+// Range requests for a single range of the form "bytes=first-" or
+// "bytes=first-last" are answered with 206 Partial Content, honouring
+// If-Range; other ranges are ignored and the whole object is served.
+//
+// Objects are read with GetObject, so objects encrypted or compressed by
+// the client are served decrypted and decompressed. Ranges of such objects
+// cannot be read, so Range headers are ignored for them and the whole
+// object is served.
+type ObjectHandler struct {
+	client    *Client
+	prefix    string
+	indexName string
+}
+
+// NewObjectHandler is used to construct an ObjectHandler for client. If
+// options is nil, objects are served from the account's /stor directory.
+func NewObjectHandler(client *Client, options *ObjectHandlerOptions) *ObjectHandler {
+	handler := &ObjectHandler{
+		client: client,
+	}
+	if options != nil {
+		handler.prefix = strings.Trim(path.Clean("/"+options.Prefix), "/")
+		handler.indexName = options.IndexName
+	}
+	return handler
+}
+
+// ServeHTTP implements http.Handler.
+func (h *ObjectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	requestPath := path.Clean("/" + r.URL.Path)
+	objectPath := strings.TrimPrefix(path.Join(h.prefix, requestPath), "/")
+	if strings.HasSuffix(r.URL.Path, "/") || objectPath == "" {
+		if h.indexName == "" {
+			http.NotFound(w, r)
+			return
+		}
+		objectPath = strings.TrimPrefix(path.Join(objectPath, h.indexName), "/")
+	}
+
+	if r.Method == http.MethodHead {
+		h.serveHead(w, r, objectPath)
+		return
+	}
+	h.serveGet(w, r, objectPath)
+}
+
+// serveGet answers a GET request for the object at objectPath.
+func (h *ObjectHandler) serveGet(w http.ResponseWriter, r *http.Request, objectPath string) {
+	input := &GetObjectInput{
+		ObjectPath: objectPath,
+	}
+	setObjectHandlerConditions(r, &input.IfMatch, &input.IfNoneMatch, &input.IfModifiedSince, &input.IfUnmodifiedSince)
+
+	ifRange := false
+	if offset, length, ok := parseObjectHandlerRange(r.Header.Get("Range")); ok {
+		input.RangeOffset = offset
+		input.RangeLength = length
+
+		// If-Range is sent on to Manta as a precondition of the ranged
+		// request, which is made again without it if the condition fails.
+		if value := r.Header.Get("If-Range"); value != "" && input.IfMatch == "" && input.IfUnmodifiedSince == nil {
+			ifRange = true
+			if modified, err := http.ParseTime(value); err == nil {
+				input.IfUnmodifiedSince = &modified
+			} else {
+				input.IfMatch = value
+			}
+		}
+	}
+
+	object, err := h.client.GetObject(input)
+	if (ifRange && IsPreconditionFailedError(err)) || errwrap.Contains(err, ErrObjectNotSeekable.Error()) {
+		input.RangeOffset = 0
+		input.RangeLength = 0
+		setObjectHandlerConditions(r, &input.IfMatch, &input.IfNoneMatch, &input.IfModifiedSince, &input.IfUnmodifiedSince)
+		object, err = h.client.GetObject(input)
+	}
+	if err != nil {
+		h.serveError(w, r, err)
+		return
+	}
+	defer object.ObjectReader.Close()
+
+	if isDirectoryContentType(object.ContentType) {
+		h.serveDirectory(w, r)
+		return
+	}
+
+	header := w.Header()
+	setObjectHandlerHeaders(header, object.ContentType, object.ETag, object.LastModified,
+		object.CacheControl, object.Expires, object.CORS)
+	if object.ContentLength != 0 {
+		header.Set("Content-Length", strconv.FormatUint(object.ContentLength, 10))
+	}
+
+	status := http.StatusOK
+	if object.ContentRange != "" {
+		header.Set("Content-Range", object.ContentRange)
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+
+	io.Copy(w, object.ObjectReader)
+}
+
+// serveHead answers a HEAD request for the object at objectPath.
+func (h *ObjectHandler) serveHead(w http.ResponseWriter, r *http.Request, objectPath string) {
+	input := &HeadObjectInput{
+		ObjectPath: objectPath,
+	}
+	setObjectHandlerConditions(r, &input.IfMatch, &input.IfNoneMatch, &input.IfModifiedSince, &input.IfUnmodifiedSince)
+
+	head, err := h.client.HeadObject(input)
+	if err != nil {
+		h.serveError(w, r, err)
+		return
+	}
+
+	if isDirectoryContentType(head.ContentType) {
+		h.serveDirectory(w, r)
+		return
+	}
+
+	header := w.Header()
+	setObjectHandlerHeaders(header, head.ContentType, head.ETag, head.LastModified,
+		head.CacheControl, head.Expires, head.CORS)
+	if contentLength, ok := h.client.decodedContentLength(head.ContentLength, head.Metadata); ok {
+		header.Set("Content-Length", strconv.FormatUint(contentLength, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveDirectory answers a request which named a directory without a
+// trailing slash, redirecting to its index if there is one.
+func (h *ObjectHandler) serveDirectory(w http.ResponseWriter, r *http.Request) {
+	if h.indexName == "" || strings.HasSuffix(r.URL.Path, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	target := path.Base(r.URL.Path) + "/"
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
+
+// serveError answers a request which failed with err.
+func (h *ObjectHandler) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case hasStatusCode(err, http.StatusNotModified):
+		w.WriteHeader(http.StatusNotModified)
+	case IsResourceNotFoundError(err) || IsDirectoryDoesNotExistError(err) || hasStatusCode(err, http.StatusNotFound):
+		http.NotFound(w, r)
+	case IsPreconditionFailedError(err):
+		http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+	case hasStatusCode(err, http.StatusRequestedRangeNotSatisfiable):
+		http.Error(w, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+	case hasStatusCode(err, http.StatusForbidden):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+	}
+}
+
+// setObjectHandlerConditions sets the conditional fields of a request to
+// Manta from the conditional headers of r.
+func setObjectHandlerConditions(r *http.Request, ifMatch, ifNoneMatch *string, ifModifiedSince, ifUnmodifiedSince **time.Time) {
+	*ifMatch = r.Header.Get("If-Match")
+	*ifNoneMatch = r.Header.Get("If-None-Match")
+	*ifModifiedSince = nil
+	*ifUnmodifiedSince = nil
+
+	if modified, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && *ifNoneMatch == "" {
+		*ifModifiedSince = &modified
+	}
+	if unmodified, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && *ifMatch == "" {
+		*ifUnmodifiedSince = &unmodified
+	}
+}
+
+// setObjectHandlerHeaders sets the headers of a response serving an object.
+func setObjectHandlerHeaders(header http.Header, contentType, etag string, lastModified time.Time, cacheControl string, expires time.Time, cors *CORSConfiguration) {
+	header.Set("Accept-Ranges", "bytes")
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	var expiresTime *time.Time
+	if !expires.IsZero() {
+		expiresTime = &expires
+	}
+	setCacheHeaders(&header, cacheControl, expiresTime)
+	setCORSHeaders(&header, cors)
+}
+
+// parseObjectHandlerRange parses a Range header holding a single range of
+// the form "bytes=first-" or "bytes=first-last", returning the offset and
+// length of the range as for GetObjectInput.
+func parseObjectHandlerRange(value string) (uint64, uint64, bool) {
+	if !strings.HasPrefix(value, "bytes=") {
+		return 0, 0, false
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(value, "bytes="))
+	if strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+
+	dash := strings.Index(spec, "-")
+	if dash <= 0 {
+		return 0, 0, false
+	}
+	first, err := strconv.ParseUint(strings.TrimSpace(spec[:dash]), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	lastText := strings.TrimSpace(spec[dash+1:])
+	if lastText == "" {
+		if first == 0 {
+			// The whole object was requested, which GetObject cannot
+			// express as a range.
+			return 0, 0, false
+		}
+		return first, 0, true
+	}
+	last, err := strconv.ParseUint(lastText, 10, 64)
+	if err != nil || last < first {
+		return 0, 0, false
+	}
+	return first, last - first + 1, true
+}
@@ -0,0 +1,86 @@
+package manta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseObjectHandlerRange(t *testing.T) {
+	cases := []struct {
+		value  string
+		offset uint64
+		length uint64
+		ok     bool
+	}{
+		{value: "", ok: false},
+		{value: "bytes=0-", ok: false},
+		{value: "bytes=0-0", offset: 0, length: 1, ok: true},
+		{value: "bytes=10-", offset: 10, length: 0, ok: true},
+		{value: "bytes=10-19", offset: 10, length: 10, ok: true},
+		{value: "bytes= 10 - 19 ", offset: 10, length: 10, ok: true},
+		{value: "bytes=-10", ok: false},
+		{value: "bytes=20-10", ok: false},
+		{value: "bytes=0-9,20-29", ok: false},
+		{value: "bytes=a-b", ok: false},
+		{value: "items=0-9", ok: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			offset, length, ok := parseObjectHandlerRange(tc.value)
+			if ok != tc.ok || offset != tc.offset || length != tc.length {
+				t.Errorf("expected (%d, %d, %t), got (%d, %d, %t)", tc.offset, tc.length, tc.ok, offset, length, ok)
+			}
+		})
+	}
+}
+
+func TestObjectHandlerRanges(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+
+	cases := []struct {
+		name     string
+		compress bool
+		status   int
+		body     string
+	}{
+		{name: "plain", status: http.StatusPartialContent, body: data[10:20]},
+		{name: "compressed", compress: true, status: http.StatusOK, body: data},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, client := newTestServer(t)
+			err := client.PutObject(&PutObjectInput{
+				ObjectPath:   "object",
+				Compress:     tc.compress,
+				ObjectReader: strings.NewReader(data),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := NewObjectHandler(client, nil)
+
+			request := httptest.NewRequest(http.MethodGet, "/object", nil)
+			request.Header.Set("Range", "bytes=10-19")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, recorder.Code)
+			}
+			if body := recorder.Body.String(); body != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, body)
+			}
+
+			request = httptest.NewRequest(http.MethodHead, "/object", nil)
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if length := recorder.Header().Get("Content-Length"); length != strconv.Itoa(len(data)) {
+				t.Errorf("expected HEAD Content-Length %d, got %q", len(data), length)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package manta
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// HTTPFileSystemOptions represents the configuration of an HTTPFileSystem.
+type HTTPFileSystemOptions struct {
+	// Listings allows the entries of directories to be read. If it is
+	// false, directories which do not contain an index.html object cannot
+	// be opened, so that http.FileServer responds to requests for them
+	// with 404 Not Found rather than a listing.
+	Listings bool
+}
+
+// HTTPFileSystem provides read-only access to a tree in Manta as an
+// http.FileSystem, so that it may be served with http.FileServer. Files
+// support Seek without downloading the object: the data is requested from
+// the current offset when it is next read, so that range requests are
+// served with ranged requests to Manta. Objects compressed or encrypted by
+// PutObject cannot be read from an arbitrary offset, so range requests for
+// them are served by reading the object from the start.
+type HTTPFileSystem struct {
+	fs       *FS
+	listings bool
+}
+
+// NewHTTPFileSystem is used to construct an HTTPFileSystem over the tree at
+// root, relative to the account's /stor directory. If options is nil,
+// default options are used.
+func NewHTTPFileSystem(client *Client, root string, options *HTTPFileSystemOptions) *HTTPFileSystem {
+	f := &HTTPFileSystem{
+		fs: NewFS(client, root),
+	}
+	if options != nil {
+		f.listings = options.Listings
+	}
+	return f
+}
+
+// Open implements http.FileSystem. Names are slash separated paths relative
+// to the root of the HTTPFileSystem, and are cleaned before use.
+func (f *HTTPFileSystem) Open(name string) (http.File, error) {
+	fsName := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if fsName == "" {
+		fsName = "."
+	}
+
+	info, err := f.fs.stat(fsName)
+	if err != nil {
+		return nil, fsPathError("open", name, err)
+	}
+
+	if !info.IsDir() {
+		return &httpObject{
+			fsObject: &fsObject{
+				fs:   f.fs,
+				name: name,
+				path: fsName,
+				info: info,
+			},
+		}, nil
+	}
+
+	if !f.listings {
+		if _, err := f.fs.stat(path.Join(fsName, "index.html")); err != nil {
+			return nil, fsPathError("open", name, err)
+		}
+	}
+	return &httpDirectory{
+		fs:       f.fs,
+		name:     name,
+		path:     fsName,
+		info:     info,
+		listings: f.listings,
+	}, nil
+}
+
+// httpObject is an open object, implementing http.File.
+type httpObject struct {
+	*fsObject
+}
+
+func (o *httpObject) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: o.name, Err: fs.ErrInvalid}
+}
+
+// httpDirectory is an open directory, implementing http.File.
+type httpDirectory struct {
+	fs       *FS
+	name     string
+	path     string
+	info     *fsFileInfo
+	listings bool
+	entries  []fs.DirEntry
+	read     bool
+	offset   int
+}
+
+func (d *httpDirectory) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *httpDirectory) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *httpDirectory) Seek(offset int64, whence int) (int64, error) {
+	return 0, &fs.PathError{Op: "seek", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *httpDirectory) Close() error {
+	return nil
+}
+
+// Readdir implements http.File, returning the entries sorted by name. It
+// fails with fs.ErrPermission if listings are not enabled.
+func (d *httpDirectory) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.listings {
+		return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: fs.ErrPermission}
+	}
+
+	if !d.read {
+		entries, err := d.fs.ReadDir(d.path)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	remaining := d.entries[d.offset:]
+	if count > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+		if count < len(remaining) {
+			remaining = remaining[:count]
+		}
+	}
+	d.offset += len(remaining)
+
+	infos := make([]os.FileInfo, len(remaining))
+	for i, entry := range remaining {
+		infos[i] = entry.(*fsFileInfo)
+	}
+	return infos, nil
+}
@@ -0,0 +1,91 @@
+package manta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestHTTPFileSystem(t *testing.T) {
+	_, client := newTestServer(t)
+	putTestEncodedObjects(t, client)
+	server := httptest.NewServer(http.FileServer(NewHTTPFileSystem(client, "objects", nil)))
+	defer server.Close()
+
+	cases := []struct {
+		name       string
+		byteRange  string
+		status     int
+		start, end int
+	}{
+		{name: "plain", status: http.StatusOK, end: len(testEncodedData)},
+		{name: "plain", byteRange: "bytes=100-199", status: http.StatusPartialContent, start: 100, end: 200},
+		{name: "compressed", status: http.StatusOK, end: len(testEncodedData)},
+		{name: "compressed", byteRange: "bytes=100-199", status: http.StatusPartialContent, start: 100, end: 200},
+		{name: "streamed", status: http.StatusOK, end: len(testEncodedData)},
+		{name: "streamed", byteRange: "bytes=900-", status: http.StatusPartialContent, start: 900, end: len(testEncodedData)},
+		{name: "encrypted", status: http.StatusOK, end: len(testEncodedData)},
+		{name: "encrypted", byteRange: "bytes=100-199", status: http.StatusPartialContent, start: 100, end: 200},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name+" "+tc.byteRange, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, server.URL+"/"+tc.name, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.byteRange != "" {
+				request.Header.Set("Range", tc.byteRange)
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			body := readTestBody(t, response.Body)
+
+			if response.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, response.StatusCode)
+			}
+			if expected := strconv.Itoa(tc.end - tc.start); response.Header.Get("Content-Length") != expected {
+				t.Errorf("expected Content-Length %s, got %q", expected, response.Header.Get("Content-Length"))
+			}
+			if body != testEncodedData[tc.start:tc.end] {
+				t.Errorf("body does not match bytes %d-%d of the object", tc.start, tc.end)
+			}
+		})
+	}
+}
+
+func TestHTTPFileSystemListings(t *testing.T) {
+	s, client := newTestServer(t)
+	s.put("site/dir/object", "data", nil)
+	s.put("site/indexed/index.html", "index", nil)
+
+	cases := []struct {
+		listings bool
+		path     string
+		status   int
+	}{
+		{listings: false, path: "/dir/", status: http.StatusNotFound},
+		{listings: true, path: "/dir/", status: http.StatusOK},
+		{listings: false, path: "/indexed/", status: http.StatusOK},
+		{listings: false, path: "/dir/object", status: http.StatusOK},
+		{listings: false, path: "/missing", status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(strconv.FormatBool(tc.listings)+" "+tc.path, func(t *testing.T) {
+			handler := http.FileServer(NewHTTPFileSystem(client, "site", &HTTPFileSystemOptions{
+				Listings: tc.listings,
+			}))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if recorder.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, recorder.Code)
+			}
+		})
+	}
+}
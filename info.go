@@ -0,0 +1,74 @@
+package manta
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	EntryTypeObject    = "object"
+	EntryTypeDirectory = "directory"
+)
+
+// GetInfoInput represents parameters to a GetInfo operation. Path is
+// relative to the account's /stor directory.
+type GetInfoInput struct {
+	Path string
+}
+
+// GetInfoOutput contains the outputs for a GetInfo operation. If Exists is
+// false, all other fields are empty. Type is one of EntryTypeObject or
+// EntryTypeDirectory.
+type GetInfoOutput struct {
+	Exists        bool
+	Type          string
+	ContentLength uint64
+	ContentType   string
+	LastModified  time.Time
+	ETag          string
+	Metadata      map[string]string
+}
+
+// GetInfo reports whether anything exists at a path, and if so whether it
+// is an object or a directory, using a single HEAD request. Unlike
+// HeadObject, a path which does not exist is not treated as an error.
+func (c *Client) GetInfo(input *GetInfoInput) (*GetInfoOutput, error) {
+	head, err := c.HeadObject(&HeadObjectInput{
+		ObjectPath: input.Path,
+	})
+	if err != nil {
+		if IsResourceNotFoundError(err) || hasStatusCode(err, http.StatusNotFound) {
+			return &GetInfoOutput{}, nil
+		}
+		return nil, errwrap.Wrapf("Error executing GetInfo request: {{err}}", err)
+	}
+
+	output := &GetInfoOutput{
+		Exists:        true,
+		Type:          EntryTypeObject,
+		ContentLength: head.ContentLength,
+		ContentType:   head.ContentType,
+		LastModified:  head.LastModified,
+		ETag:          head.ETag,
+		Metadata:      head.Metadata,
+	}
+	if isDirectoryContentType(head.ContentType) {
+		output.Type = EntryTypeDirectory
+	}
+
+	return output, nil
+}
+
+// isDirectoryContentType checks whether contentType is the content type
+// Manta reports for directories, "application/x-json-stream; type=directory".
+func isDirectoryContentType(contentType string) bool {
+	for _, param := range strings.Split(contentType, ";") {
+		if strings.TrimSpace(param) == "type=directory" {
+			return true
+		}
+	}
+	return false
+}
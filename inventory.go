@@ -0,0 +1,235 @@
+package manta
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	InventoryFormatCSV       = "csv"
+	InventoryFormatJSONLines = "jsonl"
+)
+
+const DefaultInventoryConcurrency = 10
+
+// inventoryBatchSize is the number of objects whose content types are
+// requested concurrently before their records are written.
+const inventoryBatchSize = 100
+
+// InventoryRecord describes an object in an inventory. Path is relative to
+// the account's /stor directory, and ContentType is only set if it was
+// requested.
+type InventoryRecord struct {
+	Path        string    `json:"path"`
+	Size        uint64    `json:"size"`
+	Durability  uint64    `json:"durability"`
+	ETag        string    `json:"etag"`
+	Modified    time.Time `json:"mtime"`
+	ContentType string    `json:"contentType,omitempty"`
+}
+
+// inventoryCSVHeader names the columns of an inventory in CSV format.
+var inventoryCSVHeader = []string{"path", "size", "durability", "etag", "mtime", "content_type"}
+
+// InventoryInput represents parameters to an Inventory operation. The
+// inventory is written to Writer if it is set, and otherwise uploaded as the
+// object at ObjectPath.
+type InventoryInput struct {
+	// Path is the root of the tree to list, relative to the account's
+	// /stor directory.
+	Path string
+
+	// Format is InventoryFormatCSV or InventoryFormatJSONLines. If it is
+	// empty, InventoryFormatCSV is used.
+	Format string
+
+	Writer     io.Writer
+	ObjectPath string
+
+	// IncludeContentType requests the content type of each object, which
+	// Manta does not include in directory listings, so it costs a HEAD
+	// request per object. Concurrency is the number of these requests made
+	// at once; if it is zero, DefaultInventoryConcurrency is used.
+	IncludeContentType bool
+	Concurrency        int
+}
+
+// Inventory writes a record for every object in the tree below Path, in the
+// order in which Walk visits them. In CSV format the first row names the
+// columns, and times are in RFC 3339 format.
+func (c *Client) Inventory(input *InventoryInput) error {
+	if input.Writer == nil && input.ObjectPath == "" {
+		return errors.New("Either Writer or ObjectPath must be set for Inventory")
+	}
+
+	format := input.Format
+	if format == "" {
+		format = InventoryFormatCSV
+	}
+	contentType := "text/csv"
+	switch format {
+	case InventoryFormatCSV:
+	case InventoryFormatJSONLines:
+		contentType = "application/x-json-stream"
+	default:
+		return fmt.Errorf("Unknown inventory format %q", format)
+	}
+
+	writer := input.Writer
+	var objectWriter *ObjectWriter
+	if writer == nil {
+		objectWriter = NewObjectWriter(c, &UploadInput{
+			ObjectPath:  input.ObjectPath,
+			ContentType: contentType,
+		}, nil)
+		writer = objectWriter
+	}
+
+	err := c.writeInventory(input, format, writer)
+	if objectWriter != nil {
+		if err != nil {
+			objectWriter.Abort(err)
+		} else {
+			err = objectWriter.Close()
+		}
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing Inventory request: {{err}}", err)
+	}
+	return nil
+}
+
+// writeInventory writes the inventory described by input to writer.
+func (c *Client) writeInventory(input *InventoryInput, format string, writer io.Writer) error {
+	var write func(record *InventoryRecord) error
+	var flush func() error
+
+	if format == InventoryFormatCSV {
+		csvWriter := csv.NewWriter(writer)
+		if err := csvWriter.Write(inventoryCSVHeader); err != nil {
+			return err
+		}
+		write = func(record *InventoryRecord) error {
+			return csvWriter.Write([]string{
+				record.Path,
+				strconv.FormatUint(record.Size, 10),
+				strconv.FormatUint(record.Durability, 10),
+				record.ETag,
+				record.Modified.UTC().Format(time.RFC3339Nano),
+				record.ContentType,
+			})
+		}
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	} else {
+		encoder := json.NewEncoder(writer)
+		write = func(record *InventoryRecord) error {
+			return encoder.Encode(record)
+		}
+		flush = func() error {
+			return nil
+		}
+	}
+
+	var batch []*InventoryRecord
+	writeBatch := func() error {
+		if input.IncludeContentType {
+			if err := c.inventoryContentTypes(batch, input.Concurrency); err != nil {
+				return err
+			}
+		}
+		for _, record := range batch {
+			if err := write(record); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	root := strings.Trim(path.Clean("/"+input.Path), "/")
+	err := c.Walk(root, func(entryPath string, entry *DirectoryEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type != EntryTypeObject {
+			return nil
+		}
+
+		batch = append(batch, &InventoryRecord{
+			Path:       entryPath,
+			Size:       entry.Size,
+			Durability: entry.Durability,
+			ETag:       entry.ETag,
+			Modified:   entry.ModifiedTime,
+		})
+		if len(batch) < inventoryBatchSize {
+			return nil
+		}
+		return writeBatch()
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeBatch(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// inventoryContentTypes sets the ContentType of each record, making up to
+// concurrency HEAD requests at once. Objects removed since they were listed
+// are left without a content type.
+func (c *Client) inventoryContentTypes(records []*InventoryRecord, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = DefaultInventoryConcurrency
+	}
+
+	var lock sync.Mutex
+	var firstErr error
+
+	work := make(chan *InventoryRecord)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range work {
+				head, err := c.HeadObject(&HeadObjectInput{
+					ObjectPath: record.Path,
+				})
+				if err != nil {
+					if !IsResourceNotFoundError(err) && !hasStatusCode(err, http.StatusNotFound) {
+						lock.Lock()
+						if firstErr == nil {
+							firstErr = err
+						}
+						lock.Unlock()
+					}
+					continue
+				}
+				record.ContentType = head.ContentType
+			}
+		}()
+	}
+	for _, record := range records {
+		work <- record
+	}
+	close(work)
+	wg.Wait()
+
+	return firstErr
+}
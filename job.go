@@ -1,21 +1,25 @@
 package manta
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/errwrap"
 )
 
 const (
-	JobStateDone    = "done"
+	JobStateQueued  = "queued"
 	JobStateRunning = "running"
+	JobStateDone    = "done"
 )
 
 // JobPhase represents the specification for a map or reduce phase of a Manta
@@ -35,7 +39,7 @@ type JobPhase struct {
 
 	// Init is a shell statement to execute in each compute zone before
 	// any tasks are executed. The same constraints apply as to Exec.
-	Init string `json:"init"`
+	Init string `json:"init,omitempty"`
 
 	// ReducerCount is an optional number of reducers for this phase. The
 	// default value if not specified is 1. The maximum value is 1024.
@@ -48,6 +52,93 @@ type JobPhase struct {
 	// Disk is the amount of disk space in GB to be allocated to the compute
 	// zone. Valid values are 2, 4, 8, 16, 32, 64, 128, 256, 512 or 1024.
 	Disk uint64 `json:"disk,omitempty"`
+
+	// Image is an optional semver range, such as "13.3.*", selecting the
+	// version of the compute image in which tasks run. If it is empty, the
+	// default image is used.
+	Image string `json:"image,omitempty"`
+}
+
+const (
+	JobPhaseTypeMap    = "map"
+	JobPhaseTypeReduce = "reduce"
+)
+
+// maxJobPhaseReducerCount is the largest number of reducers a reduce phase
+// may have.
+const maxJobPhaseReducerCount = 1024
+
+var (
+	// jobPhaseMemorySizes are the values allowed for JobPhase.Memory, in MB.
+	jobPhaseMemorySizes = []uint64{256, 512, 1024, 2048, 4096, 8192}
+
+	// jobPhaseDiskSizes are the values allowed for JobPhase.Disk, in GB.
+	jobPhaseDiskSizes = []uint64{2, 4, 8, 16, 32, 64, 128, 256, 512, 1024}
+)
+
+// Validate checks the phase against the constraints documented for Manta
+// jobs, so that mistakes are reported with a descriptive error before a job
+// is submitted rather than rejected by the service. An empty Type is
+// treated as a map phase, as by Manta. Init, if set, must be a command in
+// its own right and must differ from Exec, since it runs once in each
+// compute zone before any task rather than once per task.
+func (p *JobPhase) Validate() error {
+	switch p.Type {
+	case "", JobPhaseTypeMap:
+		if p.ReducerCount != 0 {
+			return errors.New("ReducerCount may only be set for reduce phases")
+		}
+	case JobPhaseTypeReduce:
+		if p.ReducerCount > maxJobPhaseReducerCount {
+			return fmt.Errorf("ReducerCount must be at most %d, not %d", maxJobPhaseReducerCount, p.ReducerCount)
+		}
+	default:
+		return fmt.Errorf("Type must be %q or %q, not %q", JobPhaseTypeMap, JobPhaseTypeReduce, p.Type)
+	}
+
+	if strings.TrimSpace(p.Exec) == "" {
+		return errors.New("Exec must be set")
+	}
+	if p.Init != "" {
+		if strings.TrimSpace(p.Init) == "" {
+			return errors.New("Init must not be blank if set")
+		}
+		if strings.TrimSpace(p.Init) == strings.TrimSpace(p.Exec) {
+			return errors.New("Init must not be the same command as Exec")
+		}
+	}
+
+	if p.Memory != 0 && !containsUint64(jobPhaseMemorySizes, p.Memory) {
+		return fmt.Errorf("Memory must be one of %v MB, not %d", jobPhaseMemorySizes, p.Memory)
+	}
+	if p.Disk != 0 && !containsUint64(jobPhaseDiskSizes, p.Disk) {
+		return fmt.Errorf("Disk must be one of %v GB, not %d", jobPhaseDiskSizes, p.Disk)
+	}
+
+	return nil
+}
+
+// validateJobPhases validates each of the phases of a job, naming operation
+// in the error returned.
+func validateJobPhases(operation string, phases []*JobPhase) error {
+	if len(phases) == 0 {
+		return fmt.Errorf("At least one phase is required for %s", operation)
+	}
+	for i, phase := range phases {
+		if err := phase.Validate(); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("Invalid phase %d for %s: {{err}}", i, operation), err)
+		}
+	}
+	return nil
+}
+
+func containsUint64(values []uint64, value uint64) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // JobSummary represents the summary of a compute job in Manta.
@@ -56,7 +147,10 @@ type JobSummary struct {
 	ID           string    `json:"name"`
 }
 
-// Job represents a compute job in Manta.
+// Job represents a compute job in Manta. State is one of the JobState
+// constants. A job is finished once its State is JobStateDone, whether it
+// completed or was cancelled; InputDone reports whether its input has been
+// ended, and DoneTime is zero until the job is finished.
 type Job struct {
 	ID          string      `json:"id"`
 	Name        string      `json:"name"`
@@ -70,7 +164,12 @@ type Job struct {
 	Stats       *JobStats   `json:"stats"`
 }
 
-// JobStats represents statistics for a compute job in Manta.
+// JobStats represents statistics for a compute job in Manta. Errors is the
+// number of errors reported, Outputs the number of output objects, Retries
+// the number of tasks retried after failures within Manta, and Tasks and
+// TasksDone the number of tasks dispatched and completed so far. Tasks
+// grows as inputs are added and phases proceed, so it is only the final
+// number of tasks once the job is finished.
 type JobStats struct {
 	Errors    uint64 `json:"errors"`
 	Outputs   uint64 `json:"outputs"`
@@ -79,6 +178,45 @@ type JobStats struct {
 	TasksDone uint64 `json:"tasksDone"`
 }
 
+// TasksRemaining returns the number of tasks dispatched but not yet
+// completed. It returns 0 if s is nil.
+func (s *JobStats) TasksRemaining() uint64 {
+	if s == nil || s.TasksDone >= s.Tasks {
+		return 0
+	}
+	return s.Tasks - s.TasksDone
+}
+
+// PercentComplete returns the percentage of the tasks dispatched so far
+// which have completed, from 0 to 100. It returns 0 if s is nil or no tasks
+// have been dispatched. Since tasks are dispatched as the job proceeds, the
+// percentage may fall as well as rise; Job.PercentComplete also accounts
+// for whether the job is finished.
+func (s *JobStats) PercentComplete() float64 {
+	if s == nil || s.Tasks == 0 {
+		return 0
+	}
+	if s.TasksDone >= s.Tasks {
+		return 100
+	}
+	return 100 * float64(s.TasksDone) / float64(s.Tasks)
+}
+
+// PercentComplete returns the percentage of the tasks of the job which have
+// completed, as for JobStats.PercentComplete, except that a finished job is
+// always 100 percent complete and one which is not finished is never quite
+// complete, since further tasks may be dispatched.
+func (j *Job) PercentComplete() float64 {
+	if j.State == JobStateDone {
+		return 100
+	}
+	percent := j.Stats.PercentComplete()
+	if percent >= 100 {
+		return 99.9
+	}
+	return percent
+}
+
 // CreateJobInput represents parameters to a CreateJob operation.
 type CreateJobInput struct {
 	Name   string      `json:"name"`
@@ -91,8 +229,13 @@ type CreateJobOutput struct {
 }
 
 // CreateJob submits a new job to be executed. This call is not
-// idempotent, so calling it twice will create two jobs.
+// idempotent, so calling it twice will create two jobs. Each phase is checked
+// with JobPhase.Validate first, and no job is created if any is invalid.
 func (c *Client) CreateJob(input *CreateJobInput) (*CreateJobOutput, error) {
+	if err := validateJobPhases("CreateJob", input.Phases); err != nil {
+		return nil, err
+	}
+
 	path := fmt.Sprintf("/%s/jobs", c.accountName)
 
 	respBody, respHeaders, err := c.executeRequest(http.MethodPost, path, nil, nil, input)
@@ -114,21 +257,230 @@ func (c *Client) CreateJob(input *CreateJobInput) (*CreateJobOutput, error) {
 	return response, nil
 }
 
-// AddJobInputs represents parameters to a AddJobInputs operation.
+const (
+	DefaultAddJobInputsBatchSize   = 10000
+	DefaultAddJobInputsConcurrency = 1
+	DefaultAddJobInputsRetries     = 3
+)
+
+// AddJobInputsInput represents parameters to an AddJobInputs operation.
+// Inputs may be given in any combination of three ways, which are sent in
+// the order listed: ObjectPaths; ObjectReader, from which newline delimited
+// paths are read; and ObjectPathChan, from which paths are received until it
+// is closed by the sender. The latter two allow inputs produced by a
+// long-running source, such as Find, to be added to a job without holding
+// them all in memory.
 type AddJobInputsInput struct {
-	JobID       string
-	ObjectPaths []string
+	JobID          string
+	ObjectPaths    []string
+	ObjectReader   io.Reader
+	ObjectPathChan <-chan string
+
+	// BatchSize is the number of inputs sent in each request. If it is
+	// zero, DefaultAddJobInputsBatchSize is used. If it is negative, all of
+	// the inputs are sent in a single request.
+	BatchSize int
+
+	// Concurrency is the number of batches sent at once. If it is zero,
+	// DefaultAddJobInputsConcurrency is used, so that batches are sent
+	// one at a time in order.
+	Concurrency int
+
+	// Retries is the number of times a failed batch is sent again. If it is
+	// zero, DefaultAddJobInputsRetries is used, and if it is negative,
+	// batches are not retried. A retried batch may be submitted twice, as
+	// described for AddJobInputs.
+	Retries int
 }
 
-// AddJobInputs submits inputs to an already created job.
+// AddJobInputs submits inputs to an already created job. The inputs are
+// divided into batches of BatchSize, which are each retried on failure, so
+// that very large numbers of inputs may be added without building a single
+// enormous request. Batches are sent one at a time unless Concurrency is
+// greater than one, in which case Manta may receive the inputs in a
+// different order from that given. Paths are read from ObjectReader and
+// ObjectPathChan only as batches are sent, so at most Concurrency batches
+// are held in memory.
+//
+// A batch is retried in full, so if a request fails after Manta has added
+// its inputs, for instance because the connection is lost before the
+// response arrives, those inputs are added to the job again and processed
+// twice. Set Retries to a negative value if this cannot be tolerated.
+//
+// If a batch fails, no further batches are sent and the error is returned;
+// batches sent before the failure remain added to the job. The rest of
+// ObjectPathChan is drained and discarded so that the sender is not left
+// blocked.
+//
+// If BatchSize is negative and ObjectReader or ObjectPathChan is set, the
+// inputs are instead streamed to Manta as they are read, in a single
+// request which cannot be retried.
 func (c *Client) AddJobInputs(input *AddJobInputsInput) error {
+	if input.BatchSize < 0 {
+		return c.addJobInputsSingle(input)
+	}
+
+	batchSize := input.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultAddJobInputsBatchSize
+	}
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultAddJobInputsConcurrency
+	}
+	retries := input.Retries
+	if retries == 0 {
+		retries = DefaultAddJobInputsRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+
+	path := fmt.Sprintf("/%s/jobs/%s/live/in", c.accountName, input.JobID)
+
+	var lock sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		lock.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		lock.Unlock()
+	}
+	getErr := func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		return firstErr
+	}
+
+	work := make(chan []string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range work {
+				if getErr() != nil {
+					continue
+				}
+				err := withRetries(retries, func() error {
+					return c.postJobInputs(path, batch)
+				})
+				if err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+
+	// send queues the batch being built once it is full, or when flush is
+	// set, and reports whether more batches should be sent.
+	batch := make([]string, 0, batchSize)
+	send := func(flush bool) bool {
+		if len(batch) == 0 || (!flush && len(batch) < batchSize) {
+			return getErr() == nil
+		}
+		if getErr() != nil {
+			return false
+		}
+		work <- batch
+		batch = make([]string, 0, batchSize)
+		return true
+	}
+	add := func(objectPath string) bool {
+		if objectPath == "" {
+			return true
+		}
+		batch = append(batch, objectPath)
+		return send(false)
+	}
+
+	more := true
+	for _, objectPath := range input.ObjectPaths {
+		if more = add(objectPath); !more {
+			break
+		}
+	}
+	if more && input.ObjectReader != nil {
+		scanner := bufio.NewScanner(input.ObjectReader)
+		for more && scanner.Scan() {
+			more = add(strings.TrimSpace(scanner.Text()))
+		}
+		if err := scanner.Err(); err != nil {
+			setErr(err)
+			more = false
+		}
+	}
+	if input.ObjectPathChan != nil {
+		for objectPath := range input.ObjectPathChan {
+			if more {
+				more = add(objectPath)
+			}
+		}
+	}
+	if more {
+		send(true)
+	}
+	close(work)
+	wg.Wait()
+
+	if err := getErr(); err != nil {
+		return errwrap.Wrapf("Error executing AddJobInputs request: {{err}}", err)
+	}
+	return nil
+}
+
+// postJobInputs sends a batch of inputs to the live input of a job at path.
+func (c *Client) postJobInputs(path string, objectPaths []string) error {
+	headers := &http.Header{}
+	headers.Set("Content-Type", "text/plain")
+	reader := strings.NewReader(strings.Join(objectPaths, "\n"))
+
+	respBody, _, err := c.executeRequestNoEncode(http.MethodPost, path, nil, headers, reader)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	return err
+}
+
+// addJobInputsSingle sends all of the inputs to a job in a single request,
+// streaming them if ObjectReader or ObjectPathChan is set.
+func (c *Client) addJobInputsSingle(input *AddJobInputsInput) error {
 	path := fmt.Sprintf("/%s/jobs/%s/live/in", c.accountName, input.JobID)
 	headers := &http.Header{}
 	headers.Set("Content-Type", "text/plain")
 
-	reader := strings.NewReader(strings.Join(input.ObjectPaths, "\n"))
+	if input.ObjectReader == nil && input.ObjectPathChan == nil {
+		if err := c.postJobInputs(path, input.ObjectPaths); err != nil {
+			return errwrap.Wrapf("Error executing AddJobInputs request: {{err}}", err)
+		}
+		return nil
+	}
 
-	respBody, _, err := c.executeRequestNoEncode(http.MethodPost, path, nil, headers, reader)
+	var readers []io.Reader
+	if len(input.ObjectPaths) != 0 {
+		readers = append(readers, strings.NewReader(strings.Join(input.ObjectPaths, "\n")+"\n"))
+	}
+	if input.ObjectReader != nil {
+		// A final line without a newline would run into the next.
+		readers = append(readers, input.ObjectReader, strings.NewReader("\n"))
+	}
+	if input.ObjectPathChan != nil {
+		pipeReader, pipeWriter := io.Pipe()
+		defer pipeReader.Close()
+		go func() {
+			for objectPath := range input.ObjectPathChan {
+				if _, err := io.WriteString(pipeWriter, objectPath+"\n"); err != nil {
+					for range input.ObjectPathChan {
+					}
+					return
+				}
+			}
+			pipeWriter.Close()
+		}()
+		readers = append(readers, pipeReader)
+	}
+
+	respBody, _, err := c.executeRequestStream(http.MethodPost, path, nil, headers, io.MultiReader(readers...))
 	if respBody != nil {
 		defer respBody.Close()
 	}
@@ -187,8 +539,16 @@ func (c *Client) CancelJob(input *CancelJobInput) error {
 }
 
 // ListJobsInput represents parameters to a ListJobs operation.
+//
+// State restricts the jobs listed to those in the given state, which is
+// JobStateRunning or JobStateDone; if it is empty, jobs in every state are
+// listed. RunningOnly is equivalent to setting State to JobStateRunning, and
+// is kept for compatibility. Name restricts the jobs listed to those with the
+// given name.
 type ListJobsInput struct {
 	RunningOnly bool
+	State       string
+	Name        string
 	Limit       uint64
 	Marker      string
 }
@@ -199,18 +559,28 @@ type ListJobsOutput struct {
 	ResultSetSize uint64
 }
 
-// ListJobs returns the list of jobs you currently have.
+// ListJobs returns the list of jobs you currently have. Jobs are returned a
+// page at a time, starting from the job named Marker if it is set, so that
+// the first job of each subsequent page is the last job of the previous one;
+// JobIterator follows the pages automatically.
 func (c *Client) ListJobs(input *ListJobsInput) (*ListJobsOutput, error) {
 	path := fmt.Sprintf("/%s/jobs", c.accountName)
 	query := &url.Values{}
+	state := input.State
 	if input.RunningOnly {
-		query.Set("state", "running")
+		state = JobStateRunning
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	if input.Name != "" {
+		query.Set("name", input.Name)
 	}
 	if input.Limit != 0 {
 		query.Set("limit", strconv.FormatUint(input.Limit, 10))
 	}
 	if input.Marker != "" {
-		query.Set("manta_path", input.Marker)
+		query.Set("marker", input.Marker)
 	}
 
 	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, query, nil, nil)
@@ -222,10 +592,10 @@ func (c *Client) ListJobs(input *ListJobsInput) (*ListJobsOutput, error) {
 	}
 
 	var results []*JobSummary
+	decoder := json.NewDecoder(respBody)
 	for {
 		current := &JobSummary{}
-		decoder := json.NewDecoder(respBody)
-		if err = decoder.Decode(&current); err != nil {
+		if err = decoder.Decode(current); err != nil {
 			if err == io.EOF {
 				break
 			}
@@ -256,7 +626,9 @@ type GetJobOutput struct {
 	Job *Job
 }
 
-// GetJob returns the list of jobs you currently have.
+// GetJob returns the current status of a job, including its state, phases
+// and statistics about the tasks it has run, so that callers may check
+// whether a job has finished and whether it produced errors.
 func (c *Client) GetJob(input *GetJobInput) (*GetJobOutput, error) {
 	path := fmt.Sprintf("/%s/jobs/%s/live/status", c.accountName, input.JobID)
 
@@ -270,7 +642,7 @@ func (c *Client) GetJob(input *GetJobInput) (*GetJobOutput, error) {
 
 	job := &Job{}
 	decoder := json.NewDecoder(respBody)
-	if err = decoder.Decode(&job); err != nil {
+	if err = decoder.Decode(job); err != nil {
 		return nil, errwrap.Wrapf("Error decoding GetJob response: {{err}}", err)
 	}
 
@@ -279,6 +651,213 @@ func (c *Client) GetJob(input *GetJobInput) (*GetJobOutput, error) {
 	}, nil
 }
 
+// ListJobOutputsInput represents parameters to a ListJobOutputs operation.
+// Limit and Marker page through the outputs as for ListDirectory.
+type ListJobOutputsInput struct {
+	JobID  string
+	Limit  uint64
+	Marker string
+}
+
+// ListJobOutputsOutput contains the outputs of a ListJobOutputs operation.
+// Outputs holds the paths of the objects produced by the final phase of the
+// job. ResultSetSize is the total number of outputs, not only those
+// returned.
+type ListJobOutputsOutput struct {
+	Outputs       []string
+	ResultSetSize uint64
+}
+
+// ListJobOutputs returns the paths of the output objects produced by a job so
+// far. The objects may be read with GetObject.
+func (c *Client) ListJobOutputs(input *ListJobOutputsInput) (*ListJobOutputsOutput, error) {
+	outputs, resultSetSize, err := c.listJobLines(input.JobID, "out", input.Limit, input.Marker)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListJobOutputs request: {{err}}", err)
+	}
+
+	return &ListJobOutputsOutput{
+		Outputs:       outputs,
+		ResultSetSize: resultSetSize,
+	}, nil
+}
+
+// listJobLines requests one of the live lists of a job, which Manta returns
+// as one item per line, and returns the items along with the value of the
+// Result-Set-Size header.
+func (c *Client) listJobLines(jobID, list string, limit uint64, marker string) ([]string, uint64, error) {
+	path := fmt.Sprintf("/%s/jobs/%s/live/%s", c.accountName, jobID, list)
+	query := &url.Values{}
+	if limit != 0 {
+		query.Set("limit", strconv.FormatUint(limit, 10))
+	}
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+
+	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, query, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(respBody)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	resultSetSize, _ := strconv.ParseUint(respHeader.Get("Result-Set-Size"), 10, 64)
+	return lines, resultSetSize, nil
+}
+
+// JobError describes an error which occurred while running a task of a job.
+// Phase is the index of the phase in which it occurred, What is a human
+// readable description of where it occurred, and Code and Message describe
+// the error. Stderr and Core are the paths of objects holding the standard
+// error and any core file of the failed task, Input is the path of the
+// input object of the task and Phase0Input the path of the input to the job
+// from which it derived.
+type JobError struct {
+	Phase       string `json:"phase"`
+	What        string `json:"what"`
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	Stderr      string `json:"stderr,omitempty"`
+	Core        string `json:"core,omitempty"`
+	Input       string `json:"input,omitempty"`
+	Phase0Input string `json:"p0input,omitempty"`
+}
+
+// Codes of the errors reported for the tasks of jobs, as found in
+// JobError.Code.
+const (
+	// JobErrorCodeUserTask means that the command run by the task exited
+	// with a non-zero status or was killed by a signal.
+	JobErrorCodeUserTask = "UserTaskError"
+
+	// JobErrorCodeTaskInit means that the task could not be started, for
+	// example because an asset was missing or the Init command failed.
+	JobErrorCodeTaskInit = "TaskInitError"
+
+	// JobErrorCodeTaskKilled means that the task was killed, usually for
+	// exceeding the resources allocated to its phase.
+	JobErrorCodeTaskKilled = "TaskKilledError"
+
+	// JobErrorCodeResourceNotFound means that an input object, or an
+	// object named by a phase, does not exist.
+	JobErrorCodeResourceNotFound = "ResourceNotFoundError"
+
+	// JobErrorCodeInvalidArgument means that an input was not valid, such
+	// as a path which does not name an object.
+	JobErrorCodeInvalidArgument = "InvalidArgumentError"
+
+	// JobErrorCodeAuthorizationFailed means that the job is not permitted
+	// to read an input object.
+	JobErrorCodeAuthorizationFailed = "AuthorizationFailedError"
+
+	// JobErrorCodeJobCancelled means that the task did not run, or did not
+	// complete, because the job was cancelled.
+	JobErrorCodeJobCancelled = "JobCancelledError"
+
+	// JobErrorCodeInternal means that Manta failed to run the task for
+	// reasons of its own.
+	JobErrorCodeInternal = "InternalError"
+)
+
+// Error implements interface Error on the *JobError type, so that the
+// Is...Error functions, such as IsUserTaskError, may be used to check the
+// Code of a JobError. JobErrors are always handled as *JobError, as
+// returned by ListJobErrors.
+func (e *JobError) Error() string {
+	if e.Input != "" {
+		return fmt.Sprintf("%s: %s (input %s)", e.Code, e.Message, e.Input)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Retryable returns true if the error is likely to be transient, so that
+// running the task again with the same input may succeed. Errors caused by
+// the job's commands, inputs or phases are not retryable, since they are
+// expected to recur until the job is changed. This is only a hint: a
+// UserTaskError may, for example, be caused by a flaky network service.
+func (e *JobError) Retryable() bool {
+	switch strings.TrimSuffix(e.Code, "Error") {
+	case strings.TrimSuffix(JobErrorCodeInternal, "Error"):
+		return true
+	default:
+		return false
+	}
+}
+
+// ListJobErrorsInput represents parameters to a ListJobErrors operation.
+// Limit and Marker page through the errors as for ListDirectory, with the
+// Input of the last error of one page as the Marker of the next.
+type ListJobErrorsInput struct {
+	JobID  string
+	Limit  uint64
+	Marker string
+}
+
+// ListJobErrorsOutput contains the outputs of a ListJobErrors operation.
+// ResultSetSize is the total number of errors, not only those returned.
+type ListJobErrorsOutput struct {
+	Errors        []*JobError
+	ResultSetSize uint64
+}
+
+// ListJobErrors returns the errors which have occurred while running a job
+// so far.
+func (c *Client) ListJobErrors(input *ListJobErrorsInput) (*ListJobErrorsOutput, error) {
+	path := fmt.Sprintf("/%s/jobs/%s/live/err", c.accountName, input.JobID)
+	query := &url.Values{}
+	if input.Limit != 0 {
+		query.Set("limit", strconv.FormatUint(input.Limit, 10))
+	}
+	if input.Marker != "" {
+		query.Set("marker", input.Marker)
+	}
+
+	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, query, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListJobErrors request: {{err}}", err)
+	}
+
+	var results []*JobError
+	decoder := json.NewDecoder(respBody)
+	for {
+		current := &JobError{}
+		if err = decoder.Decode(current); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errwrap.Wrapf("Error decoding ListJobErrors response: {{err}}", err)
+		}
+		results = append(results, current)
+	}
+
+	output := &ListJobErrorsOutput{
+		Errors: results,
+	}
+
+	resultSetSize, err := strconv.ParseUint(respHeader.Get("Result-Set-Size"), 10, 64)
+	if err == nil {
+		output.ResultSetSize = resultSetSize
+	}
+
+	return output, nil
+}
+
 // GetJobOutputInput represents parameters to a GetJobOutput operation.
 type GetJobOutputInput struct {
 	JobID string
@@ -298,9 +877,6 @@ func (c *Client) GetJobOutput(input *GetJobOutputInput) (*GetJobOutputOutput, er
 	path := fmt.Sprintf("/%s/jobs/%s/live/out", c.accountName, input.JobID)
 
 	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, nil, nil, nil)
-	if respBody != nil {
-		defer respBody.Close()
-	}
 	if err != nil {
 		return nil, errwrap.Wrapf("Error executing GetJobOutput request: {{err}}", err)
 	}
@@ -317,12 +893,42 @@ func (c *Client) GetJobOutput(input *GetJobOutputInput) (*GetJobOutputOutput, er
 	return output, nil
 }
 
-// GetJobInputInput represents parameters to a GetJobOutput operation.
+// ListJobInputsInput represents parameters to a ListJobInputs operation.
+// Limit and Marker page through the inputs as for ListDirectory, so that the
+// inputs of a job fed a very large number of objects may be listed in parts.
+type ListJobInputsInput struct {
+	JobID  string
+	Limit  uint64
+	Marker string
+}
+
+// ListJobInputsOutput contains the outputs of a ListJobInputs operation.
+// ResultSetSize is the total number of inputs, not only those returned.
+type ListJobInputsOutput struct {
+	Inputs        []string
+	ResultSetSize uint64
+}
+
+// ListJobInputs returns the paths of the input objects which have been
+// added to a job.
+func (c *Client) ListJobInputs(input *ListJobInputsInput) (*ListJobInputsOutput, error) {
+	inputs, resultSetSize, err := c.listJobLines(input.JobID, "in", input.Limit, input.Marker)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListJobInputs request: {{err}}", err)
+	}
+
+	return &ListJobInputsOutput{
+		Inputs:        inputs,
+		ResultSetSize: resultSetSize,
+	}, nil
+}
+
+// GetJobInputInput represents parameters to a GetJobInput operation.
 type GetJobInputInput struct {
 	JobID string
 }
 
-// GetJobInputOutput contains the outputs for a GetJobOutput operation. It is your
+// GetJobInputOutput contains the outputs for a GetJobInput operation. It is your
 // responsibility to ensure that the io.ReadCloser Items is closed.
 type GetJobInputOutput struct {
 	ResultSetSize uint64
@@ -336,9 +942,6 @@ func (c *Client) GetJobInput(input *GetJobInputInput) (*GetJobInputOutput, error
 	path := fmt.Sprintf("/%s/jobs/%s/live/in", c.accountName, input.JobID)
 
 	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, nil, nil, nil)
-	if respBody != nil {
-		defer respBody.Close()
-	}
 	if err != nil {
 		return nil, errwrap.Wrapf("Error executing GetJobInput request: {{err}}", err)
 	}
@@ -355,6 +958,38 @@ func (c *Client) GetJobInput(input *GetJobInputInput) (*GetJobInputOutput, error
 	return output, nil
 }
 
+// ListJobFailuresInput represents parameters to a ListJobFailures
+// operation. Limit and Marker page through the failures as for
+// ListDirectory.
+type ListJobFailuresInput struct {
+	JobID  string
+	Limit  uint64
+	Marker string
+}
+
+// ListJobFailuresOutput contains the outputs of a ListJobFailures
+// operation. ResultSetSize is the total number of failed inputs, not only
+// those returned.
+type ListJobFailuresOutput struct {
+	Failures      []string
+	ResultSetSize uint64
+}
+
+// ListJobFailures returns the paths of the input objects of a job whose
+// processing has failed so far, so that they may be set aside or given to
+// another job. ListJobErrors describes why they failed.
+func (c *Client) ListJobFailures(input *ListJobFailuresInput) (*ListJobFailuresOutput, error) {
+	failures, resultSetSize, err := c.listJobLines(input.JobID, "fail", input.Limit, input.Marker)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListJobFailures request: {{err}}", err)
+	}
+
+	return &ListJobFailuresOutput{
+		Failures:      failures,
+		ResultSetSize: resultSetSize,
+	}, nil
+}
+
 // GetJobFailuresInput represents parameters to a GetJobFailures operation.
 type GetJobFailuresInput struct {
 	JobID string
@@ -367,16 +1002,13 @@ type GetJobFailuresOutput struct {
 	Items         io.ReadCloser
 }
 
-// GetJobFailures returns the current "live" set of outputs from a job. Think of
+// GetJobFailures returns the current "live" set of failed inputs from a job. Think of
 // this like `tail -f`. If error is nil (i.e. the operation is successful), it is
 // your responsibility to close the io.ReadCloser named Items in the output.
 func (c *Client) GetJobFailures(input *GetJobFailuresInput) (*GetJobFailuresOutput, error) {
 	path := fmt.Sprintf("/%s/jobs/%s/live/fail", c.accountName, input.JobID)
 
 	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, nil, nil, nil)
-	if respBody != nil {
-		defer respBody.Close()
-	}
 	if err != nil {
 		return nil, errwrap.Wrapf("Error executing GetJobFailures request: {{err}}", err)
 	}
@@ -0,0 +1,184 @@
+package manta
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// DefaultJobAssetPrefix is the directory, relative to the account's /stor
+// directory, below which UploadJobAssets creates a directory for the assets
+// of each job.
+const DefaultJobAssetPrefix = "job-assets"
+
+// JobAssetFile describes a local file to be uploaded as a job asset. Name is
+// the name of the asset object, which must not contain a slash and defaults
+// to the base name of FilePath. Phases holds the indexes of the phases which
+// use the asset; if it is empty, the asset is added to every phase.
+type JobAssetFile struct {
+	FilePath string
+	Name     string
+	Phases   []int
+}
+
+// UploadJobAssetsInput represents parameters to an UploadJobAssets
+// operation. Prefix is relative to the account's /stor directory; if it is
+// empty, DefaultJobAssetPrefix is used.
+type UploadJobAssetsInput struct {
+	Prefix string
+	Phases []*JobPhase
+	Files  []*JobAssetFile
+}
+
+// UploadJobAssetsOutput contains the outputs of an UploadJobAssets
+// operation. DirectoryName is the directory holding the assets, relative to
+// the account's /stor directory, and Assets holds the full Manta path of
+// each asset, in the same order as Files.
+type UploadJobAssetsOutput struct {
+	DirectoryName string
+	Assets        []string
+}
+
+// UploadJobAssets uploads local files into a newly created directory below
+// Prefix and adds the Manta path of each to the Assets of the phases which
+// use it, so that the phases may be passed to CreateJob. The directory is
+// named randomly, since the ID of the job is not known until it is created.
+// Once the job is done, the assets may be removed with DeleteJobAssets.
+//
+// If an upload fails, the assets already uploaded are deleted and the phases
+// are left unchanged.
+func (c *Client) UploadJobAssets(input *UploadJobAssetsInput) (*UploadJobAssetsOutput, error) {
+	names := map[string]bool{}
+	for _, file := range input.Files {
+		name := jobAssetName(file)
+		if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("Invalid asset name for %q", file.FilePath)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("Duplicate asset name %q", name)
+		}
+		names[name] = true
+
+		for _, index := range file.Phases {
+			if index < 0 || index >= len(input.Phases) {
+				return nil, fmt.Errorf("Asset %q refers to phase %d, but there are %d phases", name, index, len(input.Phases))
+			}
+		}
+	}
+
+	prefix := input.Prefix
+	if prefix == "" {
+		prefix = DefaultJobAssetPrefix
+	}
+
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, errwrap.Wrapf("Error executing UploadJobAssets request: {{err}}", err)
+	}
+	directoryName := path.Join(strings.Trim(path.Clean("/"+prefix), "/"), hex.EncodeToString(id))
+
+	if err := c.PutDirectoryRecursive(&PutDirectoryInput{
+		DirectoryName: directoryName,
+	}); err != nil {
+		return nil, errwrap.Wrapf("Error executing UploadJobAssets request: {{err}}", err)
+	}
+
+	output := &UploadJobAssetsOutput{
+		DirectoryName: directoryName,
+	}
+	var objectPaths []string
+	for _, file := range input.Files {
+		objectPath := path.Join(directoryName, jobAssetName(file))
+		if err := c.PutFile(&PutFileInput{
+			ObjectPath: objectPath,
+			FilePath:   file.FilePath,
+		}); err != nil {
+			c.deleteJobAssets(directoryName, objectPaths)
+			return nil, errwrap.Wrapf("Error executing UploadJobAssets request: {{err}}", err)
+		}
+		objectPaths = append(objectPaths, objectPath)
+		output.Assets = append(output.Assets, fmt.Sprintf("/%s/stor/%s", c.accountName, objectPath))
+	}
+
+	for i, file := range input.Files {
+		phases := file.Phases
+		if len(phases) == 0 {
+			for index := range input.Phases {
+				phases = append(phases, index)
+			}
+		}
+		for _, index := range phases {
+			input.Phases[index].Assets = append(input.Phases[index].Assets, output.Assets[i])
+		}
+	}
+
+	return output, nil
+}
+
+// jobAssetName returns the name of the asset object for file.
+func jobAssetName(file *JobAssetFile) string {
+	if file.Name != "" {
+		return file.Name
+	}
+	return filepath.Base(file.FilePath)
+}
+
+// DeleteJobAssetsInput represents parameters to a DeleteJobAssets operation.
+// DirectoryName is as returned by UploadJobAssets.
+type DeleteJobAssetsInput struct {
+	DirectoryName string
+}
+
+// DeleteJobAssets deletes the assets uploaded by UploadJobAssets and the
+// directory which holds them. It should only be called once the jobs which
+// use the assets are done.
+func (c *Client) DeleteJobAssets(input *DeleteJobAssetsInput) error {
+	directoryName := strings.Trim(path.Clean("/"+input.DirectoryName), "/")
+	if directoryName == "" {
+		return errors.New("DirectoryName must be set for DeleteJobAssets")
+	}
+
+	var objectPaths []string
+	err := c.Walk(directoryName, func(entryPath string, entry *DirectoryEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type == EntryTypeObject {
+			objectPaths = append(objectPaths, entryPath)
+		}
+		return nil
+	})
+	if err == nil {
+		err = c.deleteJobAssets(directoryName, objectPaths)
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing DeleteJobAssets request: {{err}}", err)
+	}
+	return nil
+}
+
+// deleteJobAssets deletes the asset objects at objectPaths and then the
+// directory which holds them.
+func (c *Client) deleteJobAssets(directoryName string, objectPaths []string) error {
+	deleted, err := c.DeleteObjects(&DeleteObjectsInput{
+		ObjectPaths: objectPaths,
+	})
+	if err != nil {
+		return deleted.Failed()[0].Error
+	}
+
+	err = c.DeleteDirectory(&DeleteDirectoryInput{
+		DirectoryName: directoryName,
+	})
+	if err != nil && !IsResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package manta
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// AddFindJobInputsInput represents parameters to an AddFindJobInputs
+// operation. Find selects the inputs, of which only objects are added.
+// BatchSize is the number of inputs sent in each request; if it is zero,
+// DefaultAddJobInputsBatchSize is used.
+type AddFindJobInputsInput struct {
+	JobID     string
+	Find      *FindInput
+	BatchSize int
+}
+
+// AddFindJobInputsOutput contains the outputs of an AddFindJobInputs
+// operation. Inputs is the number of inputs added to the job.
+type AddFindJobInputsOutput struct {
+	Inputs uint64
+}
+
+// AddFindJobInputs runs a Find and adds each matching object to a job as it
+// is found, then ends the input of the job, like `mfind | mjob addinputs`.
+// Directories which match are skipped, since they cannot be job inputs.
+//
+// If the search or adding inputs fails, the error is returned and the input
+// of the job is left open, with the inputs added before the failure.
+func (c *Client) AddFindJobInputs(input *AddFindJobInputsInput) (*AddFindJobInputsOutput, error) {
+	batchSize := input.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultAddJobInputsBatchSize
+	}
+
+	output := &AddFindJobInputsOutput{}
+	batch := make([]string, 0, batchSize)
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := c.AddJobInputs(&AddJobInputsInput{
+			JobID:       input.JobID,
+			ObjectPaths: batch,
+			BatchSize:   batchSize,
+		}); err != nil {
+			return err
+		}
+		output.Inputs += uint64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	find := &FindInput{}
+	if input.Find != nil {
+		*find = *input.Find
+	}
+	if find.Type == "" {
+		find.Type = EntryTypeObject
+	}
+
+	err := c.Find(find, func(entryPath string, entry *DirectoryEntry) error {
+		if entry.Type != EntryTypeObject {
+			return nil
+		}
+		batch = append(batch, fmt.Sprintf("/%s/stor/%s", c.accountName, entryPath))
+		if len(batch) < batchSize {
+			return nil
+		}
+		return send()
+	})
+	if err == nil {
+		err = send()
+	}
+	if err == nil {
+		err = c.EndJobInput(&EndJobInputInput{
+			JobID: input.JobID,
+		})
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing AddFindJobInputs request: {{err}}", err)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,153 @@
+package manta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// The platform of Manta compute zones, for which Go programs run as job
+// assets must be built.
+const (
+	DefaultGoJobAssetGOOS   = "illumos"
+	DefaultGoJobAssetGOARCH = "amd64"
+)
+
+// UploadGoJobAssetInput represents parameters to an UploadGoJobAsset
+// operation. The program is the prebuilt binary at BinaryPath if it is set,
+// and is otherwise built from the main package Package, which is given as
+// to `go build`, such as an import path or "./cmd/tool".
+type UploadGoJobAssetInput struct {
+	Package    string
+	BinaryPath string
+
+	// GOOS and GOARCH are the platform for which Package is built. If
+	// they are empty, DefaultGoJobAssetGOOS and DefaultGoJobAssetGOARCH
+	// are used. BuildFlags are passed to `go build` before the package.
+	GOOS       string
+	GOARCH     string
+	BuildFlags []string
+
+	// Name is the name of the asset and of the program in the compute
+	// zone. If it is empty, the base name of BinaryPath or Package is
+	// used. Prefix is as for UploadJobAssets.
+	Name   string
+	Prefix string
+
+	// Args are the arguments with which the program is run by Exec. They
+	// are quoted, so are passed to the program as given.
+	Args []string
+}
+
+// UploadGoJobAssetOutput contains the outputs of an UploadGoJobAsset
+// operation. Assets, Init and Exec are the values for the phase which runs
+// the program, as accepted by NewMapPhase and NewReducePhase through
+// JobPhaseOptions. DirectoryName may be passed to DeleteJobAssets once the
+// job is done.
+type UploadGoJobAssetOutput struct {
+	DirectoryName string
+	Assets        []string
+	Init          string
+	Exec          string
+}
+
+// UploadGoJobAsset uploads a Go program as a job asset, building it for
+// the platform of the compute zones first unless a binary is given, and
+// returns the phase settings which run it. Since assets are mounted read
+// only, the Init command copies the program to /var/tmp and makes it
+// executable, and Exec runs it from there with Args.
+//
+// Building requires the go command, and programs which use cgo cannot be
+// built in this way.
+func (c *Client) UploadGoJobAsset(input *UploadGoJobAssetInput) (*UploadGoJobAssetOutput, error) {
+	if input.Package == "" && input.BinaryPath == "" {
+		return nil, errors.New("Either Package or BinaryPath must be set for UploadGoJobAsset")
+	}
+
+	name := input.Name
+	if name == "" {
+		if input.BinaryPath != "" {
+			name = filepath.Base(input.BinaryPath)
+		} else {
+			name = path.Base(filepath.ToSlash(strings.TrimSuffix(input.Package, "/...")))
+		}
+		if name == "." || name == ".." || name == "/" {
+			return nil, fmt.Errorf("Name must be set for UploadGoJobAsset, since none can be derived from %q", input.Package)
+		}
+	}
+
+	binaryPath := input.BinaryPath
+	if binaryPath == "" {
+		dir, err := ioutil.TempDir("", "manta-go-asset")
+		if err != nil {
+			return nil, errwrap.Wrapf("Error executing UploadGoJobAsset request: {{err}}", err)
+		}
+		defer os.RemoveAll(dir)
+
+		binaryPath = filepath.Join(dir, name)
+		if err := buildGoJobAsset(input, binaryPath); err != nil {
+			return nil, errwrap.Wrapf("Error executing UploadGoJobAsset request: {{err}}", err)
+		}
+	}
+
+	assets, err := c.UploadJobAssets(&UploadJobAssetsInput{
+		Prefix: input.Prefix,
+		Files: []*JobAssetFile{
+			{
+				FilePath: binaryPath,
+				Name:     name,
+			},
+		},
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing UploadGoJobAsset request: {{err}}", err)
+	}
+
+	asset := assets.Assets[0]
+	program := "/var/tmp/" + name
+	command := []string{shellQuote(program)}
+	for _, arg := range input.Args {
+		command = append(command, shellQuote(arg))
+	}
+
+	return &UploadGoJobAssetOutput{
+		DirectoryName: assets.DirectoryName,
+		Assets:        assets.Assets,
+		Init:          fmt.Sprintf("cp %s %s && chmod 755 %s", shellQuote("/assets"+asset), shellQuote(program), shellQuote(program)),
+		Exec:          strings.Join(command, " "),
+	}, nil
+}
+
+// buildGoJobAsset builds the package of input for the compute zones,
+// writing the binary to binaryPath.
+func buildGoJobAsset(input *UploadGoJobAssetInput, binaryPath string) error {
+	goos := input.GOOS
+	if goos == "" {
+		goos = DefaultGoJobAssetGOOS
+	}
+	goarch := input.GOARCH
+	if goarch == "" {
+		goarch = DefaultGoJobAssetGOARCH
+	}
+
+	args := []string{"build", "-o", binaryPath}
+	args = append(args, input.BuildFlags...)
+	args = append(args, input.Package)
+
+	cmd := exec.Command("go", args...)
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error building %s: %s: %s", input.Package, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package manta
+
+// listJobsPageSize is the number of jobs requested per page by a
+// JobIterator if no Limit is given.
+const listJobsPageSize = 1024
+
+// JobIterator iterates over the jobs returned by ListJobs, requesting
+// further pages as each is exhausted, until every job has been returned.
+//
+// A JobIterator is used in the same way as a DirectoryIterator:
+//
+//	iterator := manta.NewJobIterator(client, &manta.ListJobsInput{
+//		State: manta.JobStateDone,
+//	})
+//	for iterator.Next() {
+//		job := iterator.Job()
+//		...
+//	}
+//	if err := iterator.Err(); err != nil {
+//		...
+//	}
+type JobIterator struct {
+	client *Client
+	input  ListJobsInput
+
+	page          []*JobSummary
+	requested     bool
+	resultSetSize uint64
+
+	job  *JobSummary
+	err  error
+	done bool
+}
+
+// NewJobIterator is used to construct a JobIterator over the jobs matching
+// the filters of input. The Limit of input, if set, is the number of jobs
+// requested per page, and iteration starts from Marker if it is set. Since
+// each page after the first repeats a job, a Limit of 1 is raised to 2. If
+// input is nil, every job is listed. No request is made until Next is first
+// called.
+func NewJobIterator(client *Client, input *ListJobsInput) *JobIterator {
+	iterator := &JobIterator{
+		client: client,
+	}
+	if input != nil {
+		iterator.input = *input
+	}
+	if iterator.input.Limit == 0 {
+		iterator.input.Limit = listJobsPageSize
+	} else if iterator.input.Limit == 1 {
+		iterator.input.Limit = 2
+	}
+	return iterator
+}
+
+// Next advances the iterator to the next job, which is then available from
+// Job. It returns false when there are no more jobs or an error occurs,
+// which is then available from Err.
+func (i *JobIterator) Next() bool {
+	for len(i.page) == 0 {
+		if i.done || i.err != nil {
+			return false
+		}
+		if i.err = i.requestPage(); i.err != nil {
+			return false
+		}
+	}
+
+	i.job = i.page[0]
+	i.page = i.page[1:]
+	return true
+}
+
+// requestPage requests the page of jobs which follows the last job
+// returned.
+func (i *JobIterator) requestPage() error {
+	output, err := i.client.ListJobs(&i.input)
+	if err != nil {
+		return err
+	}
+	i.resultSetSize = output.ResultSetSize
+
+	// Only a page shorter than requested is the last: a full page may
+	// hold nothing but the marker once it is skipped.
+	page := output.Jobs
+	if len(page) < int(i.input.Limit) {
+		i.done = true
+	}
+	if len(page) != 0 {
+		// Each page after the first starts with the last job of the
+		// previous page.
+		if i.requested && page[0].ID == i.input.Marker {
+			page = page[1:]
+		}
+		i.input.Marker = output.Jobs[len(output.Jobs)-1].ID
+	}
+	i.requested = true
+
+	i.page = page
+	return nil
+}
+
+// Job returns the job most recently returned by Next.
+func (i *JobIterator) Job() *JobSummary {
+	return i.job
+}
+
+// Err returns the error which stopped iteration, if any.
+func (i *JobIterator) Err() error {
+	return i.err
+}
+
+// ResultSetSize returns the total number of jobs matching the filters, as
+// reported by Manta with the most recent page of jobs.
+func (i *JobIterator) ResultSetSize() uint64 {
+	return i.resultSetSize
+}
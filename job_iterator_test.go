@@ -0,0 +1,73 @@
+package manta
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// handleTestJobs serves a listing of the given number of jobs from s.
+func handleTestJobs(s *testServer, jobs int) []string {
+	var names []string
+	for i := 0; i < jobs; i++ {
+		names = append(names, fmt.Sprintf("job-%04d", i))
+	}
+
+	s.handle("jobs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Result-Set-Size", strconv.Itoa(jobs))
+		writeTestDirectoryPage(w, r, names, func(name string) *DirectoryEntry {
+			return &DirectoryEntry{
+				Name:         name,
+				Type:         EntryTypeDirectory,
+				ModifiedTime: time.Now(),
+			}
+		})
+	})
+	return names
+}
+
+func TestJobIterator(t *testing.T) {
+	cases := []struct {
+		name     string
+		jobs     int
+		limit    uint64
+		requests int
+	}{
+		{name: "no jobs", jobs: 0, limit: 0, requests: 1},
+		{name: "single page", jobs: 5, limit: 0, requests: 1},
+		{name: "limit of one", jobs: 3, limit: 1, requests: 3},
+		{name: "limit of two", jobs: 3, limit: 2, requests: 3},
+		{name: "exact pages", jobs: 7, limit: 4, requests: 3},
+		{name: "partial page", jobs: 8, limit: 4, requests: 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			names := handleTestJobs(s, tc.jobs)
+
+			iterator := NewJobIterator(client, &ListJobsInput{
+				Limit: tc.limit,
+			})
+			var listed []string
+			for iterator.Next() {
+				listed = append(listed, iterator.Job().ID)
+			}
+			if err := iterator.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(listed) != fmt.Sprint(names) {
+				t.Errorf("expected %v, got %v", names, listed)
+			}
+			if iterator.ResultSetSize() != uint64(tc.jobs) {
+				t.Errorf("expected ResultSetSize %d, got %d", tc.jobs, iterator.ResultSetSize())
+			}
+			if requests := s.countRequests("GET jobs"); requests != tc.requests {
+				t.Errorf("expected %d requests, got %d", tc.requests, requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package manta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// ReadJobManifest reads a job manifest in the JSON format accepted by
+// `mjob create -f`, which is either an object with "name" and "phases"
+// members or an array of phases alone. The job it describes may be passed
+// to CreateJob. Each phase is checked with JobPhase.Validate.
+func ReadJobManifest(r io.Reader) (*CreateJobInput, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error reading job manifest: {{err}}", err)
+	}
+
+	manifest := &CreateJobInput{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &manifest.Phases)
+	} else {
+		err = json.Unmarshal(data, manifest)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error decoding job manifest: {{err}}", err)
+	}
+
+	if len(manifest.Phases) == 0 {
+		return nil, errors.New("Job manifest must have at least one phase")
+	}
+	for i, phase := range manifest.Phases {
+		if phase == nil {
+			return nil, fmt.Errorf("Invalid phase %d in job manifest: phase is null", i)
+		}
+		if err := phase.Validate(); err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("Invalid phase %d in job manifest: {{err}}", i), err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// WriteJobManifest writes the job described by manifest in the JSON format
+// accepted by `mjob create -f`, so that it may be shared with node-manta
+// tools or read back with ReadJobManifest. The manifest of an existing job
+// may be written by setting Name and Phases from the Job returned by
+// GetJob.
+func WriteJobManifest(w io.Writer, manifest *CreateJobInput) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errwrap.Wrapf("Error encoding job manifest: {{err}}", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return errwrap.Wrapf("Error writing job manifest: {{err}}", err)
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+package manta
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// listJobLinesPageSize is the number of items requested per page when
+// listing all of the items of a live list of a job.
+const listJobLinesPageSize = 1024
+
+// listAllJobLines returns every item of one of the live lists of a job,
+// requesting them a page at a time. As with directories, the first item of
+// each subsequent page is the marker, which is skipped.
+func (c *Client) listAllJobLines(jobID, list string) ([]string, error) {
+	var lines []string
+	marker := ""
+	for {
+		page, _, err := c.listJobLines(jobID, list, listJobLinesPageSize, marker)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range page {
+			if marker != "" && line == marker {
+				continue
+			}
+			lines = append(lines, line)
+		}
+
+		if len(page) < listJobLinesPageSize {
+			return lines, nil
+		}
+		marker = page[len(page)-1]
+	}
+}
+
+// listAllJobErrors returns every error reported for the tasks of a job,
+// requesting them a page at a time. Errors are listed by input, so the
+// Input of the last error of each page is the marker for the next, and
+// errors at the start of a page which were already listed are skipped.
+func (c *Client) listAllJobErrors(jobID string) ([]*JobError, error) {
+	var jobErrors []*JobError
+	seen := map[JobError]bool{}
+	marker := ""
+	for {
+		output, err := c.ListJobErrors(&ListJobErrorsInput{
+			JobID:  jobID,
+			Limit:  listJobLinesPageSize,
+			Marker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, jobError := range output.Errors {
+			if marker != "" && jobError.Input == marker && seen[*jobError] {
+				continue
+			}
+			seen[*jobError] = true
+			jobErrors = append(jobErrors, jobError)
+		}
+
+		page := output.Errors
+		if len(page) < listJobLinesPageSize {
+			return jobErrors, nil
+		}
+		// Errors without an input, or a page of errors which all share
+		// one, give no marker from which to continue.
+		next := page[len(page)-1].Input
+		if next == "" || next == marker {
+			return jobErrors, nil
+		}
+		marker = next
+	}
+}
+
+// getJobOutputObject requests an output object of a job, which is stored
+// below the job rather than the account's /stor directory. It is your
+// responsibility to close the io.ReadCloser returned.
+func (c *Client) getJobOutputObject(objectPath string) (io.ReadCloser, error) {
+	headers := &http.Header{}
+	headers.Set("Accept-Encoding", "identity")
+
+	respBody, _, err := c.executeRequest(http.MethodGet, c.absoluteObjectPath(objectPath), nil, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	return respBody, nil
+}
+
+// GetJobOutputContentsInput represents parameters to a GetJobOutputContents
+// or ForEachJobOutput operation.
+type GetJobOutputContentsInput struct {
+	JobID string
+}
+
+// JobOutputFunc is the type of the function called by ForEachJobOutput for
+// each output object of a job, with the full Manta path of the object and a
+// reader for its contents. The reader is only valid until the function
+// returns. Returning an error stops the iteration, and the error is returned
+// by ForEachJobOutput.
+type JobOutputFunc func(objectPath string, reader io.Reader) error
+
+// ForEachJobOutput calls fn for each of the output objects of a job in turn,
+// in the order in which ListJobOutputs returns them.
+func (c *Client) ForEachJobOutput(input *GetJobOutputContentsInput, fn JobOutputFunc) error {
+	outputs, err := c.listAllJobLines(input.JobID, "out")
+	if err != nil {
+		return errwrap.Wrapf("Error executing ForEachJobOutput request: {{err}}", err)
+	}
+
+	for _, objectPath := range outputs {
+		reader, err := c.getJobOutputObject(objectPath)
+		if err != nil {
+			return errwrap.Wrapf("Error executing ForEachJobOutput request: {{err}}", err)
+		}
+		err = fn(objectPath, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetJobOutputContentsOutput contains the outputs of a GetJobOutputContents
+// operation. It is your responsibility to ensure that the io.ReadCloser
+// ObjectReader is closed.
+type GetJobOutputContentsOutput struct {
+	Outputs      []string
+	ObjectReader io.ReadCloser
+}
+
+// GetJobOutputContents returns the contents of all of the output objects of
+// a job concatenated into a single stream, in the order listed in Outputs,
+// so that the results of a small job may be read in one go. Each object is
+// requested only when the reader reaches it.
+func (c *Client) GetJobOutputContents(input *GetJobOutputContentsInput) (*GetJobOutputContentsOutput, error) {
+	outputs, err := c.listAllJobLines(input.JobID, "out")
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing GetJobOutputContents request: {{err}}", err)
+	}
+
+	return &GetJobOutputContentsOutput{
+		Outputs: outputs,
+		ObjectReader: &jobOutputsReader{
+			client:  c,
+			outputs: outputs,
+		},
+	}, nil
+}
+
+// jobOutputsReader reads the contents of a list of job output objects in
+// turn.
+type jobOutputsReader struct {
+	client  *Client
+	outputs []string
+	current io.ReadCloser
+}
+
+func (r *jobOutputsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.outputs) == 0 {
+				return 0, io.EOF
+			}
+			reader, err := r.client.getJobOutputObject(r.outputs[0])
+			if err != nil {
+				return 0, errwrap.Wrapf("Error reading job output: {{err}}", err)
+			}
+			r.outputs = r.outputs[1:]
+			r.current = reader
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *jobOutputsReader) Close() error {
+	r.outputs = nil
+	if r.current != nil {
+		err := r.current.Close()
+		r.current = nil
+		return err
+	}
+	return nil
+}
+
+// jobOutputStorPrefix returns the prefix of the paths of the objects stored
+// by a job.
+func (c *Client) jobOutputStorPrefix(jobID string) string {
+	return fmt.Sprintf("/%s/jobs/%s/stor/", c.accountName, jobID)
+}
+
+// jobOutputInput returns the path of the input from which the map phase
+// output at objectPath was produced. Manta names such outputs for their
+// input followed by the phase number and a UUID; outputs of reduce phases,
+// and any not named in this way, have no single input, so the empty string
+// is returned for them.
+func (c *Client) jobOutputInput(jobID, objectPath string) string {
+	relativePath := strings.TrimPrefix(objectPath, c.jobOutputStorPrefix(jobID))
+	if relativePath == objectPath {
+		return ""
+	}
+
+	parts := strings.Split(relativePath, ".")
+	if len(parts) < 3 {
+		return ""
+	}
+	if _, err := strconv.Atoi(parts[len(parts)-2]); err != nil {
+		return ""
+	}
+	input := "/" + strings.Join(parts[:len(parts)-2], ".")
+	if strings.HasPrefix(input, "/reduce") && !strings.Contains(input[1:], "/") {
+		return ""
+	}
+	return input
+}
+
+// DownloadJobOutputsInput represents parameters to a DownloadJobOutputs
+// operation. The outputs are downloaded into the directory at LocalPath if
+// it is set, and otherwise concatenated into the file at FilePath.
+// Concurrency is the number of outputs downloaded at once into a directory;
+// if it is zero, DefaultDirectoryTransferConcurrency is used.
+type DownloadJobOutputsInput struct {
+	JobID       string
+	LocalPath   string
+	FilePath    string
+	Concurrency int
+}
+
+// JobOutputFile describes an output object downloaded by DownloadJobOutputs.
+// Input is the full path of the input object from which the output was
+// produced, or empty if there was no single input, as for the outputs of a
+// reduce phase. FilePath is the local file to which it was downloaded.
+type JobOutputFile struct {
+	ObjectPath string
+	Input      string
+	FilePath   string
+}
+
+// DownloadJobOutputsOutput contains the outputs of a DownloadJobOutputs
+// operation, in the order in which ListJobOutputs returned them.
+type DownloadJobOutputsOutput struct {
+	Files []*JobOutputFile
+}
+
+// DownloadJobOutputs downloads the output objects of a job. When downloading
+// into a directory, each output is written to its path relative to the
+// directory in which the job stores its outputs, which for the outputs of a
+// map phase is the path of its input followed by the phase number and a
+// UUID.
+func (c *Client) DownloadJobOutputs(input *DownloadJobOutputsInput) (*DownloadJobOutputsOutput, error) {
+	if input.LocalPath == "" && input.FilePath == "" {
+		return nil, errors.New("Either LocalPath or FilePath must be set for DownloadJobOutputs")
+	}
+
+	outputs, err := c.listAllJobLines(input.JobID, "out")
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing DownloadJobOutputs request: {{err}}", err)
+	}
+
+	result := &DownloadJobOutputsOutput{
+		Files: make([]*JobOutputFile, len(outputs)),
+	}
+	prefix := c.jobOutputStorPrefix(input.JobID)
+	for i, objectPath := range outputs {
+		filePath := input.FilePath
+		if input.LocalPath != "" {
+			relativePath := strings.TrimPrefix(strings.TrimPrefix(objectPath, prefix), "/")
+			filePath = filepath.Join(input.LocalPath, filepath.FromSlash(path.Clean("/" + relativePath)))
+		}
+		result.Files[i] = &JobOutputFile{
+			ObjectPath: objectPath,
+			Input:      c.jobOutputInput(input.JobID, objectPath),
+			FilePath:   filePath,
+		}
+	}
+
+	if input.LocalPath == "" {
+		err = c.downloadJobOutputsToFile(input.FilePath, outputs)
+	} else {
+		err = c.downloadJobOutputsToDirectory(result.Files, input.Concurrency)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing DownloadJobOutputs request: {{err}}", err)
+	}
+
+	return result, nil
+}
+
+// downloadJobOutputsToFile concatenates the output objects into the file at
+// filePath.
+func (c *Client) downloadJobOutputsToFile(filePath string, outputs []string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	reader := &jobOutputsReader{
+		client:  c,
+		outputs: outputs,
+	}
+	_, err = io.Copy(file, reader)
+	reader.Close()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// downloadJobOutputsToDirectory downloads each output object to its file,
+// using a pool of concurrent workers.
+func (c *Client) downloadJobOutputsToDirectory(files []*JobOutputFile, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = DefaultDirectoryTransferConcurrency
+	}
+
+	var lock sync.Mutex
+	var firstErr error
+
+	work := make(chan *JobOutputFile)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range work {
+				if err := c.downloadJobOutput(file); err != nil {
+					lock.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	for _, file := range files {
+		work <- file
+	}
+	close(work)
+	wg.Wait()
+
+	return firstErr
+}
+
+// downloadJobOutput downloads a single output object to its file.
+func (c *Client) downloadJobOutput(file *JobOutputFile) error {
+	if err := os.MkdirAll(filepath.Dir(file.FilePath), 0755); err != nil {
+		return err
+	}
+
+	reader, err := c.getJobOutputObject(file.ObjectPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	f, err := os.Create(file.FilePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, reader)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
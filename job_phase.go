@@ -0,0 +1,65 @@
+package manta
+
+import (
+	"github.com/hashicorp/errwrap"
+)
+
+// JobPhaseOptions represents options for the phases constructed by
+// NewMapPhase and NewReducePhase. Each field has the meaning of the
+// JobPhase field of the same name, and is left unset if it is zero.
+// ReducerCount may only be set for reduce phases.
+type JobPhaseOptions struct {
+	Assets       []string
+	Init         string
+	ReducerCount uint
+	Memory       uint64
+	Disk         uint64
+	Image        string
+}
+
+// NewMapPhase is used to construct a map phase which runs exec for each
+// input object. If options is nil, Manta's defaults are used. An error is
+// returned if the resulting phase is not valid, as reported by
+// JobPhase.Validate.
+func NewMapPhase(exec string, options *JobPhaseOptions) (*JobPhase, error) {
+	phase, err := newJobPhase(JobPhaseTypeMap, exec, options)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error constructing map phase: {{err}}", err)
+	}
+	return phase, nil
+}
+
+// NewReducePhase is used to construct a reduce phase which runs exec over
+// the outputs of the previous phase, or the inputs of the job if it is the
+// first. If options is nil, Manta's defaults are used, including a single
+// reducer. An error is returned if the resulting phase is not valid, as
+// reported by JobPhase.Validate.
+func NewReducePhase(exec string, options *JobPhaseOptions) (*JobPhase, error) {
+	phase, err := newJobPhase(JobPhaseTypeReduce, exec, options)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error constructing reduce phase: {{err}}", err)
+	}
+	return phase, nil
+}
+
+func newJobPhase(phaseType, exec string, options *JobPhaseOptions) (*JobPhase, error) {
+	phase := &JobPhase{
+		Type: phaseType,
+		Exec: exec,
+	}
+	if options != nil {
+		if len(options.Assets) != 0 {
+			phase.Assets = append([]string{}, options.Assets...)
+		}
+		phase.Init = options.Init
+		phase.ReducerCount = options.ReducerCount
+		phase.Memory = options.Memory
+		phase.Disk = options.Disk
+		phase.Image = options.Image
+	}
+
+	if err := phase.Validate(); err != nil {
+		return nil, err
+	}
+	return phase, nil
+}
@@ -0,0 +1,85 @@
+package manta
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// RetryJobFailuresInput represents parameters to a RetryJobFailures
+// operation. JobID is the finished job whose failed inputs are retried. If
+// Name is empty, the new job is given the name of the original.
+type RetryJobFailuresInput struct {
+	JobID string
+	Name  string
+}
+
+// RetryJobFailuresOutput contains the outputs of a RetryJobFailures
+// operation. JobID is the ID of the new job, and Inputs holds the failed
+// inputs which were given to it. If the original job had no failures, no
+// job is created and both are empty.
+type RetryJobFailuresOutput struct {
+	JobID  string
+	Inputs []string
+}
+
+// RetryJobFailures creates a new job with the same phases as a finished
+// job and gives it only the inputs which failed in that job. The input of
+// the new job is ended, so it runs to completion without further calls;
+// use WaitForJob to wait for it to finish.
+func (c *Client) RetryJobFailures(input *RetryJobFailuresInput) (*RetryJobFailuresOutput, error) {
+	job, err := c.GetJob(&GetJobInput{
+		JobID: input.JobID,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing RetryJobFailures request: {{err}}", err)
+	}
+	if job.Job.State != JobStateDone {
+		return nil, fmt.Errorf("Job %s must be finished for RetryJobFailures, but is %s", input.JobID, job.Job.State)
+	}
+
+	failures, err := c.listAllJobLines(input.JobID, "fail")
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing RetryJobFailures request: {{err}}", err)
+	}
+	if len(failures) == 0 {
+		return &RetryJobFailuresOutput{}, nil
+	}
+
+	name := input.Name
+	if name == "" {
+		name = job.Job.Name
+	}
+
+	retry, err := c.CreateJob(&CreateJobInput{
+		Name:   name,
+		Phases: job.Job.Phases,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing RetryJobFailures request: {{err}}", err)
+	}
+
+	if err := c.AddJobInputs(&AddJobInputsInput{
+		JobID:       retry.JobID,
+		ObjectPaths: failures,
+	}); err != nil {
+		c.CancelJob(&CancelJobInput{
+			JobID: retry.JobID,
+		})
+		return nil, errwrap.Wrapf("Error executing RetryJobFailures request: {{err}}", err)
+	}
+
+	if err := c.EndJobInput(&EndJobInputInput{
+		JobID: retry.JobID,
+	}); err != nil {
+		c.CancelJob(&CancelJobInput{
+			JobID: retry.JobID,
+		})
+		return nil, errwrap.Wrapf("Error executing RetryJobFailures request: {{err}}", err)
+	}
+
+	return &RetryJobFailuresOutput{
+		JobID:  retry.JobID,
+		Inputs: failures,
+	}, nil
+}
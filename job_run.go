@@ -0,0 +1,165 @@
+package manta
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// RunJobInput represents parameters to a RunJob operation. Name and Phases
+// describe the job as for CreateJob, and the inputs are given as for
+// AddJobInputs. MinInterval, MaxInterval and PollStrategy control how often
+// the job is checked, as for WaitForJob.
+//
+// AssetFiles are local files uploaded with UploadJobAssets below AssetPrefix
+// before the job is created, and deleted once it is done. The Phases given
+// are not changed; the assets are added to copies of them. If RunJob
+// returns before the job is known to have finished, the assets are left in
+// place, since the job may still be reading them, and the error returned
+// names the directory holding them.
+type RunJobInput struct {
+	Name   string
+	Phases []*JobPhase
+
+	AssetFiles  []*JobAssetFile
+	AssetPrefix string
+
+	ObjectPaths    []string
+	ObjectReader   io.Reader
+	ObjectPathChan <-chan string
+
+	MinInterval  time.Duration
+	MaxInterval  time.Duration
+	PollStrategy PollStrategy
+}
+
+// RunJobOutput contains the outputs of a RunJob operation. Job is the final
+// status of the job, Outputs holds the paths of its output objects and
+// Errors the errors reported for its tasks.
+type RunJobOutput struct {
+	Job     *Job
+	Outputs []string
+	Errors  []*JobError
+}
+
+// Failed returns true if the job was cancelled or any of its tasks failed.
+func (o *RunJobOutput) Failed() bool {
+	return o.Job.Cancelled || len(o.Errors) != 0 || (o.Job.Stats != nil && o.Job.Stats.Errors != 0)
+}
+
+// RunJob runs a job from start to finish, like `mjob create -w -o`: it
+// creates the job, adds its inputs, ends its input, waits for it to finish
+// and collects its outputs and errors. Errors in the tasks of the job do not
+// cause an error to be returned; use RunJobOutput.Failed to check for them.
+//
+// If ctx is cancelled or expires before the job finishes, the job is
+// cancelled, on a best effort basis, and the error of ctx is returned.
+func (c *Client) RunJob(ctx context.Context, input *RunJobInput) (*RunJobOutput, error) {
+	// The assets are deleted on return unless the job may still be
+	// running, in which case assetsDirectory is cleared.
+	assetsDirectory := ""
+	defer func() {
+		if assetsDirectory != "" {
+			c.DeleteJobAssets(&DeleteJobAssetsInput{
+				DirectoryName: assetsDirectory,
+			})
+		}
+	}()
+
+	// The phases are checked before any assets are uploaded, so that an
+	// invalid job makes no requests.
+	if err := validateJobPhases("RunJob", input.Phases); err != nil {
+		return nil, err
+	}
+
+	phases := input.Phases
+	if len(input.AssetFiles) != 0 {
+		phases = make([]*JobPhase, len(input.Phases))
+		for i, phase := range input.Phases {
+			copied := *phase
+			copied.Assets = append([]string{}, phase.Assets...)
+			phases[i] = &copied
+		}
+
+		assets, err := c.UploadJobAssets(&UploadJobAssetsInput{
+			Prefix: input.AssetPrefix,
+			Phases: phases,
+			Files:  input.AssetFiles,
+		})
+		if err != nil {
+			return nil, errwrap.Wrapf("Error executing RunJob request: {{err}}", err)
+		}
+		assetsDirectory = assets.DirectoryName
+	}
+
+	job, err := c.CreateJob(&CreateJobInput{
+		Name:   input.Name,
+		Phases: phases,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing RunJob request: {{err}}", err)
+	}
+
+	cancel := func(err error) (*RunJobOutput, error) {
+		c.CancelJob(&CancelJobInput{
+			JobID: job.JobID,
+		})
+		if assetsDirectory != "" {
+			message := fmt.Sprintf("Error executing RunJob request, leaving job assets in %s: {{err}}", assetsDirectory)
+			assetsDirectory = ""
+			return nil, errwrap.Wrapf(message, err)
+		}
+		return nil, errwrap.Wrapf("Error executing RunJob request: {{err}}", err)
+	}
+
+	if len(input.ObjectPaths) != 0 || input.ObjectReader != nil || input.ObjectPathChan != nil {
+		err := c.AddJobInputs(&AddJobInputsInput{
+			JobID:          job.JobID,
+			ObjectPaths:    input.ObjectPaths,
+			ObjectReader:   input.ObjectReader,
+			ObjectPathChan: input.ObjectPathChan,
+		})
+		if err != nil {
+			return cancel(err)
+		}
+	}
+
+	if err := c.EndJobInput(&EndJobInputInput{
+		JobID: job.JobID,
+	}); err != nil {
+		return cancel(err)
+	}
+
+	status, err := c.WaitForJob(ctx, &WaitForJobInput{
+		JobID:        job.JobID,
+		MinInterval:  input.MinInterval,
+		MaxInterval:  input.MaxInterval,
+		PollStrategy: input.PollStrategy,
+	})
+	if err != nil {
+		return cancel(err)
+	}
+
+	outputs, err := c.listAllJobLines(job.JobID, "out")
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing RunJob request: {{err}}", err)
+	}
+
+	output := &RunJobOutput{
+		Job:     status.Job,
+		Outputs: outputs,
+	}
+
+	if status.Job.Stats == nil || status.Job.Stats.Errors != 0 {
+		jobErrors, err := c.listAllJobErrors(job.JobID)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error executing RunJob request: {{err}}", err)
+		}
+		output.Errors = jobErrors
+	}
+
+	return output, nil
+}
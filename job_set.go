@@ -0,0 +1,190 @@
+package manta
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const DefaultJobSetInterval = 5 * time.Second
+
+// JobSetOptions represents options for a JobSet.
+type JobSetOptions struct {
+	// Interval is the interval at which the jobs of the set are checked.
+	// If it is zero, DefaultJobSetInterval is used.
+	Interval time.Duration
+}
+
+// JobSetEvent is delivered by a JobSet when one of its jobs finishes, or
+// when checking its jobs fails. If the job finished, Job is its final
+// status. Otherwise Err describes the failure; JobID is empty if the
+// failure affected every job, and the jobs remain in the set unless the job
+// could not be found.
+type JobSetEvent struct {
+	JobID string
+	Job   *Job
+	Err   error
+}
+
+// JobSet waits on many jobs at once, for orchestrators which run jobs in
+// parallel. Rather than requesting the status of each job, on each check a
+// JobSet lists the account's unfinished jobs, and only requests the status
+// of those of its jobs which are no longer listed, so that its cost depends
+// little on the number of jobs.
+//
+// A JobSet is used by adding jobs to it, running Run in a goroutine and
+// receiving events from Events until the channel is closed:
+//
+//	set := manta.NewJobSet(client, nil)
+//	set.Add(jobIDs...)
+//	go set.Run(ctx)
+//	for event := range set.Events() {
+//		...
+//	}
+//
+// Jobs may be added from any goroutine while Run is running.
+type JobSet struct {
+	client   *Client
+	interval time.Duration
+	events   chan *JobSetEvent
+
+	lock sync.Mutex
+	jobs map[string]bool
+}
+
+// NewJobSet is used to construct an empty JobSet for client. If options is
+// nil, default options are used.
+func NewJobSet(client *Client, options *JobSetOptions) *JobSet {
+	set := &JobSet{
+		client:   client,
+		interval: DefaultJobSetInterval,
+		events:   make(chan *JobSetEvent),
+		jobs:     map[string]bool{},
+	}
+	if options != nil && options.Interval > 0 {
+		set.interval = options.Interval
+	}
+	return set
+}
+
+// Add adds jobs to the set.
+func (s *JobSet) Add(jobIDs ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, jobID := range jobIDs {
+		s.jobs[jobID] = true
+	}
+}
+
+// Remove stops waiting on a job, without cancelling it.
+func (s *JobSet) Remove(jobID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.jobs, jobID)
+}
+
+// Len returns the number of jobs in the set which have not finished.
+func (s *JobSet) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.jobs)
+}
+
+// Events returns the channel on which events are delivered. It is closed
+// when Run returns.
+func (s *JobSet) Events() <-chan *JobSetEvent {
+	return s.events
+}
+
+// Run checks the jobs of the set at the set's interval, delivering an event
+// for each as it finishes, until every job has finished or ctx is cancelled
+// or expires, in which case the error of ctx is returned. Run must only be
+// called once.
+func (s *JobSet) Run(ctx context.Context) error {
+	defer close(s.events)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if s.Len() == 0 {
+			return nil
+		}
+
+		for _, event := range s.check() {
+			select {
+			case s.events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if s.Len() == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// check checks the jobs of the set once, removing those which have finished,
+// and returns the events to be delivered.
+func (s *JobSet) check() []*JobSetEvent {
+	unfinished := map[string]bool{}
+	for _, state := range []string{JobStateQueued, JobStateRunning} {
+		iterator := NewJobIterator(s.client, &ListJobsInput{
+			State: state,
+		})
+		for iterator.Next() {
+			unfinished[iterator.Job().ID] = true
+		}
+		if err := iterator.Err(); err != nil {
+			return []*JobSetEvent{{Err: err}}
+		}
+	}
+
+	s.lock.Lock()
+	var candidates []string
+	for jobID := range s.jobs {
+		if !unfinished[jobID] {
+			candidates = append(candidates, jobID)
+		}
+	}
+	s.lock.Unlock()
+
+	var events []*JobSetEvent
+	for _, jobID := range candidates {
+		status, err := s.client.GetJob(&GetJobInput{
+			JobID: jobID,
+		})
+		if err != nil {
+			if IsJobNotFoundError(err) || IsResourceNotFoundError(err) {
+				s.Remove(jobID)
+			}
+			events = append(events, &JobSetEvent{
+				JobID: jobID,
+				Err:   err,
+			})
+			continue
+		}
+
+		// A job may have moved from queued to running between the two
+		// listings, so it is only finished if its status says so.
+		if status.Job.State != JobStateDone {
+			continue
+		}
+		s.Remove(jobID)
+		events = append(events, &JobSetEvent{
+			JobID: jobID,
+			Job:   status.Job,
+		})
+	}
+	return events
+}
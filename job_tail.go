@@ -0,0 +1,124 @@
+package manta
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultTailJobOutputsInterval = 2 * time.Second
+
+// TailJobOutputsInput represents parameters to a TailJobOutputs operation.
+// Interval is the interval at which new outputs are checked for; if it is
+// zero, DefaultTailJobOutputsInterval is used.
+//
+// Path, if it is set, is called with the full Manta path of each new output
+// object. Contents, if it is set, is then called with the object and a
+// reader for its contents, as for ForEachJobOutput. Returning an error from
+// either stops the operation, and the error is returned by TailJobOutputs.
+type TailJobOutputsInput struct {
+	JobID    string
+	Interval time.Duration
+	Path     func(objectPath string) error
+	Contents JobOutputFunc
+}
+
+// TailJobOutputsOutput contains the outputs of a TailJobOutputs operation.
+// Job is the final status of the job, and Outputs is the number of output
+// objects seen.
+type TailJobOutputsOutput struct {
+	Job     *Job
+	Outputs uint64
+}
+
+// TailJobOutputs polls the live outputs of a job, like `mjob watch -o`,
+// passing each output object to the callbacks as soon as it is committed
+// rather than once the job is finished, so that interactive tools may show
+// results as they appear. It returns once the job is finished and every
+// output has been seen. If ctx is cancelled or expires first, the error of
+// ctx is returned, wrapped.
+func (c *Client) TailJobOutputs(ctx context.Context, input *TailJobOutputsInput) (*TailJobOutputsOutput, error) {
+	interval := input.Interval
+	if interval <= 0 {
+		interval = DefaultTailJobOutputsInterval
+	}
+
+	output := &TailJobOutputsOutput{}
+	marker := ""
+	for {
+		// The status is retrieved before the outputs are listed, so that
+		// once it shows the job finished, the listing is complete.
+		status, err := c.GetJob(&GetJobInput{
+			JobID: input.JobID,
+		})
+		if err != nil {
+			return nil, errwrap.Wrapf("Error executing TailJobOutputs request: {{err}}", err)
+		}
+
+		marker, err = c.tailJobOutputs(input, marker, output)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error executing TailJobOutputs request: {{err}}", err)
+		}
+
+		if status.Job.State == JobStateDone {
+			output.Job = status.Job
+			return output, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errwrap.Wrapf("Error executing TailJobOutputs request: {{err}}", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// tailJobOutputs passes each output listed after marker to the callbacks of
+// input, and returns the marker from which to list next time.
+func (c *Client) tailJobOutputs(input *TailJobOutputsInput, marker string, output *TailJobOutputsOutput) (string, error) {
+	for {
+		page, _, err := c.listJobLines(input.JobID, "out", listJobLinesPageSize, marker)
+		if err != nil {
+			return marker, err
+		}
+
+		for _, objectPath := range page {
+			if marker != "" && objectPath == marker {
+				continue
+			}
+			if err := c.tailJobOutput(input, objectPath); err != nil {
+				return marker, err
+			}
+			output.Outputs++
+		}
+
+		if len(page) != 0 {
+			marker = page[len(page)-1]
+		}
+		if len(page) < listJobLinesPageSize {
+			return marker, nil
+		}
+	}
+}
+
+// tailJobOutput passes a single output object to the callbacks of input.
+func (c *Client) tailJobOutput(input *TailJobOutputsInput, objectPath string) error {
+	if input.Path != nil {
+		if err := input.Path(objectPath); err != nil {
+			return err
+		}
+	}
+	if input.Contents == nil {
+		return nil
+	}
+
+	reader, err := c.getJobOutputObject(objectPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return input.Contents(objectPath, reader)
+}
@@ -0,0 +1,85 @@
+package manta
+
+import (
+	"testing"
+)
+
+func TestJobPhaseValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		phase JobPhase
+		valid bool
+	}{
+		{name: "map", phase: JobPhase{Exec: "wc"}, valid: true},
+		{name: "reduce", phase: JobPhase{Type: JobPhaseTypeReduce, Exec: "cat", ReducerCount: 1024}, valid: true},
+		{name: "unknown type", phase: JobPhase{Type: "filter", Exec: "wc"}},
+		{name: "no exec", phase: JobPhase{Exec: " "}},
+		{name: "map reducers", phase: JobPhase{Exec: "wc", ReducerCount: 2}},
+		{name: "too many reducers", phase: JobPhase{Type: JobPhaseTypeReduce, Exec: "cat", ReducerCount: 1025}},
+		{name: "memory", phase: JobPhase{Exec: "wc", Memory: 1024, Disk: 16}, valid: true},
+		{name: "bad memory", phase: JobPhase{Exec: "wc", Memory: 1000}},
+		{name: "bad disk", phase: JobPhase{Exec: "wc", Disk: 3}},
+		{name: "init", phase: JobPhase{Exec: "wc", Init: "mkdir /var/tmp/out"}, valid: true},
+		{name: "blank init", phase: JobPhase{Exec: "wc", Init: "  "}},
+		{name: "init same as exec", phase: JobPhase{Exec: "wc -l", Init: " wc -l"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.phase.Validate()
+			if tc.valid && err != nil {
+				t.Errorf("expected phase to be valid, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("expected phase to be invalid")
+			}
+		})
+	}
+}
+
+func TestCreateJobValidatesFirst(t *testing.T) {
+	s, client := newTestServer(t)
+
+	_, err := client.CreateJob(&CreateJobInput{
+		Phases: []*JobPhase{{Exec: "wc", Init: "wc"}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid phase")
+	}
+	if requests := len(s.requestLog()); requests != 0 {
+		t.Errorf("expected no requests, got %d", requests)
+	}
+}
+
+func TestJobErrorCodes(t *testing.T) {
+	cases := []struct {
+		code      string
+		is        func(error) bool
+		retryable bool
+	}{
+		{code: JobErrorCodeUserTask, is: IsUserTaskError},
+		{code: "UserTask", is: IsUserTaskError},
+		{code: JobErrorCodeTaskInit, is: IsTaskInitError},
+		{code: JobErrorCodeResourceNotFound, is: IsResourceNotFoundError},
+		{code: JobErrorCodeJobCancelled, is: IsJobCancelledError},
+		{code: JobErrorCodeInternal, is: IsInternalError, retryable: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.code, func(t *testing.T) {
+			var err error = &JobError{
+				Code:    tc.code,
+				Message: "failed",
+			}
+			if !tc.is(err) {
+				t.Errorf("expected %s to be matched by its predicate", tc.code)
+			}
+			if IsAuthSchemeError(err) {
+				t.Errorf("expected %s not to be matched by another predicate", tc.code)
+			}
+			if retryable := err.(*JobError).Retryable(); retryable != tc.retryable {
+				t.Errorf("expected Retryable to be %t, got %t", tc.retryable, retryable)
+			}
+		})
+	}
+}
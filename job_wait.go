@@ -0,0 +1,274 @@
+package manta
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	DefaultWaitForJobMinInterval = 1 * time.Second
+	DefaultWaitForJobMaxInterval = 30 * time.Second
+)
+
+// PollStrategy decides how long to wait before each check of the status of
+// a job, so that short jobs may be checked often and long jobs rarely.
+type PollStrategy interface {
+	// NextInterval returns the interval to wait before the next check.
+	// job is the status found by the previous check, or nil before the
+	// first, and checks is the number of checks made so far.
+	NextInterval(job *Job, checks int) time.Duration
+}
+
+// PollStrategyFunc is a function which implements PollStrategy, for
+// strategies which depend on the progress of the job, such as the
+// proportion of its tasks which are done.
+type PollStrategyFunc func(job *Job, checks int) time.Duration
+
+// NextInterval implements interface PollStrategy on the PollStrategyFunc
+// type.
+func (f PollStrategyFunc) NextInterval(job *Job, checks int) time.Duration {
+	return f(job, checks)
+}
+
+// FixedPollStrategy returns a PollStrategy which checks a job at a steady
+// interval.
+func FixedPollStrategy(interval time.Duration) PollStrategy {
+	return PollStrategyFunc(func(job *Job, checks int) time.Duration {
+		return interval
+	})
+}
+
+// ExponentialPollStrategy returns a PollStrategy which first checks a job
+// after minInterval and then doubles the interval after each check up to
+// maxInterval. If either is zero, DefaultWaitForJobMinInterval or
+// DefaultWaitForJobMaxInterval is used.
+func ExponentialPollStrategy(minInterval, maxInterval time.Duration) PollStrategy {
+	if minInterval <= 0 {
+		minInterval = DefaultWaitForJobMinInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = DefaultWaitForJobMaxInterval
+	}
+
+	return PollStrategyFunc(func(job *Job, checks int) time.Duration {
+		interval := minInterval
+		for i := 0; i < checks && interval < maxInterval; i++ {
+			interval *= 2
+		}
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+		return interval
+	})
+}
+
+// WaitForJobInput represents parameters to a WaitForJob operation. The job
+// is checked according to PollStrategy if it is set. Otherwise it is first
+// checked after MinInterval, and the interval then doubles after each check
+// up to MaxInterval, as by ExponentialPollStrategy.
+type WaitForJobInput struct {
+	JobID        string
+	MinInterval  time.Duration
+	MaxInterval  time.Duration
+	PollStrategy PollStrategy
+}
+
+// WaitForJobOutput contains the outputs of a WaitForJob operation.
+type WaitForJobOutput struct {
+	Job *Job
+}
+
+// WaitForJob polls the status of a job until it is finished, whether because
+// it completed or because it was cancelled, and returns its final status.
+// Whether the job succeeded may be judged from Job.Cancelled and the Errors
+// in Job.Stats. If ctx is cancelled or expires first, the error of ctx is
+// returned, wrapped.
+func (c *Client) WaitForJob(ctx context.Context, input *WaitForJobInput) (*WaitForJobOutput, error) {
+	strategy := input.PollStrategy
+	if strategy == nil {
+		strategy = ExponentialPollStrategy(input.MinInterval, input.MaxInterval)
+	}
+
+	job, err := c.pollJob(ctx, input.JobID, strategy, nil)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing WaitForJob request: {{err}}", err)
+	}
+
+	return &WaitForJobOutput{
+		Job: job,
+	}, nil
+}
+
+// pollJob polls the status of a job until it is finished, calling fn, if it
+// is set, with each status retrieved. The interval before each check is
+// chosen by strategy.
+func (c *Client) pollJob(ctx context.Context, jobID string, strategy PollStrategy, fn func(*Job)) (*Job, error) {
+	var job *Job
+	for checks := 0; ; checks++ {
+		timer := time.NewTimer(strategy.NextInterval(job, checks))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		status, err := c.GetJob(&GetJobInput{
+			JobID: jobID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		job = status.Job
+		if fn != nil {
+			fn(job)
+		}
+		if job.State == JobStateDone {
+			return job, nil
+		}
+	}
+}
+
+const DefaultWatchJobInterval = 5 * time.Second
+
+// JobProgress describes the progress of a job watched by WatchJob. Job is
+// the latest status of the job, and Delta holds the change in each of its
+// statistics since the previous call.
+type JobProgress struct {
+	Job   *Job
+	Delta JobStats
+}
+
+// JobProgressFunc is the type of the function called by WatchJob as a job
+// makes progress.
+type JobProgressFunc func(progress *JobProgress)
+
+// WatchJobInput represents parameters to a WatchJob operation. The job is
+// checked according to PollStrategy if it is set, and otherwise at Interval;
+// if that is zero, DefaultWatchJobInterval is used.
+type WatchJobInput struct {
+	JobID        string
+	Interval     time.Duration
+	PollStrategy PollStrategy
+	Progress     JobProgressFunc
+}
+
+// WatchJobOutput contains the outputs of a WatchJob operation.
+type WatchJobOutput struct {
+	Job *Job
+}
+
+// WatchJob polls the status of a job until it is finished, as for
+// WaitForJob but by default at a steady interval, calling Progress whenever the
+// statistics of the job or its state have changed, so that the progress of a
+// long running job may be displayed. Progress is always called with the
+// final status of the job.
+func (c *Client) WatchJob(ctx context.Context, input *WatchJobInput) (*WatchJobOutput, error) {
+	strategy := input.PollStrategy
+	if strategy == nil {
+		interval := input.Interval
+		if interval <= 0 {
+			interval = DefaultWatchJobInterval
+		}
+		strategy = FixedPollStrategy(interval)
+	}
+
+	previous := JobStats{}
+	previousState := ""
+	job, err := c.pollJob(ctx, input.JobID, strategy, func(job *Job) {
+		current := JobStats{}
+		if job.Stats != nil {
+			current = *job.Stats
+		}
+		if current == previous && job.State == previousState && job.State != JobStateDone {
+			return
+		}
+
+		if input.Progress != nil {
+			input.Progress(&JobProgress{
+				Job: job,
+				Delta: JobStats{
+					Errors:    statDelta(current.Errors, previous.Errors),
+					Outputs:   statDelta(current.Outputs, previous.Outputs),
+					Retries:   statDelta(current.Retries, previous.Retries),
+					Tasks:     statDelta(current.Tasks, previous.Tasks),
+					TasksDone: statDelta(current.TasksDone, previous.TasksDone),
+				},
+			})
+		}
+		previous = current
+		previousState = job.State
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing WatchJob request: {{err}}", err)
+	}
+
+	return &WatchJobOutput{
+		Job: job,
+	}, nil
+}
+
+// statDelta returns the increase from previous to current, or zero if the
+// statistic has gone down.
+func statDelta(current, previous uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+	return current - previous
+}
+
+// CancelJobAndWaitInput represents parameters to a CancelJobAndWait
+// operation. MinInterval, MaxInterval and PollStrategy control how often the
+// job is checked, as for WaitForJob.
+type CancelJobAndWaitInput struct {
+	JobID        string
+	MinInterval  time.Duration
+	MaxInterval  time.Duration
+	PollStrategy PollStrategy
+}
+
+// CancelJobAndWaitOutput contains the outputs of a CancelJobAndWait
+// operation.
+type CancelJobAndWaitOutput struct {
+	Job *Job
+}
+
+// CancelJobAndWait cancels a job and then polls its status until it is
+// finished, since cancellation is asynchronous and tasks may still be
+// running when CancelJob returns. The final status of the job is returned;
+// Job.Cancelled is false if the job finished before it could be cancelled.
+// If ctx is cancelled or expires first, the error of ctx is returned,
+// wrapped, and the job may still be running.
+func (c *Client) CancelJobAndWait(ctx context.Context, input *CancelJobAndWaitInput) (*CancelJobAndWaitOutput, error) {
+	if err := c.CancelJob(&CancelJobInput{
+		JobID: input.JobID,
+	}); err != nil {
+		// Cancelling a job which has already finished fails, but leaves
+		// the job in the end state the caller is waiting for.
+		status, getErr := c.GetJob(&GetJobInput{
+			JobID: input.JobID,
+		})
+		if getErr != nil || status.Job.State != JobStateDone {
+			return nil, errwrap.Wrapf("Error executing CancelJobAndWait request: {{err}}", err)
+		}
+		return &CancelJobAndWaitOutput{
+			Job: status.Job,
+		}, nil
+	}
+
+	strategy := input.PollStrategy
+	if strategy == nil {
+		strategy = ExponentialPollStrategy(input.MinInterval, input.MaxInterval)
+	}
+
+	job, err := c.pollJob(ctx, input.JobID, strategy, nil)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing CancelJobAndWait request: {{err}}", err)
+	}
+
+	return &CancelJobAndWaitOutput{
+		Job: job,
+	}, nil
+}
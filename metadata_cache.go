@@ -0,0 +1,257 @@
+package manta
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultMetadataCacheTTL = 30 * time.Second
+
+// MetadataCacheOptions represents options for a MetadataCache.
+type MetadataCacheOptions struct {
+	// TTL is how long results are cached for. If it is zero,
+	// DefaultMetadataCacheTTL is used.
+	TTL time.Duration
+
+	// CacheNotFound caches the not found errors returned by HeadObject as
+	// well as its results, so that repeatedly checking for an object which
+	// does not exist is also served from the cache.
+	CacheNotFound bool
+}
+
+// MetadataCache caches the results of ListDirectory and HeadObject requests
+// for a period, so that code which repeatedly lists or stats the same paths
+// does not make a request to Manta each time. Changes made in Manta are not
+// seen until the cached result expires or is invalidated, so callers which
+// change a path should call Invalidate for it.
+//
+// A MetadataCache may be used from multiple goroutines.
+type MetadataCache struct {
+	client  *Client
+	options MetadataCacheOptions
+
+	lock      sync.Mutex
+	entries   map[string]*metadataCacheEntry
+	nextSweep int
+}
+
+type metadataCacheEntry struct {
+	expires time.Time
+
+	// path is the object or directory the result describes.
+	path string
+
+	head *HeadObjectOutput
+	list *ListDirectoryOutput
+	err  error
+}
+
+// NewMetadataCache is used to construct a MetadataCache for client. If
+// options is nil, default options are used.
+func NewMetadataCache(client *Client, options *MetadataCacheOptions) *MetadataCache {
+	cache := &MetadataCache{
+		client:  client,
+		entries: map[string]*metadataCacheEntry{},
+	}
+	if options != nil {
+		cache.options = *options
+	}
+	if cache.options.TTL <= 0 {
+		cache.options.TTL = DefaultMetadataCacheTTL
+	}
+	return cache
+}
+
+// cachePath normalises a path relative to the account's /stor directory.
+func cachePath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// HeadObject returns the cached result of HeadObject for the object at
+// ObjectPath, making the request if there is none. Conditional requests are
+// passed to the client without being cached.
+func (m *MetadataCache) HeadObject(input *HeadObjectInput) (*HeadObjectOutput, error) {
+	if input.IfMatch != "" || input.IfNoneMatch != "" || input.IfModifiedSince != nil || input.IfUnmodifiedSince != nil {
+		return m.client.HeadObject(input)
+	}
+
+	objectPath := cachePath(input.ObjectPath)
+	key := "head:" + objectPath
+	if entry := m.get(key); entry != nil {
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		return copyHeadObjectOutput(entry.head), nil
+	}
+
+	output, err := m.client.HeadObject(input)
+	if err != nil {
+		notFound := IsResourceNotFoundError(err) || hasStatusCode(err, http.StatusNotFound)
+		if notFound && m.options.CacheNotFound {
+			m.put(key, &metadataCacheEntry{
+				path: objectPath,
+				err:  err,
+			})
+		}
+		return nil, err
+	}
+
+	m.put(key, &metadataCacheEntry{
+		path: objectPath,
+		head: copyHeadObjectOutput(output),
+	})
+	return output, nil
+}
+
+// ListDirectory returns the cached result of ListDirectory for input,
+// making the request if there is none. Each distinct combination of the
+// fields of input is cached separately.
+func (m *MetadataCache) ListDirectory(input *ListDirectoryInput) (*ListDirectoryOutput, error) {
+	directoryName := cachePath(input.DirectoryName)
+	key := fmt.Sprintf("list:%s?%s", directoryName, listDirectoryQuery(input).Encode())
+	if entry := m.get(key); entry != nil {
+		return copyListDirectoryOutput(entry.list), nil
+	}
+
+	output, err := m.client.ListDirectory(input)
+	if err != nil {
+		return nil, err
+	}
+
+	m.put(key, &metadataCacheEntry{
+		path: directoryName,
+		list: copyListDirectoryOutput(output),
+	})
+	return output, nil
+}
+
+// Invalidate discards the cached results for the object or directory at
+// p, including listings of p and of the directory which contains it.
+func (m *MetadataCache) Invalidate(p string) {
+	p = cachePath(p)
+	parent := path.Dir(p)
+	if parent == "." {
+		parent = ""
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for key, entry := range m.entries {
+		if entry.path == p || (entry.list != nil && entry.path == parent) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// InvalidatePrefix discards the cached results for p and every path below
+// it, as well as listings of the directory which contains p, for use after
+// a tree is changed.
+func (m *MetadataCache) InvalidatePrefix(p string) {
+	p = cachePath(p)
+	parent := path.Dir(p)
+	if parent == "." {
+		parent = ""
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for key, entry := range m.entries {
+		if p == "" || entry.path == p || strings.HasPrefix(entry.path, p+"/") || (entry.list != nil && entry.path == parent) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// InvalidateAll discards every cached result.
+func (m *MetadataCache) InvalidateAll() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.entries = map[string]*metadataCacheEntry{}
+	m.nextSweep = 0
+}
+
+// get returns the unexpired entry for key, or nil if there is none.
+func (m *MetadataCache) get(key string) *metadataCacheEntry {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil
+	}
+	return entry
+}
+
+// put caches entry under key. Expired entries are swept whenever the number
+// of entries has doubled since the last sweep, so that results which are
+// never requested again do not accumulate.
+func (m *MetadataCache) put(key string, entry *metadataCacheEntry) {
+	now := time.Now()
+	entry.expires = now.Add(m.options.TTL)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.entries[key] = entry
+	if len(m.entries) < m.nextSweep {
+		return
+	}
+	for key, entry := range m.entries {
+		if now.After(entry.expires) {
+			delete(m.entries, key)
+		}
+	}
+	m.nextSweep = 2*len(m.entries) + 64
+}
+
+// copyHeadObjectOutput returns a copy of output, so that callers cannot
+// change the cached result.
+func copyHeadObjectOutput(output *HeadObjectOutput) *HeadObjectOutput {
+	result := *output
+	if output.Metadata != nil {
+		result.Metadata = make(map[string]string, len(output.Metadata))
+		for key, value := range output.Metadata {
+			result.Metadata[key] = value
+		}
+	}
+	result.RoleTags = copyStrings(output.RoleTags)
+	if output.CORS != nil {
+		cors := *output.CORS
+		cors.AllowMethods = copyStrings(output.CORS.AllowMethods)
+		cors.AllowHeaders = copyStrings(output.CORS.AllowHeaders)
+		cors.ExposeHeaders = copyStrings(output.CORS.ExposeHeaders)
+		result.CORS = &cors
+	}
+	return &result
+}
+
+// copyStrings returns a copy of values, preserving nil.
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// copyListDirectoryOutput returns a copy of output, so that callers cannot
+// change the cached result.
+func copyListDirectoryOutput(output *ListDirectoryOutput) *ListDirectoryOutput {
+	result := *output
+	result.Entries = make([]*DirectoryEntry, len(output.Entries))
+	for i, entry := range output.Entries {
+		copied := *entry
+		result.Entries[i] = &copied
+	}
+	return &result
+}
@@ -0,0 +1,358 @@
+package manta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	// MpuMaxParts is the maximum number of parts in a multipart upload.
+	// Parts are numbered from 0 to MpuMaxParts-1.
+	MpuMaxParts = 10000
+
+	// MpuMinPartSize is the minimum size in bytes of every part of a
+	// multipart upload other than the last.
+	MpuMinPartSize = 5 * 1024 * 1024
+)
+
+const (
+	MpuStateCreated    = "created"
+	MpuStateFinalizing = "finalizing"
+	MpuStateDone       = "done"
+
+	MpuResultCommitted = "committed"
+	MpuResultAborted   = "aborted"
+)
+
+// CreateMpuUploadInput represents parameters to a CreateMpuUpload operation.
+// The fields have the same meaning as the equivalent fields of PutObjectInput,
+// and are applied to the object created when the upload is committed. If
+// ContentLength or ContentMD5 are set, the committed object must match them.
+type CreateMpuUploadInput struct {
+	ObjectPath      string
+	DurabilityLevel uint64
+	ContentType     string
+	ContentLength   uint64
+	ContentMD5      string
+	Metadata        map[string]string
+	Headers         map[string]string
+}
+
+// CreateMpuUploadOutput contains the outputs of a CreateMpuUpload operation.
+type CreateMpuUploadOutput struct {
+	ID             string `json:"id"`
+	PartsDirectory string `json:"partsDirectory"`
+}
+
+// CreateMpuUpload begins a multipart upload, which allows an object too large
+// for a single PUT to be uploaded as a series of independent parts.
+func (c *Client) CreateMpuUpload(input *CreateMpuUploadInput) (*CreateMpuUploadOutput, error) {
+	path := fmt.Sprintf("/%s/uploads", c.accountName)
+
+	headers := &http.Header{}
+	if input.DurabilityLevel != 0 {
+		headers.Set("Durability-Level", strconv.FormatUint(input.DurabilityLevel, 10))
+	}
+	if input.ContentType != "" {
+		headers.Set("Content-Type", input.ContentType)
+	}
+	if input.ContentLength != 0 {
+		headers.Set("Content-Length", strconv.FormatUint(input.ContentLength, 10))
+	}
+	if input.ContentMD5 != "" {
+		headers.Set("Content-MD5", input.ContentMD5)
+	}
+	setMetadataHeaders(headers, input.Metadata)
+	for key, value := range input.Headers {
+		headers.Set(key, value)
+	}
+
+	objectHeaders := map[string]string{}
+	for key := range *headers {
+		objectHeaders[strings.ToLower(key)] = headers.Get(key)
+	}
+
+	body := struct {
+		ObjectPath string            `json:"objectPath"`
+		Headers    map[string]string `json:"headers,omitempty"`
+	}{
+		ObjectPath: fmt.Sprintf("/%s/stor/%s", c.accountName, input.ObjectPath),
+		Headers:    objectHeaders,
+	}
+
+	respBody, _, err := c.executeRequest(http.MethodPost, path, nil, nil, body)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing CreateMpuUpload request: {{err}}", err)
+	}
+
+	output := &CreateMpuUploadOutput{}
+	decoder := json.NewDecoder(respBody)
+	if err = decoder.Decode(output); err != nil {
+		return nil, errwrap.Wrapf("Error decoding CreateMpuUpload response: {{err}}", err)
+	}
+
+	return output, nil
+}
+
+// UploadPartInput represents parameters to an UploadPart operation.
+// ContentLength is handled in the same way as for PutObjectInput.
+type UploadPartInput struct {
+	ID            string
+	PartNumber    uint64
+	ContentLength uint64
+	ContentMD5    string
+	ObjectReader  io.Reader
+}
+
+// UploadPartOutput contains the outputs of an UploadPart operation. The
+// ETag must be passed to CommitUpload.
+type UploadPartOutput struct {
+	PartNumber uint64
+	ETag       string
+}
+
+// UploadPart uploads a single part of a multipart upload. Uploading a part
+// with the same number as an existing part replaces it.
+func (c *Client) UploadPart(input *UploadPartInput) (*UploadPartOutput, error) {
+	if input.PartNumber >= MpuMaxParts {
+		return nil, fmt.Errorf("PartNumber must be less than %d, got %d", MpuMaxParts, input.PartNumber)
+	}
+
+	path := fmt.Sprintf("%s/%d", c.uploadPath(input.ID), input.PartNumber)
+
+	headers := &http.Header{}
+	contentLength := input.ContentLength
+	if contentLength == 0 {
+		if seeker, ok := input.ObjectReader.(io.ReadSeeker); ok {
+			remaining, err := remainingLength(seeker)
+			if err != nil {
+				return nil, errwrap.Wrapf("Error determining Content-Length: {{err}}", err)
+			}
+			contentLength = remaining
+		}
+	}
+	if contentLength != 0 {
+		headers.Set("Content-Length", strconv.FormatUint(contentLength, 10))
+	}
+	if input.ContentMD5 != "" {
+		headers.Set("Content-MD5", input.ContentMD5)
+	}
+
+	respBody, respHeaders, err := c.executeRequestReader(http.MethodPut, path, nil, headers, input.ObjectReader)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing UploadPart request: {{err}}", err)
+	}
+
+	return &UploadPartOutput{
+		PartNumber: input.PartNumber,
+		ETag:       respHeaders.Get("Etag"),
+	}, nil
+}
+
+// MpuPart represents a part of a multipart upload which has been uploaded.
+type MpuPart struct {
+	PartNumber   uint64
+	ETag         string
+	Size         uint64
+	ModifiedTime time.Time
+}
+
+// ListPartsInput represents parameters to a ListParts operation.
+type ListPartsInput struct {
+	ID string
+}
+
+// ListPartsOutput contains the outputs of a ListParts operation. Parts are
+// not necessarily in order of PartNumber.
+type ListPartsOutput struct {
+	Parts         []*MpuPart
+	ResultSetSize uint64
+}
+
+// ListParts lists the parts of a multipart upload which have been uploaded.
+// The parts are requested a page at a time until all of them have been
+// listed.
+func (c *Client) ListParts(input *ListPartsInput) (*ListPartsOutput, error) {
+	output := &ListPartsOutput{}
+
+	marker := ""
+	for page := 0; ; page++ {
+		entries, resultSetSize, err := c.listPartsPage(input.ID, marker)
+		if err != nil {
+			return nil, err
+		}
+		if page == 0 {
+			output.ResultSetSize = resultSetSize
+		}
+
+		for _, entry := range entries {
+			if marker != "" && entry.Name == marker {
+				continue
+			}
+
+			partNumber, err := strconv.ParseUint(entry.Name, 10, 64)
+			if err != nil {
+				continue
+			}
+			output.Parts = append(output.Parts, &MpuPart{
+				PartNumber:   partNumber,
+				ETag:         entry.ETag,
+				Size:         entry.Size,
+				ModifiedTime: entry.ModifiedTime,
+			})
+		}
+
+		if len(entries) < listDirectoryPageSize {
+			return output, nil
+		}
+		marker = entries[len(entries)-1].Name
+	}
+}
+
+// listPartsPage returns a page of the entries of the parts directory of a
+// multipart upload, starting from marker, along with the Result-Set-Size of
+// the directory.
+func (c *Client) listPartsPage(id, marker string) ([]*DirectoryEntry, uint64, error) {
+	path := c.uploadPath(id)
+	query := listDirectoryQuery(&ListDirectoryInput{
+		Limit:  listDirectoryPageSize,
+		Marker: marker,
+	})
+
+	respBody, respHeader, err := c.executeRequest(http.MethodGet, path, query, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, 0, errwrap.Wrapf("Error executing ListParts request: {{err}}", err)
+	}
+
+	var entries []*DirectoryEntry
+	decoder := json.NewDecoder(respBody)
+	for {
+		current := &DirectoryEntry{}
+		if err = decoder.Decode(current); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, 0, errwrap.Wrapf("Error decoding ListParts response: {{err}}", err)
+		}
+		entries = append(entries, current)
+	}
+
+	resultSetSize, _ := strconv.ParseUint(respHeader.Get("Result-Set-Size"), 10, 64)
+	return entries, resultSetSize, nil
+}
+
+// GetUploadInput represents parameters to a GetUpload operation.
+type GetUploadInput struct {
+	ID string
+}
+
+// GetUploadOutput contains the outputs of a GetUpload operation. State is one
+// of the MpuState constants; once it is MpuStateDone, Result is one of the
+// MpuResult constants.
+type GetUploadOutput struct {
+	ID             string            `json:"id"`
+	State          string            `json:"state"`
+	Result         string            `json:"result"`
+	TargetObject   string            `json:"targetObject"`
+	PartsDirectory string            `json:"partsDirectory"`
+	Headers        map[string]string `json:"headers"`
+	NumCopies      uint64            `json:"numCopies"`
+	CreationTimeMs int64             `json:"creationTimeMs"`
+}
+
+// GetUpload returns the state of a multipart upload.
+func (c *Client) GetUpload(input *GetUploadInput) (*GetUploadOutput, error) {
+	path := fmt.Sprintf("%s/state", c.uploadPath(input.ID))
+
+	respBody, _, err := c.executeRequest(http.MethodGet, path, nil, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing GetUpload request: {{err}}", err)
+	}
+
+	output := &GetUploadOutput{}
+	decoder := json.NewDecoder(respBody)
+	if err = decoder.Decode(output); err != nil {
+		return nil, errwrap.Wrapf("Error decoding GetUpload response: {{err}}", err)
+	}
+
+	return output, nil
+}
+
+// CommitUploadInput represents parameters to a CommitUpload operation. Parts
+// holds the ETags of the parts to assemble into the object, in order from
+// part 0.
+type CommitUploadInput struct {
+	ID    string
+	Parts []string
+}
+
+// CommitUpload assembles the uploaded parts of a multipart upload into the
+// target object. Once committed, the upload may not be aborted.
+func (c *Client) CommitUpload(input *CommitUploadInput) error {
+	path := fmt.Sprintf("%s/commit", c.uploadPath(input.ID))
+
+	body := struct {
+		Parts []string `json:"parts"`
+	}{
+		Parts: input.Parts,
+	}
+
+	respBody, _, err := c.executeRequest(http.MethodPost, path, nil, nil, body)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing CommitUpload request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// AbortUploadInput represents parameters to an AbortUpload operation.
+type AbortUploadInput struct {
+	ID string
+}
+
+// AbortUpload abandons a multipart upload, discarding any uploaded parts.
+func (c *Client) AbortUpload(input *AbortUploadInput) error {
+	path := fmt.Sprintf("%s/abort", c.uploadPath(input.ID))
+
+	respBody, _, err := c.executeRequest(http.MethodPost, path, nil, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing AbortUpload request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// uploadPath returns the path of the parts directory of the multipart upload
+// with the given ID, which Manta places under a prefix directory named for
+// the first character of the ID.
+func (c *Client) uploadPath(id string) string {
+	if id == "" {
+		return fmt.Sprintf("/%s/uploads", c.accountName)
+	}
+	return fmt.Sprintf("/%s/uploads/%s/%s", c.accountName, id[:1], id)
+}
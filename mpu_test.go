@@ -0,0 +1,82 @@
+package manta
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// handleTestParts serves the parts directory of the upload with the given ID,
+// which holds the given number of parts, from s.
+func handleTestParts(s *testServer, id string, parts int) {
+	var names []string
+	for i := 0; i < parts; i++ {
+		names = append(names, strconv.Itoa(i))
+	}
+	sort.Strings(names)
+
+	s.handle("uploads/"+id[:1]+"/"+id, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-json-stream; type=directory")
+		w.Header().Set("Result-Set-Size", strconv.Itoa(parts))
+		writeTestDirectoryPage(w, r, names, func(name string) *DirectoryEntry {
+			return &DirectoryEntry{
+				Name:         name,
+				Type:         EntryTypeObject,
+				ETag:         "etag-" + name,
+				Size:         MpuMinPartSize,
+				ModifiedTime: time.Now(),
+			}
+		})
+	})
+}
+
+func TestListParts(t *testing.T) {
+	cases := []struct {
+		name     string
+		parts    int
+		requests int
+	}{
+		{name: "empty", parts: 0, requests: 1},
+		{name: "single page", parts: 3, requests: 1},
+		{name: "full page", parts: listDirectoryPageSize, requests: 2},
+		{name: "one past a page", parts: listDirectoryPageSize + 1, requests: 2},
+		{name: "many pages", parts: MpuMaxParts, requests: MpuMaxParts/(listDirectoryPageSize-1) + 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			id := "a1b2c3"
+			handleTestParts(s, id, tc.parts)
+
+			output, err := client.ListParts(&ListPartsInput{
+				ID: id,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(output.Parts) != tc.parts {
+				t.Fatalf("expected %d parts, got %d", tc.parts, len(output.Parts))
+			}
+			if output.ResultSetSize != uint64(tc.parts) {
+				t.Errorf("expected ResultSetSize %d, got %d", tc.parts, output.ResultSetSize)
+			}
+			seen := map[uint64]bool{}
+			for _, part := range output.Parts {
+				if seen[part.PartNumber] {
+					t.Fatalf("part %d listed twice", part.PartNumber)
+				}
+				seen[part.PartNumber] = true
+				if part.ETag != "etag-"+strconv.FormatUint(part.PartNumber, 10) {
+					t.Errorf("part %d has ETag %q", part.PartNumber, part.ETag)
+				}
+			}
+			if requests := s.countRequests("GET uploads/"); requests != tc.requests {
+				t.Errorf("expected %d requests, got %d", tc.requests, requests)
+			}
+		})
+	}
+}
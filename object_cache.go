@@ -0,0 +1,484 @@
+package manta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// ObjectCacheOptions represents options for an ObjectCache.
+type ObjectCacheOptions struct {
+	// Directory is the local directory in which objects are cached. It is
+	// created if it does not exist, and is required.
+	Directory string
+}
+
+// ObjectCache caches the data of objects on local disk, so that code which
+// repeatedly reads the same large objects does not download them each time.
+// Each object is cached under its path and ETag. When an object is read
+// again, the cached copy is revalidated with a HEAD request, and served from
+// disk if the ETag of the object has not changed. The headers and metadata
+// returned with a cached copy are those of the HEAD response, so changes
+// made by PutObjectMetadata are seen although the data is not downloaded.
+//
+// Objects are stored as returned by GetObject, so objects encrypted on the
+// client are cached decrypted; the directory should be protected
+// accordingly. The cache is kept across processes, and the directory may be
+// shared by multiple ObjectCaches. If an object cannot be written to the
+// cache, it is returned without being cached. Nothing is evicted
+// automatically: use Invalidate or InvalidateAll to remove cached objects.
+//
+// An ObjectCache may be used from multiple goroutines.
+type ObjectCache struct {
+	client    *Client
+	directory string
+
+	// lock serialises updates to the records of cached objects.
+	lock sync.Mutex
+}
+
+// objectCacheRecord describes a cached object. It is stored as JSON
+// alongside the data of the object.
+type objectCacheRecord struct {
+	Path            string
+	ETag            string
+	ContentType     string
+	LastModified    time.Time
+	ContentMD5      string
+	DurabilityLevel uint64
+	CacheControl    string
+	Expires         time.Time
+	CORS            *CORSConfiguration
+	RoleTags        []string
+	Metadata        map[string]string
+}
+
+// NewObjectCache is used to construct an ObjectCache for client, creating
+// the cache directory if necessary.
+func NewObjectCache(client *Client, options *ObjectCacheOptions) (*ObjectCache, error) {
+	if options == nil || options.Directory == "" {
+		return nil, errors.New("NewObjectCache: Directory is required")
+	}
+
+	if err := os.MkdirAll(options.Directory, 0700); err != nil {
+		return nil, errwrap.Wrapf("Error creating object cache directory: {{err}}", err)
+	}
+
+	return &ObjectCache{
+		client:    client,
+		directory: options.Directory,
+	}, nil
+}
+
+// GetObject retrieves an object as for Client.GetObject, serving it from the
+// cache if the cached copy is still current. Otherwise the object is
+// downloaded, and cached once ObjectReader has been read to the end. Ranged
+// and conditional requests, and those which set SkipDecompression, are
+// passed to the client without being cached.
+func (o *ObjectCache) GetObject(input *GetObjectInput) (*GetObjectOutput, error) {
+	if input.RangeOffset != 0 || input.RangeLength != 0 || input.SkipDecompression ||
+		input.IfMatch != "" || input.IfNoneMatch != "" || input.IfModifiedSince != nil || input.IfUnmodifiedSince != nil {
+		return o.client.GetObject(input)
+	}
+
+	objectPath := cachePath(input.ObjectPath)
+	record := o.lookup(objectPath)
+	if record == nil {
+		return o.fetch(objectPath, input)
+	}
+
+	// A conditional GET would be answered with 304 Not Modified, which
+	// does not report changes made to the metadata of the object, so the
+	// cached copy is revalidated with a HEAD request instead.
+	head, err := o.client.HeadObject(&HeadObjectInput{
+		ObjectPath: input.ObjectPath,
+	})
+	if err != nil {
+		if IsResourceNotFoundError(err) || hasStatusCode(err, http.StatusNotFound) {
+			o.Invalidate(objectPath)
+		}
+		return nil, err
+	}
+	if head.ETag != record.ETag {
+		return o.fetch(objectPath, input)
+	}
+
+	if record.refresh(head) {
+		o.update(record)
+	}
+	return o.open(record)
+}
+
+// Invalidate removes the cached copy of the object at p.
+func (o *ObjectCache) Invalidate(p string) error {
+	p = cachePath(p)
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	record := o.readRecord(p)
+	if err := removeIfExists(o.recordFile(p)); err != nil {
+		return err
+	}
+	if record != nil {
+		return removeIfExists(o.dataFile(p, record.ETag))
+	}
+	return nil
+}
+
+// InvalidateAll removes every cached object from the cache directory. Other
+// files in the directory are left in place.
+func (o *ObjectCache) InvalidateAll() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	files, err := ioutil.ReadDir(o.directory)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || !isObjectCacheFile(file.Name()) {
+			continue
+		}
+		if err := removeIfExists(filepath.Join(o.directory, file.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isObjectCacheFile returns whether name is the name of a file written by
+// an ObjectCache: a file named by recordFile or dataFile, or a temporary
+// file created by store or writeFileAtomic.
+func isObjectCacheFile(name string) bool {
+	if suffix := strings.TrimPrefix(name, ".tmp-"); suffix != name {
+		return isDigits(suffix)
+	}
+
+	hash := strings.TrimSuffix(strings.TrimSuffix(name, ".json"), ".data")
+	if hash == name || len(hash) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
+
+// isDigits returns whether s is a non-empty string of decimal digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// fetch downloads the object at objectPath, caching it as it is read.
+func (o *ObjectCache) fetch(objectPath string, input *GetObjectInput) (*GetObjectOutput, error) {
+	output, err := o.client.GetObject(input)
+	if err != nil {
+		return nil, err
+	}
+
+	o.store(objectPath, output)
+	return output, nil
+}
+
+// store arranges for the data of output to be written to the cache as it is
+// read, and for the object to be recorded as cached once it has been read
+// to the end. Objects without an ETag cannot be revalidated, so are not
+// cached.
+func (o *ObjectCache) store(objectPath string, output *GetObjectOutput) {
+	if output.ETag == "" {
+		return
+	}
+
+	file, err := ioutil.TempFile(o.directory, ".tmp-")
+	if err != nil {
+		return
+	}
+
+	output.ObjectReader = &objectCacheReader{
+		body: output.ObjectReader,
+		file: file,
+		commit: func() error {
+			return o.commit(file.Name(), &objectCacheRecord{
+				Path:            objectPath,
+				ETag:            output.ETag,
+				ContentType:     output.ContentType,
+				LastModified:    output.LastModified,
+				ContentMD5:      output.ContentMD5,
+				DurabilityLevel: output.DurabilityLevel,
+				CacheControl:    output.CacheControl,
+				Expires:         output.Expires,
+				CORS:            output.CORS,
+				RoleTags:        output.RoleTags,
+				Metadata:        output.Metadata,
+			})
+		},
+	}
+}
+
+// commit moves the downloaded data of an object into place and records it
+// as cached, replacing any previously cached version.
+func (o *ObjectCache) commit(tempFile string, record *objectCacheRecord) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	previous := o.readRecord(record.Path)
+	if err := os.Rename(tempFile, o.dataFile(record.Path, record.ETag)); err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+	if err := writeFileAtomic(o.directory, o.recordFile(record.Path), encoded); err != nil {
+		return err
+	}
+	if previous != nil && previous.ETag != record.ETag {
+		removeIfExists(o.dataFile(record.Path, previous.ETag))
+	}
+	return nil
+}
+
+// update rewrites the record of a cached object with refreshed headers and
+// metadata, unless the object has been replaced in the cache since the
+// record was read.
+func (o *ObjectCache) update(record *objectCacheRecord) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	current := o.readRecord(record.Path)
+	if current == nil || current.ETag != record.ETag {
+		return nil
+	}
+	return writeFileAtomic(o.directory, o.recordFile(record.Path), encoded)
+}
+
+// refresh updates the record of a cached object with the headers and
+// metadata of head, which describes the same version of the object, and
+// returns whether any of them changed. Metadata describing how the object
+// is encoded is kept only if the record has it: GetObject removes it from
+// objects it decrypts or decompresses, and it cannot change without the
+// data of the object changing.
+func (r *objectCacheRecord) refresh(head *HeadObjectOutput) bool {
+	metadata := make(map[string]string, len(head.Metadata))
+	for key, value := range head.Metadata {
+		if strings.HasPrefix(key, "encrypt-") || strings.HasPrefix(key, "compress-") {
+			if _, ok := r.Metadata[key]; !ok {
+				continue
+			}
+		}
+		metadata[key] = value
+	}
+
+	refreshed := objectCacheRecord{
+		Path:            r.Path,
+		ETag:            r.ETag,
+		ContentType:     head.ContentType,
+		LastModified:    head.LastModified,
+		ContentMD5:      r.ContentMD5,
+		DurabilityLevel: head.DurabilityLevel,
+		CacheControl:    head.CacheControl,
+		Expires:         head.Expires,
+		CORS:            head.CORS,
+		RoleTags:        head.RoleTags,
+		Metadata:        metadata,
+	}
+	// The records are compared as stored, since times read back from JSON
+	// do not compare equal to those parsed from headers.
+	before, err := json.Marshal(r)
+	if err != nil {
+		return false
+	}
+	after, err := json.Marshal(&refreshed)
+	if err != nil || bytes.Equal(before, after) {
+		return false
+	}
+	*r = refreshed
+	return true
+}
+
+// lookup returns the record of the cached copy of the object at objectPath,
+// or nil if it is not cached.
+func (o *ObjectCache) lookup(objectPath string) *objectCacheRecord {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	record := o.readRecord(objectPath)
+	if record == nil {
+		return nil
+	}
+	if _, err := os.Stat(o.dataFile(objectPath, record.ETag)); err != nil {
+		return nil
+	}
+	return record
+}
+
+// open returns the cached copy of an object described by record.
+func (o *ObjectCache) open(record *objectCacheRecord) (*GetObjectOutput, error) {
+	data, err := os.Open(o.dataFile(record.Path, record.ETag))
+	if err != nil {
+		// The object was replaced in the cache since it was looked up,
+		// so is requested again.
+		return o.GetObject(&GetObjectInput{
+			ObjectPath: record.Path,
+		})
+	}
+
+	info, err := data.Stat()
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+
+	return &GetObjectOutput{
+		ContentLength:   uint64(info.Size()),
+		ContentType:     record.ContentType,
+		LastModified:    record.LastModified,
+		ContentMD5:      record.ContentMD5,
+		ETag:            record.ETag,
+		DurabilityLevel: record.DurabilityLevel,
+		CacheControl:    record.CacheControl,
+		Expires:         record.Expires,
+		CORS:            record.CORS,
+		RoleTags:        record.RoleTags,
+		Metadata:        record.Metadata,
+		ObjectReader:    data,
+	}, nil
+}
+
+// readRecord returns the record of the cached copy of the object at
+// objectPath, or nil if there is none.
+func (o *ObjectCache) readRecord(objectPath string) *objectCacheRecord {
+	encoded, err := ioutil.ReadFile(o.recordFile(objectPath))
+	if err != nil {
+		return nil
+	}
+
+	record := &objectCacheRecord{}
+	if err := json.Unmarshal(encoded, record); err != nil || record.Path != objectPath {
+		return nil
+	}
+	return record
+}
+
+// recordFile returns the path of the file recording the cached copy of the
+// object at objectPath.
+func (o *ObjectCache) recordFile(objectPath string) string {
+	sum := sha256.Sum256([]byte(objectPath))
+	return filepath.Join(o.directory, hex.EncodeToString(sum[:])+".json")
+}
+
+// dataFile returns the path of the file holding the data of the version of
+// the object at objectPath with the given ETag.
+func (o *ObjectCache) dataFile(objectPath, etag string) string {
+	sum := sha256.Sum256([]byte(objectPath + "\x00" + etag))
+	return filepath.Join(o.directory, hex.EncodeToString(sum[:])+".data")
+}
+
+// objectCacheReader copies the data of an object to a file as it is read,
+// and commits the file to the cache once the object has been read to the
+// end. If it is closed first, or reading or writing fails, the file is
+// discarded. Failures to write the cache are not reported to the reader,
+// since the object is read successfully regardless.
+type objectCacheReader struct {
+	body   io.ReadCloser
+	file   *os.File
+	commit func() error
+}
+
+func (r *objectCacheReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if r.file == nil {
+		return n, err
+	}
+
+	if n > 0 {
+		if _, writeErr := r.file.Write(p[:n]); writeErr != nil {
+			r.discard()
+		}
+	}
+	if err == io.EOF && r.file != nil {
+		file := r.file
+		r.file = nil
+		if file.Close() == nil {
+			r.commit()
+		} else {
+			os.Remove(file.Name())
+		}
+	} else if err != nil {
+		r.discard()
+	}
+	return n, err
+}
+
+func (r *objectCacheReader) Close() error {
+	r.discard()
+	return r.body.Close()
+}
+
+// discard removes the partially written file, if any.
+func (r *objectCacheReader) discard() {
+	if r.file == nil {
+		return
+	}
+	r.file.Close()
+	os.Remove(r.file.Name())
+	r.file = nil
+}
+
+// writeFileAtomic writes data to name by way of a temporary file in
+// directory, so that readers never see a partially written file.
+func writeFileAtomic(directory, name string, data []byte) error {
+	file, err := ioutil.TempFile(directory, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), name); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// removeIfExists removes the file name, ignoring its absence.
+func removeIfExists(name string) error {
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package manta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestObjectCache(t *testing.T) {
+	cases := []struct {
+		name     string
+		compress bool
+		change   func(t *testing.T, s *testServer, client *Client)
+		data     string
+		metadata map[string]string
+		gets     int
+	}{
+		{
+			name:     "unchanged",
+			data:     "original",
+			metadata: map[string]string{"version": "1"},
+			gets:     1,
+		},
+		{
+			name:     "compressed",
+			compress: true,
+			data:     "original",
+			metadata: map[string]string{"version": "1"},
+			gets:     1,
+		},
+		{
+			name: "metadata changed",
+			change: func(t *testing.T, s *testServer, client *Client) {
+				err := client.PutObjectMetadata(&PutObjectMetadataInput{
+					ObjectPath: "object",
+					Metadata:   map[string]string{"version": "2"},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			data:     "original",
+			metadata: map[string]string{"version": "2"},
+			gets:     1,
+		},
+		{
+			name: "object replaced",
+			change: func(t *testing.T, s *testServer, client *Client) {
+				err := client.PutObject(&PutObjectInput{
+					ObjectPath:   "object",
+					Metadata:     map[string]string{"version": "3"},
+					ObjectReader: strings.NewReader("replaced"),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			data:     "replaced",
+			metadata: map[string]string{"version": "3"},
+			gets:     2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			err := client.PutObject(&PutObjectInput{
+				ObjectPath:   "object",
+				Compress:     tc.compress,
+				Metadata:     map[string]string{"version": "1"},
+				ObjectReader: strings.NewReader("original"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cache, err := NewObjectCache(client, &ObjectCacheOptions{
+				Directory: t.TempDir(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if i == 1 && tc.change != nil {
+					tc.change(t, s, client)
+				}
+
+				output, err := cache.GetObject(&GetObjectInput{ObjectPath: "object"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				data := readTestBody(t, output.ObjectReader)
+				output.ObjectReader.Close()
+
+				if i == 0 {
+					continue
+				}
+				if data != tc.data {
+					t.Errorf("data = %q, want %q", data, tc.data)
+				}
+				if len(output.Metadata) != len(tc.metadata) {
+					t.Errorf("metadata = %v, want %v", output.Metadata, tc.metadata)
+				}
+				for key, value := range tc.metadata {
+					if output.Metadata[key] != value {
+						t.Errorf("metadata = %v, want %v", output.Metadata, tc.metadata)
+					}
+				}
+			}
+
+			if gets := s.countRequests("GET stor/object"); gets != tc.gets {
+				t.Errorf("made %d GET requests, want %d", gets, tc.gets)
+			}
+		})
+	}
+}
+
+func TestObjectCacheRemoved(t *testing.T) {
+	s, client := newTestServer(t)
+	s.put("object", "data", nil)
+
+	directory := t.TempDir()
+	cache, err := NewObjectCache(client, &ObjectCacheOptions{
+		Directory: directory,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := cache.GetObject(&GetObjectInput{ObjectPath: "object"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readTestBody(t, output.ObjectReader)
+	output.ObjectReader.Close()
+
+	if err := client.DeleteObject(&DeleteObjectInput{ObjectPath: "object"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetObject(&GetObjectInput{ObjectPath: "object"}); err == nil {
+		t.Fatal("expected an error reading a removed object")
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".json") {
+			t.Errorf("record %s left in the cache for a removed object", file.Name())
+		}
+	}
+}
+
+func TestObjectCacheInvalidateAll(t *testing.T) {
+	s, client := newTestServer(t)
+	s.put("object", "data", nil)
+
+	directory := t.TempDir()
+	cache, err := NewObjectCache(client, &ObjectCacheOptions{
+		Directory: directory,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := cache.GetObject(&GetObjectInput{ObjectPath: "object"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readTestBody(t, output.ObjectReader)
+	output.ObjectReader.Close()
+
+	unrelated := []string{"settings.json", "backup.data", ".tmp-unrelated"}
+	for _, name := range unrelated {
+		if err := ioutil.WriteFile(filepath.Join(directory, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cache.InvalidateAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(unrelated) {
+		var names []string
+		for _, file := range files {
+			names = append(names, file.Name())
+		}
+		t.Errorf("files left in the cache directory = %v, want %v", names, unrelated)
+	}
+	for _, name := range unrelated {
+		if _, err := os.Stat(filepath.Join(directory, name)); err != nil {
+			t.Errorf("unrelated file %s was removed", name)
+		}
+	}
+}
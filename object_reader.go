@@ -0,0 +1,204 @@
+package manta
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultObjectReaderReadAhead = 256 * 1024
+
+// ObjectReaderOptions represents the configuration of an ObjectReader.
+type ObjectReaderOptions struct {
+	// ReadAhead is the minimum number of bytes requested at once. Reads
+	// smaller than this are served from a buffer filled by a single ranged
+	// request. If zero, DefaultObjectReaderReadAhead is used.
+	ReadAhead int
+}
+
+// ObjectReader provides random access to an object in Manta using ranged
+// requests, so that formats such as zip can be read without downloading the
+// whole object. It implements io.ReaderAt, io.ReadSeeker and io.Closer.
+// ReadAt may be called concurrently; Read and Seek may not.
+//
+// Every request is made conditional on the ETag of the object when the
+// ObjectReader was created, so reads fail if the object is replaced.
+//
+// Objects compressed or encrypted by PutObject cannot be decoded from an
+// arbitrary offset, so NewObjectReader returns ErrObjectNotSeekable for them;
+// use GetObject to read them from the start.
+type ObjectReader struct {
+	client     *Client
+	objectPath string
+	etag       string
+	size       int64
+	readAhead  int
+
+	offset int64
+
+	lock         sync.Mutex
+	buffer       []byte
+	bufferOffset int64
+	closed       bool
+}
+
+// NewObjectReader is used to construct an ObjectReader for the object at
+// objectPath. If options is nil, default options are used.
+func NewObjectReader(client *Client, objectPath string, options *ObjectReaderOptions) (*ObjectReader, error) {
+	head, err := client.HeadObject(&HeadObjectInput{
+		ObjectPath: objectPath,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing NewObjectReader request: {{err}}", err)
+	}
+	if isEncodedObject(head.Metadata) {
+		return nil, ErrObjectNotSeekable
+	}
+
+	reader := &ObjectReader{
+		client:     client,
+		objectPath: objectPath,
+		etag:       head.ETag,
+		size:       int64(head.ContentLength),
+		readAhead:  DefaultObjectReaderReadAhead,
+	}
+	if options != nil && options.ReadAhead > 0 {
+		reader.readAhead = options.ReadAhead
+	}
+
+	return reader, nil
+}
+
+// ErrObjectNotSeekable is returned by NewObjectReader, and wrapped in the
+// error returned by ranged GetObject requests which would decode the data,
+// for objects which were compressed or encrypted by PutObject, and so can
+// only be read sequentially.
+var ErrObjectNotSeekable = errors.New("Objects compressed or encrypted by the client cannot be read at arbitrary offsets")
+
+// Size returns the size of the object in bytes.
+func (r *ObjectReader) Size() int64 {
+	return r.size
+}
+
+// ETag returns the ETag of the object being read.
+func (r *ObjectReader) ETag() string {
+	return r.etag
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("ObjectReader.ReadAt: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	want := int64(len(p))
+	if off+want > r.size {
+		want = r.size - off
+	}
+
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		return 0, errors.New("ObjectReader: read after Close")
+	}
+	if off >= r.bufferOffset && off+want <= r.bufferOffset+int64(len(r.buffer)) {
+		n := copy(p[:want], r.buffer[off-r.bufferOffset:])
+		r.lock.Unlock()
+		return r.result(n, len(p))
+	}
+	r.lock.Unlock()
+
+	if want >= int64(r.readAhead) {
+		n, err := r.fetch(p[:want], off)
+		if err != nil {
+			return n, err
+		}
+		return r.result(n, len(p))
+	}
+
+	length := int64(r.readAhead)
+	if off+length > r.size {
+		length = r.size - off
+	}
+	buffer := make([]byte, length)
+	if _, err := r.fetch(buffer, off); err != nil {
+		return 0, err
+	}
+
+	r.lock.Lock()
+	r.buffer = buffer
+	r.bufferOffset = off
+	r.lock.Unlock()
+
+	n := copy(p[:want], buffer)
+	return r.result(n, len(p))
+}
+
+// result returns io.EOF alongside a read which stopped short at the end of
+// the object.
+func (r *ObjectReader) result(n, requested int) (int, error) {
+	if n < requested {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch fills p with the data of the object starting at off.
+func (r *ObjectReader) fetch(p []byte, off int64) (int, error) {
+	output, err := r.client.GetObject(&GetObjectInput{
+		ObjectPath:  r.objectPath,
+		IfMatch:     r.etag,
+		RangeOffset: uint64(off),
+		RangeLength: uint64(len(p)),
+	})
+	if err != nil {
+		return 0, errwrap.Wrapf("Error executing ObjectReader request: {{err}}", err)
+	}
+	defer output.ObjectReader.Close()
+
+	return io.ReadFull(output.ObjectReader, p)
+}
+
+// Read implements io.Reader.
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return r.offset, errors.New("ObjectReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return r.offset, errors.New("ObjectReader.Seek: negative position")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+// Close implements io.Closer, releasing the read-ahead buffer.
+func (r *ObjectReader) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.closed = true
+	r.buffer = nil
+	return nil
+}
@@ -0,0 +1,52 @@
+package manta
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestObjectReader(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+
+	cases := []struct {
+		name     string
+		compress bool
+		err      error
+	}{
+		{name: "plain"},
+		{name: "compressed", compress: true, err: ErrObjectNotSeekable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, client := newTestServer(t)
+			err := client.PutObject(&PutObjectInput{
+				ObjectPath:   "object",
+				Compress:     tc.compress,
+				ObjectReader: strings.NewReader(data),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := NewObjectReader(client, "object", &ObjectReaderOptions{
+				ReadAhead: 64,
+			})
+			if err != tc.err {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			defer reader.Close()
+
+			if _, err := reader.Seek(995, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestBody(t, reader); got != data[995:] {
+				t.Errorf("expected %q after seeking, got %q", data[995:], got)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package manta
+
+import (
+	"errors"
+	"io"
+)
+
+// ObjectWriter is an io.WriteCloser which uploads the data written to it as
+// an object, so that encoders and archive writers can target Manta directly.
+// The upload is made by an Uploader, so small objects are sent with a single
+// request and large ones with a multipart upload. The object is not complete
+// until Close returns successfully.
+type ObjectWriter struct {
+	pipe   *io.PipeWriter
+	done   chan struct{}
+	output *UploadOutput
+	err    error
+}
+
+// NewObjectWriter is used to construct an ObjectWriter which uploads an
+// object as described by input, using an Uploader configured by options.
+// The ObjectReader of input is ignored. If options is nil, default options
+// are used.
+func NewObjectWriter(client *Client, input *UploadInput, options *UploaderOptions) *ObjectWriter {
+	reader, writer := io.Pipe()
+
+	w := &ObjectWriter{
+		pipe: writer,
+		done: make(chan struct{}),
+	}
+
+	uploadInput := *input
+	uploadInput.ObjectReader = reader
+	uploader := NewUploader(client, options)
+
+	go func() {
+		defer close(w.done)
+		w.output, w.err = uploader.Upload(&uploadInput)
+		// Unblock any writer if the upload stopped reading early.
+		reader.CloseWithError(w.err)
+	}()
+
+	return w
+}
+
+// Write implements io.Writer. If the upload has failed, the error is
+// returned.
+func (w *ObjectWriter) Write(p []byte) (int, error) {
+	return w.pipe.Write(p)
+}
+
+// Close implements io.Closer, completing the upload and waiting for it to
+// finish. The error returned is that of the upload.
+func (w *ObjectWriter) Close() error {
+	w.pipe.Close()
+	<-w.done
+	return w.err
+}
+
+// Abort abandons the upload, so that no object is created or replaced, and
+// waits for it to stop.
+func (w *ObjectWriter) Abort(err error) {
+	if err == nil {
+		err = errors.New("ObjectWriter: upload aborted")
+	}
+	w.pipe.CloseWithError(err)
+	<-w.done
+}
+
+// Output returns the output of the upload once Close has returned.
+func (w *ObjectWriter) Output() *UploadOutput {
+	return w.output
+}
@@ -1,11 +1,17 @@
 package manta
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -13,39 +19,112 @@ import (
 	"github.com/hashicorp/errwrap"
 )
 
-// GetObjectInput represents parameters to a GetObject operation.
+const (
+	// DefaultDurabilityLevel is the number of copies of an object stored by
+	// Manta when no durability level is requested.
+	DefaultDurabilityLevel = 2
+
+	// MinDurabilityLevel and MaxDurabilityLevel bound the number of copies
+	// of an object which may be requested.
+	MinDurabilityLevel = 1
+	MaxDurabilityLevel = 6
+)
+
+// GetObjectInput represents parameters to a GetObject operation. If either of
+// RangeOffset or RangeLength is non-zero, only the requested byte range of the
+// object is retrieved. A RangeLength of zero means "until the end of the object".
 type GetObjectInput struct {
-	ObjectPath string
+	ObjectPath        string
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
+	RangeOffset       uint64
+	RangeLength       uint64
+
+	// SkipDecompression returns the data of an object compressed by
+	// PutObject as stored, rather than decompressing it.
+	SkipDecompression bool
 }
 
 // GetObjectOutput contains the outputs for a GetObject operation. It is your
 // responsibility to ensure that the io.ReadCloser ObjectReader is closed.
+// Metadata holds the user metadata stored with the object, keyed by lower case
+// name without the "m-" header prefix.
 type GetObjectOutput struct {
-	ContentLength uint64
-	ContentType   string
-	LastModified  time.Time
-	ContentMD5    string
-	ETag          string
-	Metadata      map[string]string
-	ObjectReader  io.ReadCloser
+	ContentLength   uint64
+	ContentType     string
+	LastModified    time.Time
+	ContentMD5      string
+	ETag            string
+	DurabilityLevel uint64
+	ContentRange    string
+	CacheControl    string
+	Expires         time.Time
+	CORS            *CORSConfiguration
+	RoleTags        []string
+	Metadata        map[string]string
+	ObjectReader    io.ReadCloser
 }
 
 // GetObject retrieves an object from the Manta service. If error is nil (i.e.
 // the call returns successfully), it is your responsibility to close the io.ReadCloser
 // named ObjectReader in the operation output.
+//
+// If IfNoneMatch or IfModifiedSince are set and the object has not changed,
+// a *MantaError with a StatusCode of 304 (Not Modified) is returned.
+//
+// For ranged requests, ContentLength is the length of the range returned and
+// ContentRange holds the Content-Range header sent by the server.
+//
+// If the object was stored with a SHA-256 digest, the data is verified as it
+// is read, and a *ChecksumMismatchError is returned in place of io.EOF if the
+// digest does not match. Ranged requests are not verified.
+//
+// If the connection fails part way through reading ObjectReader, the rest of
+// the object is requested from the point reached, on the condition that the
+// object has not been replaced in the meantime, so the failure is only seen if
+// the object cannot be read again.
+//
+// If the client was constructed with an EncryptionKeyProvider, objects
+// encrypted on the client are decrypted as they are read, and ContentLength
+// is the length of the plaintext. Ranged requests for encrypted objects fail
+// with an error wrapping ErrObjectNotSeekable.
+//
+// Objects compressed by PutObject are decompressed as they are read unless
+// SkipDecompression is set, in which case the compression metadata is left in
+// Metadata. ContentLength is the length of the decompressed data if it was
+// known when the object was uploaded, and zero otherwise. Ranged requests for
+// compressed objects must set SkipDecompression, and otherwise fail with an
+// error wrapping ErrObjectNotSeekable.
 func (c *Client) GetObject(input *GetObjectInput) (*GetObjectOutput, error) {
 	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.ObjectPath)
+	headers := &http.Header{}
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if input.RangeOffset != 0 || input.RangeLength != 0 {
+		if input.RangeLength != 0 {
+			headers.Set("Range", fmt.Sprintf("bytes=%d-%d", input.RangeOffset, input.RangeOffset+input.RangeLength-1))
+		} else {
+			headers.Set("Range", fmt.Sprintf("bytes=%d-", input.RangeOffset))
+		}
+	}
+	// Stop the transport from decompressing objects stored with a
+	// Content-Encoding of gzip itself, which would hide their headers.
+	headers.Set("Accept-Encoding", "identity")
 
-	respBody, respHeaders, err := c.executeRequest(http.MethodGet, path, nil, nil, nil)
+	respBody, respHeaders, err := c.executeRequest(http.MethodGet, path, nil, headers, nil)
 	if err != nil {
-		respBody.Close()
-		return nil, errwrap.Wrapf("Error executing GetDirectory request: {{err}}", err)
+		return nil, errwrap.Wrapf("Error executing GetObject request: {{err}}", err)
 	}
 
 	response := &GetObjectOutput{
 		ContentType:  respHeaders.Get("Content-Type"),
 		ContentMD5:   respHeaders.Get("Content-MD5"),
 		ETag:         respHeaders.Get("Etag"),
+		ContentRange: respHeaders.Get("Content-Range"),
+		CacheControl: respHeaders.Get("Cache-Control"),
+		CORS:         parseCORSHeaders(respHeaders),
+		RoleTags:     splitHeaderList(respHeaders.Get("Role-Tag")),
 		ObjectReader: respBody,
 	}
 
@@ -54,18 +133,173 @@ func (c *Client) GetObject(input *GetObjectInput) (*GetObjectOutput, error) {
 		response.LastModified = lastModified
 	}
 
+	expires, err := http.ParseTime(respHeaders.Get("Expires"))
+	if err == nil {
+		response.Expires = expires
+	}
+
 	contentLength, err := strconv.ParseUint(respHeaders.Get("Content-Length"), 10, 64)
 	if err == nil {
 		response.ContentLength = contentLength
+		if response.ETag != "" && contentLength != 0 {
+			response.ObjectReader = &resumingReader{
+				client:    c,
+				path:      path,
+				etag:      response.ETag,
+				body:      respBody,
+				offset:    input.RangeOffset,
+				remaining: contentLength,
+			}
+		}
 	}
 
-	metadata := map[string]string{}
-	for key, values := range respHeaders {
-		if strings.HasPrefix(key, "m-") {
-			metadata[key] = strings.Join(values, ", ")
+	durabilityLevel, err := strconv.ParseUint(respHeaders.Get("Durability-Level"), 10, 64)
+	if err == nil {
+		response.DurabilityLevel = durabilityLevel
+	}
+
+	response.Metadata = parseMetadataHeaders(respHeaders)
+
+	ranged := input.RangeOffset != 0 || input.RangeLength != 0
+	if c.encryptionKeys != nil && respHeaders.Get(encryptTypeHeader) != "" {
+		if err := c.decryptObject(response, respHeaders, ranged); err != nil {
+			response.ObjectReader.Close()
+			return nil, errwrap.Wrapf("Error decrypting object: {{err}}", err)
+		}
+	}
+	decrypted := c.encryptionKeys != nil || respHeaders.Get(encryptTypeHeader) == ""
+	if decrypted && !input.SkipDecompression && respHeaders.Get(compressTypeHeader) != "" {
+		if err := decompressObject(response, respHeaders, ranged); err != nil {
+			response.ObjectReader.Close()
+			return nil, errwrap.Wrapf("Error decompressing object: {{err}}", err)
 		}
 	}
-	response.Metadata = metadata
+	decompressed := !input.SkipDecompression || respHeaders.Get(compressTypeHeader) == ""
+	if expected := respHeaders.Get(contentSHA256Header); expected != "" && decrypted && decompressed && !ranged {
+		response.ObjectReader = newVerifyingReader(response.ObjectReader, input.ObjectPath, expected)
+	}
+
+	return response, nil
+}
+
+// GetObjectToOutput contains the outputs of a GetObjectTo operation. The
+// fields other than BytesWritten are as for GetObjectOutput.
+type GetObjectToOutput struct {
+	BytesWritten    uint64
+	ContentLength   uint64
+	ContentType     string
+	LastModified    time.Time
+	ContentMD5      string
+	ETag            string
+	DurabilityLevel uint64
+	ContentRange    string
+	Metadata        map[string]string
+}
+
+// GetObjectTo retrieves an object as for GetObject, and copies its data into
+// writer using io.Copy, so that writers implementing io.ReaderFrom such as
+// *os.File can receive it efficiently. If the length of the object is known
+// and fewer bytes are received, io.ErrUnexpectedEOF is returned.
+func (c *Client) GetObjectTo(writer io.Writer, input *GetObjectInput) (*GetObjectToOutput, error) {
+	object, err := c.GetObject(input)
+	if err != nil {
+		return nil, err
+	}
+	defer object.ObjectReader.Close()
+
+	output := &GetObjectToOutput{
+		ContentLength:   object.ContentLength,
+		ContentType:     object.ContentType,
+		LastModified:    object.LastModified,
+		ContentMD5:      object.ContentMD5,
+		ETag:            object.ETag,
+		DurabilityLevel: object.DurabilityLevel,
+		ContentRange:    object.ContentRange,
+		Metadata:        object.Metadata,
+	}
+
+	n, err := io.Copy(writer, object.ObjectReader)
+	output.BytesWritten = uint64(n)
+	if err == nil && object.ContentLength != 0 && output.BytesWritten < object.ContentLength {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return output, errwrap.Wrapf("Error copying object for GetObjectTo: {{err}}", err)
+	}
+
+	return output, nil
+}
+
+// HeadObjectInput represents parameters to a HeadObject operation.
+type HeadObjectInput struct {
+	ObjectPath        string
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
+}
+
+// HeadObjectOutput contains the outputs for a HeadObject operation. Metadata is
+// keyed in the same way as for GetObjectOutput.
+type HeadObjectOutput struct {
+	ContentLength   uint64
+	ContentType     string
+	LastModified    time.Time
+	ContentMD5      string
+	ETag            string
+	DurabilityLevel uint64
+	CacheControl    string
+	Expires         time.Time
+	CORS            *CORSConfiguration
+	RoleTags        []string
+	Metadata        map[string]string
+}
+
+// HeadObject retrieves the metadata of an object from the Manta service,
+// without downloading the object itself.
+func (c *Client) HeadObject(input *HeadObjectInput) (*HeadObjectOutput, error) {
+	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.ObjectPath)
+	headers := &http.Header{}
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+
+	respBody, respHeaders, err := c.executeRequest(http.MethodHead, path, nil, headers, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing HeadObject request: {{err}}", err)
+	}
+
+	response := &HeadObjectOutput{
+		ContentType:  respHeaders.Get("Content-Type"),
+		ContentMD5:   respHeaders.Get("Content-MD5"),
+		ETag:         respHeaders.Get("Etag"),
+		CacheControl: respHeaders.Get("Cache-Control"),
+		CORS:         parseCORSHeaders(respHeaders),
+		RoleTags:     splitHeaderList(respHeaders.Get("Role-Tag")),
+	}
+
+	lastModified, err := time.Parse(time.RFC1123, respHeaders.Get("Last-Modified"))
+	if err == nil {
+		response.LastModified = lastModified
+	}
+
+	expires, err := http.ParseTime(respHeaders.Get("Expires"))
+	if err == nil {
+		response.Expires = expires
+	}
+
+	contentLength, err := strconv.ParseUint(respHeaders.Get("Content-Length"), 10, 64)
+	if err == nil {
+		response.ContentLength = contentLength
+	}
+
+	durabilityLevel, err := strconv.ParseUint(respHeaders.Get("Durability-Level"), 10, 64)
+	if err == nil {
+		response.DurabilityLevel = durabilityLevel
+	}
+
+	response.Metadata = parseMetadataHeaders(respHeaders)
 
 	return response, nil
 }
@@ -73,13 +307,29 @@ func (c *Client) GetObject(input *GetObjectInput) (*GetObjectOutput, error) {
 // DeleteObjectInput represents parameters to a DeleteObject operation.
 type DeleteObjectInput struct {
 	ObjectPath string
+
+	// IfMatch, if set, is the ETag the object must have for it to be
+	// deleted. Passing the ETag returned when the object was read or
+	// written allows a compare-and-delete which fails rather than
+	// removing an object replaced by another writer in the meantime.
+	IfMatch string
+
+	// IfNoneMatch, IfModifiedSince and IfUnmodifiedSince set the
+	// corresponding HTTP conditional request headers.
+	IfNoneMatch       string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
 }
 
-// DeleteObject deletes an object.
+// DeleteObject deletes an object. If any of the conditional fields of the
+// input are set and the condition does not hold, the object is left in place
+// and an error for which IsPreconditionFailedError returns true is returned.
 func (c *Client) DeleteObject(input *DeleteObjectInput) error {
 	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.ObjectPath)
+	headers := &http.Header{}
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
 
-	respBody, _, err := c.executeRequest(http.MethodDelete, path, nil, nil, nil)
+	respBody, _, err := c.executeRequest(http.MethodDelete, path, nil, headers, nil)
 	if respBody != nil {
 		defer respBody.Close()
 	}
@@ -91,16 +341,28 @@ func (c *Client) DeleteObject(input *DeleteObjectInput) error {
 }
 
 // PutObjectMetadataInput represents parameters to a PutObjectMetadata operation.
+// Metadata keys and the caching, CORS and role tag fields are handled in the
+// same way as for PutObject.
 type PutObjectMetadataInput struct {
-	ObjectPath  string
-	ContentType string
-	Metadata    map[string]string
+	ObjectPath   string
+	ContentType  string
+	CacheControl string
+	Expires      *time.Time
+	CORS         *CORSConfiguration
+	RoleTags     []string
+	Metadata     map[string]string
+
+	// IfMatch, if set, makes the update conditional on the object having
+	// this ETag, so that metadata read from one version of an object is
+	// not written to a version which replaced it.
+	IfMatch string
 }
 
 // PutObjectMetadata allows you to overwrite the HTTP headers for an already
 // existing object, without changing the data. Note this is an idempotent "replace"
 // operation, so you must specify the complete set of HTTP headers you want
-// stored on each request.
+// stored on each request. If ContentType is empty, the Content-Type header is
+// omitted from the request.
 //
 // You cannot change "critical" headers:
 // 	- Content-Length
@@ -112,10 +374,14 @@ func (c *Client) PutObjectMetadata(input *PutObjectMetadataInput) error {
 	query.Set("metadata", "true")
 
 	headers := &http.Header{}
-	headers.Set("Content-Type", input.ContentType)
-	for key, value := range input.Metadata {
-		headers.Set(key, value)
+	if input.ContentType != "" {
+		headers.Set("Content-Type", input.ContentType)
 	}
+	setCacheHeaders(headers, input.CacheControl, input.Expires)
+	setCORSHeaders(headers, input.CORS)
+	setRoleTagHeader(headers, input.RoleTags)
+	setMetadataHeaders(headers, input.Metadata)
+	setConditionalHeaders(headers, input.IfMatch, "", nil, nil)
 
 	respBody, _, err := c.executeRequest(http.MethodPut, path, query, headers, nil)
 	if respBody != nil {
@@ -130,17 +396,110 @@ func (c *Client) PutObjectMetadata(input *PutObjectMetadataInput) error {
 
 // PutObjectInput represents parameters to a PutObject operation.
 type PutObjectInput struct {
-	ObjectPath       string
-	DurabilityLevel  uint64
+	// ObjectPath is the path of the object, relative to the account's
+	// /stor directory.
+	ObjectPath string
+
+	// DurabilityLevel is the number of copies of the object Manta should
+	// store, between MinDurabilityLevel and MaxDurabilityLevel. If it is
+	// zero, the server default of DefaultDurabilityLevel copies is used.
+	DurabilityLevel uint64
+
+	// ContentType is the media type of the object. If it is empty, it is
+	// inferred from the extension of ObjectPath. If that fails and
+	// SniffContentType is set, the first 512 bytes of the data are
+	// examined using the algorithm of http.DetectContentType.
 	ContentType      string
-	ContentMD5       string
-	IfMatch          string
-	IfModifiedSince  *time.Time
+	SniffContentType bool
+
+	// ContentMD5 is the base64-encoded MD5 digest of the object data. If
+	// ComputeMD5 is set and ContentMD5 is not, the digest is calculated by
+	// the client. For an io.ReadSeeker this happens before the upload so
+	// that Manta can reject corrupted data; otherwise the digest is
+	// computed as the data is streamed, and compared with the digest
+	// computed by Manta once the upload completes.
+	ContentMD5 string
+	ComputeMD5 bool
+
+	// ComputeSHA256 stores the hex encoded SHA-256 digest of the object
+	// data as the "content-sha256" metadata item, which GetObject verifies
+	// when the whole object is read. The digest is computed before the
+	// upload, so ObjectReader must be an io.Seeker or GetBody must be set.
+	ComputeSHA256 bool
+
+	// IfMatch, IfNoneMatch, IfModifiedSince and IfUnmodifiedSince set the
+	// corresponding HTTP conditional request headers.
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
+
+	// CreateOnly makes the request fail with an *AlreadyExistsError if an
+	// object already exists at ObjectPath, by sending If-None-Match: *.
+	// This allows an object to be used as a lock. It may not be combined
+	// with IfNoneMatch.
+	CreateOnly bool
+
+	// ContentLength is the size of the object in bytes. If it is zero and
+	// ObjectReader is an io.Seeker, the remaining length of ObjectReader
+	// is used. Otherwise the length is unknown, and the data is sent using
+	// chunked transfer encoding. In that case MaxContentLength may be set
+	// to an upper bound on the size of the object, which Manta uses to
+	// reserve space; the server default is 5GB. ContentLength and
+	// MaxContentLength may not both be set.
 	ContentLength    uint64
 	MaxContentLength uint64
-	ObjectReader     io.ReadSeeker
+
+	// CacheControl and Expires are stored with the object and returned as
+	// the Cache-Control and Expires headers when it is retrieved, which
+	// allows browsers and proxies to cache objects served from /public.
+	CacheControl string
+	Expires      *time.Time
+
+	// CORS sets the access-control-* headers Manta returns with the object,
+	// allowing it to be fetched by scripts served from other origins.
+	CORS *CORSConfiguration
+
+	// RoleTags are the names of the RBAC roles which are granted access to
+	// the object, sent as the Role-Tag header.
+	RoleTags []string
+
+	// Metadata holds user metadata to store with the object. Each key is
+	// sent as an "m-" prefixed header, which is added if the key does not
+	// already have it.
+	Metadata map[string]string
+
+	// Headers are additional HTTP headers sent as-is, after the headers
+	// derived from the other fields of the input.
+	Headers map[string]string
+
+	// Compress gzips the object data as it is uploaded, storing it with a
+	// Content-Encoding of gzip. GetObject decompresses such objects
+	// transparently. The compressed length is not known in advance, so
+	// the data is sent using chunked transfer encoding and ContentMD5 is
+	// ignored.
+	Compress bool
+
+	// ObjectReader is the source of the object data, which is read as it
+	// is sent so that arbitrarily large objects may be uploaded without
+	// buffering them in memory. If ObjectReader is also an io.Seeker the
+	// request may be retried, resending the data from the position of
+	// ObjectReader when PutObject was called; otherwise it is attempted
+	// once.
+	ObjectReader io.Reader
+
+	// GetBody may be set instead of ObjectReader to allow retries of a
+	// request whose data cannot be rewound. It is called to obtain a fresh
+	// copy of the object data for each attempt, and if the result is an
+	// io.Closer it is closed once no longer needed. ContentLength should
+	// be set, since the length cannot be determined in advance.
+	GetBody func() (io.Reader, error)
 }
 
+// PutObject creates or overwrites an object, streaming its contents from
+// ObjectReader. If the client was constructed with an EncryptionKeyProvider,
+// the object is encrypted before it leaves the client; in that case any
+// ContentMD5 given is ignored, since it cannot match the encrypted data.
 func (c *Client) PutObject(input *PutObjectInput) error {
 	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.ObjectPath)
 
@@ -148,36 +507,386 @@ func (c *Client) PutObject(input *PutObjectInput) error {
 		return errors.New("ContentLength and MaxContentLength may not both be set to non-zero values.")
 	}
 
+	if input.CreateOnly && input.IfNoneMatch != "" {
+		return errors.New("CreateOnly and IfNoneMatch may not both be set.")
+	}
+
+	if input.DurabilityLevel != 0 && (input.DurabilityLevel < MinDurabilityLevel || input.DurabilityLevel > MaxDurabilityLevel) {
+		return fmt.Errorf("DurabilityLevel must be between %d and %d, got %d",
+			MinDurabilityLevel, MaxDurabilityLevel, input.DurabilityLevel)
+	}
+
+	objectReader := input.ObjectReader
+	if input.GetBody != nil {
+		factoryReader := &bodyFactoryReader{
+			getBody: input.GetBody,
+		}
+		defer factoryReader.Close()
+		objectReader = factoryReader
+	}
+	contentType := input.ContentType
+	if contentType == "" {
+		var err error
+		contentType, objectReader, err = detectContentType(input.ObjectPath, objectReader, input.SniffContentType)
+		if err != nil {
+			return errwrap.Wrapf("Error detecting Content-Type: {{err}}", err)
+		}
+	}
+
+	contentLength := input.ContentLength
+	if contentLength == 0 && input.MaxContentLength == 0 && input.GetBody == nil {
+		if seeker, ok := objectReader.(io.ReadSeeker); ok {
+			remaining, err := remainingLength(seeker)
+			if err != nil {
+				return errwrap.Wrapf("Error determining Content-Length: {{err}}", err)
+			}
+			contentLength = remaining
+		}
+	}
+
 	headers := &http.Header{}
 	if input.DurabilityLevel != 0 {
 		headers.Set("Durability-Level", strconv.FormatUint(input.DurabilityLevel, 10))
 	}
-	if input.ContentType != "" {
-		headers.Set("Content-Type", input.ContentType)
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+	if input.ComputeSHA256 {
+		seeker, ok := objectReader.(io.ReadSeeker)
+		if !ok {
+			return errors.New("ComputeSHA256 requires ObjectReader to be an io.Seeker, or GetBody to be set")
+		}
+		digest, err := computeSHA256(seeker)
+		if err != nil {
+			return errwrap.Wrapf("Error computing SHA-256: {{err}}", err)
+		}
+		headers.Set(contentSHA256Header, digest)
+	}
+	setCacheHeaders(headers, input.CacheControl, input.Expires)
+	setCORSHeaders(headers, input.CORS)
+	setRoleTagHeader(headers, input.RoleTags)
+	setMetadataHeaders(headers, input.Metadata)
+
+	contentMD5 := input.ContentMD5
+	maxContentLength := input.MaxContentLength
+	if input.Compress {
+		var err error
+		objectReader, err = compressObject(objectReader, contentLength, headers)
+		if err != nil {
+			return errwrap.Wrapf("Error compressing object: {{err}}", err)
+		}
+		contentLength = 0
+		contentMD5 = ""
+	}
+	if c.encryptionKeys != nil {
+		var err error
+		objectReader, contentLength, err = c.encryptObject(objectReader, contentLength, headers)
+		if err != nil {
+			return errwrap.Wrapf("Error encrypting object: {{err}}", err)
+		}
+		contentMD5 = ""
+		if maxContentLength != 0 {
+			maxContentLength += encryptionOverhead
+		}
+	}
+
+	var md5Hash hash.Hash
+	if input.ComputeMD5 && contentMD5 == "" {
+		if seeker, ok := objectReader.(io.ReadSeeker); ok {
+			digest, err := computeMD5(seeker)
+			if err != nil {
+				return errwrap.Wrapf("Error computing Content-MD5: {{err}}", err)
+			}
+			contentMD5 = digest
+		} else {
+			md5Hash = md5.New()
+			objectReader = io.TeeReader(objectReader, md5Hash)
+		}
 	}
-	if input.ContentMD5 != "" {
-		headers.Set("Content-MD$", input.ContentMD5)
+	if contentMD5 != "" {
+		headers.Set("Content-MD5", contentMD5)
 	}
-	if input.IfMatch != "" {
-		headers.Set("If-Match", input.IfMatch)
+	setConditionalHeaders(headers, input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if input.CreateOnly {
+		headers.Set("If-None-Match", "*")
 	}
-	if input.IfModifiedSince != nil {
-		headers.Set("If-Modified-Since", input.IfModifiedSince.Format(time.RFC1123))
+	if contentLength != 0 {
+		headers.Set("Content-Length", strconv.FormatUint(contentLength, 10))
 	}
-	if input.ContentLength != 0 {
-		headers.Set("Content-Length", strconv.FormatUint(input.ContentLength, 10))
+	if maxContentLength != 0 {
+		headers.Set("Max-Content-Length", strconv.FormatUint(maxContentLength, 10))
 	}
-	if input.MaxContentLength != 0 {
-		headers.Set("Max-Content-Length", strconv.FormatUint(input.MaxContentLength, 10))
+	for key, value := range input.Headers {
+		headers.Set(key, value)
 	}
 
-	respBody, _, err := c.executeRequestNoEncode(http.MethodPut, path, nil, headers, input.ObjectReader)
+	respBody, respHeaders, err := c.executeRequestReader(http.MethodPut, path, nil, headers, objectReader)
 	if respBody != nil {
 		defer respBody.Close()
 	}
 	if err != nil {
-		return errwrap.Wrapf("Error executing PutObjectMetadata request: {{err}}", err)
+		if input.CreateOnly && IsPreconditionFailedError(err) {
+			return &AlreadyExistsError{
+				ObjectPath: input.ObjectPath,
+			}
+		}
+		return errwrap.Wrapf("Error executing PutObject request: {{err}}", err)
+	}
+
+	if md5Hash != nil {
+		contentMD5 = base64.StdEncoding.EncodeToString(md5Hash.Sum(nil))
+	}
+	computedMD5 := respHeaders.Get("Computed-MD5")
+	if contentMD5 != "" && computedMD5 != "" && contentMD5 != computedMD5 {
+		return &ChecksumMismatchError{
+			ObjectPath: input.ObjectPath,
+			Algorithm:  "MD5",
+			Expected:   contentMD5,
+			Actual:     computedMD5,
+		}
 	}
 
 	return nil
 }
+
+// detectContentType infers a content type for the object at objectPath from
+// its extension, falling back to sniffing the start of reader if sniff is set.
+// Since sniffing consumes data, the reader returned must be used in place of
+// reader. An empty string is returned if no content type could be determined.
+func detectContentType(objectPath string, reader io.Reader, sniff bool) (string, io.Reader, error) {
+	if contentType := mime.TypeByExtension(path.Ext(objectPath)); contentType != "" {
+		return contentType, reader, nil
+	}
+	if !sniff || reader == nil {
+		return "", reader, nil
+	}
+
+	buffer := make([]byte, 512)
+	n, err := io.ReadFull(reader, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	contentType := http.DetectContentType(buffer[:n])
+
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		if _, err := seeker.Seek(int64(-n), io.SeekCurrent); err != nil {
+			return "", nil, err
+		}
+		return contentType, seeker, nil
+	}
+	return contentType, io.MultiReader(bytes.NewReader(buffer[:n]), reader), nil
+}
+
+// remainingLength returns the number of bytes left to read from reader,
+// leaving reader positioned where it started.
+func remainingLength(reader io.Seeker) (uint64, error) {
+	start, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	end, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := reader.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return uint64(end - start), nil
+}
+
+// computeMD5 returns the base64-encoded MD5 digest of the remaining contents
+// of reader, leaving reader positioned where it started.
+func computeMD5(reader io.ReadSeeker) (string, error) {
+	digest, err := computeDigest(reader, md5.New())
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(digest), nil
+}
+
+// computeDigest returns the digest computed by digestHash of the remaining
+// data of reader, leaving its position unchanged.
+func computeDigest(reader io.ReadSeeker, digestHash hash.Hash) ([]byte, error) {
+	start, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(digestHash, reader); err != nil {
+		return nil, err
+	}
+
+	if _, err := reader.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return digestHash.Sum(nil), nil
+}
+
+// setConditionalHeaders sets the HTTP conditional request headers supported
+// by Manta on headers, skipping any which are not specified.
+func setConditionalHeaders(headers *http.Header, ifMatch, ifNoneMatch string, ifModifiedSince, ifUnmodifiedSince *time.Time) {
+	if ifMatch != "" {
+		headers.Set("If-Match", ifMatch)
+	}
+	if ifNoneMatch != "" {
+		headers.Set("If-None-Match", ifNoneMatch)
+	}
+	if ifModifiedSince != nil {
+		headers.Set("If-Modified-Since", ifModifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if ifUnmodifiedSince != nil {
+		headers.Set("If-Unmodified-Since", ifUnmodifiedSince.UTC().Format(http.TimeFormat))
+	}
+}
+
+// metadataHeaderPrefix is the prefix Manta requires on the names of headers
+// which carry user metadata.
+const metadataHeaderPrefix = "m-"
+
+// setMetadataHeaders sets a header on headers for each item of user metadata,
+// adding the "m-" prefix to the key where it is missing.
+func setMetadataHeaders(headers *http.Header, metadata map[string]string) {
+	for key, value := range metadata {
+		if !strings.HasPrefix(strings.ToLower(key), metadataHeaderPrefix) {
+			key = metadataHeaderPrefix + key
+		}
+		headers.Set(key, value)
+	}
+}
+
+// parseMetadataHeaders collects the user metadata headers from headers into a
+// map keyed by lower case name without the "m-" prefix.
+func parseMetadataHeaders(headers http.Header) map[string]string {
+	metadata := map[string]string{}
+	for key, values := range headers {
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, metadataHeaderPrefix) {
+			metadata[strings.TrimPrefix(key, metadataHeaderPrefix)] = strings.Join(values, ", ")
+		}
+	}
+	return metadata
+}
+
+// isEncodedObject returns true if metadata, as returned by HeadObject, shows
+// that an object was compressed or encrypted by PutObject. The stored data of
+// such objects cannot be decoded from an arbitrary offset, so they must be
+// read from the start.
+func isEncodedObject(metadata map[string]string) bool {
+	_, compressed := metadata[strings.TrimPrefix(compressTypeHeader, metadataHeaderPrefix)]
+	_, encrypted := metadata[strings.TrimPrefix(encryptTypeHeader, metadataHeaderPrefix)]
+	return compressed || encrypted
+}
+
+// decodedContentLength returns the length of the data which GetObject returns
+// for an object, given its stored length and its metadata as returned by
+// HeadObject. The second result is false if the length is not known, as for
+// objects compressed without their length being recorded.
+func (c *Client) decodedContentLength(contentLength uint64, metadata map[string]string) (uint64, bool) {
+	_, encrypted := metadata[strings.TrimPrefix(encryptTypeHeader, metadataHeaderPrefix)]
+	if encrypted && c.encryptionKeys == nil {
+		// The object is returned as stored.
+		return contentLength, true
+	}
+
+	if _, compressed := metadata[strings.TrimPrefix(compressTypeHeader, metadataHeaderPrefix)]; compressed {
+		length, err := strconv.ParseUint(metadata[strings.TrimPrefix(compressPlaintextLengthHeader, metadataHeaderPrefix)], 10, 64)
+		return length, err == nil
+	}
+	if encrypted {
+		if contentLength < encryptionOverhead {
+			return 0, false
+		}
+		return contentLength - encryptionOverhead, true
+	}
+	return contentLength, true
+}
+
+// CORSConfiguration holds the cross-origin resource sharing headers stored
+// with an object. Each field corresponds to the access-control-* header of
+// the same name; empty fields are not sent.
+type CORSConfiguration struct {
+	AllowOrigin   string
+	AllowMethods  []string
+	AllowHeaders  []string
+	ExposeHeaders []string
+	MaxAge        uint64
+}
+
+// setCacheHeaders sets the Cache-Control and Expires headers on headers if
+// they are non-empty.
+func setCacheHeaders(headers *http.Header, cacheControl string, expires *time.Time) {
+	if cacheControl != "" {
+		headers.Set("Cache-Control", cacheControl)
+	}
+	if expires != nil {
+		headers.Set("Expires", expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+// setCORSHeaders sets the access-control-* headers described by cors on
+// headers. A nil cors sets no headers.
+func setCORSHeaders(headers *http.Header, cors *CORSConfiguration) {
+	if cors == nil {
+		return
+	}
+	if cors.AllowOrigin != "" {
+		headers.Set("Access-Control-Allow-Origin", cors.AllowOrigin)
+	}
+	if len(cors.AllowMethods) != 0 {
+		headers.Set("Access-Control-Allow-Methods", strings.Join(cors.AllowMethods, ", "))
+	}
+	if len(cors.AllowHeaders) != 0 {
+		headers.Set("Access-Control-Allow-Headers", strings.Join(cors.AllowHeaders, ", "))
+	}
+	if len(cors.ExposeHeaders) != 0 {
+		headers.Set("Access-Control-Expose-Headers", strings.Join(cors.ExposeHeaders, ", "))
+	}
+	if cors.MaxAge != 0 {
+		headers.Set("Access-Control-Max-Age", strconv.FormatUint(cors.MaxAge, 10))
+	}
+}
+
+// setRoleTagHeader sets the Role-Tag header on headers if roleTags is not
+// empty.
+func setRoleTagHeader(headers *http.Header, roleTags []string) {
+	if len(roleTags) != 0 {
+		headers.Set("Role-Tag", strings.Join(roleTags, ", "))
+	}
+}
+
+// parseCORSHeaders reads the access-control-* headers from headers, returning
+// nil if none are present.
+func parseCORSHeaders(headers http.Header) *CORSConfiguration {
+	cors := &CORSConfiguration{
+		AllowOrigin:   headers.Get("Access-Control-Allow-Origin"),
+		AllowMethods:  splitHeaderList(headers.Get("Access-Control-Allow-Methods")),
+		AllowHeaders:  splitHeaderList(headers.Get("Access-Control-Allow-Headers")),
+		ExposeHeaders: splitHeaderList(headers.Get("Access-Control-Expose-Headers")),
+	}
+	maxAge, err := strconv.ParseUint(headers.Get("Access-Control-Max-Age"), 10, 64)
+	if err == nil {
+		cors.MaxAge = maxAge
+	}
+
+	if cors.AllowOrigin == "" && cors.AllowMethods == nil && cors.AllowHeaders == nil &&
+		cors.ExposeHeaders == nil && cors.MaxAge == 0 {
+		return nil
+	}
+	return cors
+}
+
+// splitHeaderList splits a comma separated header value into its elements,
+// returning nil for an empty value.
+func splitHeaderList(value string) []string {
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
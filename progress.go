@@ -0,0 +1,103 @@
+package manta
+
+import (
+	"io"
+	"sync"
+)
+
+// ProgressFunc is called as the data of an object is transferred.
+// transferred is the number of bytes transferred so far across all parts,
+// and total is the size of the object, or zero if it is not known.
+// partNumber identifies the part or range whose transfer made progress.
+//
+// Calls are never made concurrently, but may come from any goroutine. If a
+// part is retried, transferred goes down by the amount sent in the failed
+// attempt.
+type ProgressFunc func(transferred, total, partNumber uint64)
+
+// progressTracker accumulates progress across the parts of a transfer.
+type progressTracker struct {
+	lock        sync.Mutex
+	fn          ProgressFunc
+	total       uint64
+	transferred int64
+}
+
+func newProgressTracker(fn ProgressFunc, total uint64) *progressTracker {
+	return &progressTracker{
+		fn:    fn,
+		total: total,
+	}
+}
+
+// add records n more bytes transferred for partNumber. n may be negative
+// to discard progress from a failed attempt.
+func (t *progressTracker) add(n int64, partNumber uint64) {
+	if t == nil || t.fn == nil || n == 0 {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.transferred += n
+	t.fn(uint64(t.transferred), t.total, partNumber)
+}
+
+// reader wraps r so that reads from it are reported to the tracker. If r is
+// an io.Seeker, so is the result, and seeking adjusts the progress reported
+// for the part, which must start at offset zero of r.
+func (t *progressTracker) reader(r io.Reader, partNumber uint64) io.Reader {
+	if t == nil || t.fn == nil {
+		return r
+	}
+
+	pr := &progressReader{
+		reader:     r,
+		tracker:    t,
+		partNumber: partNumber,
+	}
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		return &progressReadSeeker{
+			progressReader: pr,
+			seeker:         seeker,
+		}
+	}
+	return pr
+}
+
+type progressReader struct {
+	reader     io.Reader
+	tracker    *progressTracker
+	partNumber uint64
+	position   int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.position += int64(n)
+	r.tracker.add(int64(n), r.partNumber)
+	return n, err
+}
+
+// discard withdraws all the progress reported by r.
+func (r *progressReader) discard() {
+	r.tracker.add(-r.position, r.partNumber)
+	r.position = 0
+}
+
+type progressReadSeeker struct {
+	*progressReader
+	seeker io.ReadSeeker
+}
+
+func (r *progressReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	position, err := r.seeker.Seek(offset, whence)
+	if err != nil {
+		return position, err
+	}
+
+	r.tracker.add(position-r.position, r.partNumber)
+	r.position = position
+	return position, nil
+}
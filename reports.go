@@ -0,0 +1,414 @@
+package manta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// Types of the usage reports Manta generates below /:account/reports/usage.
+const (
+	UsageReportTypeStorage = "storage"
+	UsageReportTypeRequest = "request"
+	UsageReportTypeCompute = "compute"
+	UsageReportTypeSummary = "summary"
+)
+
+// reportsPath returns the absolute path of the directory below the
+// account's /reports directory named by elements.
+func (c *Client) reportsPath(elements ...string) string {
+	return path.Join(append([]string{"/", c.accountName, "reports"}, elements...)...)
+}
+
+// listReportsDirectory calls fn for every entry of the directory at the
+// absolute path directoryPath, which is outside the account's /stor
+// directory and so cannot be listed with ListDirectory.
+func (c *Client) listReportsDirectory(directoryPath string, fn func(*DirectoryEntry) error) error {
+	marker := ""
+	for {
+		query := &url.Values{}
+		query.Set("limit", strconv.Itoa(listDirectoryPageSize))
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		respBody, _, err := c.executeRequest(http.MethodGet, directoryPath, query, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		var entries []*DirectoryEntry
+		decoder := json.NewDecoder(respBody)
+		for {
+			current := &DirectoryEntry{}
+			if err = decoder.Decode(current); err != nil {
+				break
+			}
+			entries = append(entries, current)
+		}
+		respBody.Close()
+		if err != io.EOF {
+			return err
+		}
+
+		for _, entry := range entries {
+			if marker != "" && entry.Name == marker {
+				continue
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if len(entries) < listDirectoryPageSize {
+			return nil
+		}
+		marker = entries[len(entries)-1].Name
+	}
+}
+
+// UsageReport describes a usage report listed by ListUsageReports. Path is
+// the full Manta path of the report object, and Time is the start of the
+// period it covers, in UTC, as given by the names of the directories which
+// contain it.
+type UsageReport struct {
+	Type string
+	Path string
+	Time time.Time
+	Size uint64
+}
+
+// ListUsageReportsInput represents parameters to a ListUsageReports
+// operation. Type is one of the UsageReportType constants. If Start or End
+// is set, only reports for periods which begin at or after Start, and
+// before End, are listed.
+type ListUsageReportsInput struct {
+	Type  string
+	Start time.Time
+	End   time.Time
+}
+
+// ListUsageReportsOutput contains the outputs of a ListUsageReports
+// operation, in order of Time.
+type ListUsageReportsOutput struct {
+	Reports []*UsageReport
+}
+
+// ListUsageReports lists the usage reports of one type which Manta has
+// generated for the account. Reports are stored in directories named for the
+// year, month, day and, for hourly reports, hour they cover, and directories
+// whose period lies outside Start and End are not listed.
+func (c *Client) ListUsageReports(input *ListUsageReportsInput) (*ListUsageReportsOutput, error) {
+	switch input.Type {
+	case UsageReportTypeStorage, UsageReportTypeRequest, UsageReportTypeCompute, UsageReportTypeSummary:
+	default:
+		return nil, fmt.Errorf("Unknown usage report type %q", input.Type)
+	}
+
+	output := &ListUsageReportsOutput{}
+	err := c.listDatedReports(c.reportsPath("usage", input.Type), input.Start, input.End, func(reportPath string, start time.Time, entry *DirectoryEntry) {
+		output.Reports = append(output.Reports, &UsageReport{
+			Type: input.Type,
+			Path: reportPath,
+			Time: start,
+			Size: entry.Size,
+		})
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing ListUsageReports request: {{err}}", err)
+	}
+
+	return output, nil
+}
+
+// listDatedReports calls fn, in order of time, for each report below the
+// directory at directoryPath, in which reports are stored in directories
+// named for the year, month, day and, optionally, hour they cover. If start
+// or end is set, only reports for periods which begin at or after start,
+// and before end, are included, and directories outside that range are not
+// listed. A directory which does not exist has no reports.
+func (c *Client) listDatedReports(directoryPath string, start, end time.Time, fn func(reportPath string, start time.Time, entry *DirectoryEntry)) error {
+	type report struct {
+		path  string
+		start time.Time
+		entry *DirectoryEntry
+	}
+	var reports []*report
+
+	var list func(directoryPath string, fields []int) error
+	list = func(directoryPath string, fields []int) error {
+		return c.listReportsDirectory(directoryPath, func(entry *DirectoryEntry) error {
+			entryPath := path.Join(directoryPath, entry.Name)
+
+			if entry.Type == EntryTypeDirectory {
+				value, err := strconv.Atoi(entry.Name)
+				if err != nil || len(fields) == 4 {
+					return nil
+				}
+				childFields := append(append([]int{}, fields...), value)
+				periodStart, periodEnd := reportPeriod(childFields)
+				if (!start.IsZero() && !periodEnd.After(start)) || (!end.IsZero() && !periodStart.Before(end)) {
+					return nil
+				}
+				return list(entryPath, childFields)
+			}
+
+			if len(fields) == 0 {
+				return nil
+			}
+			periodStart, _ := reportPeriod(fields)
+			if (!start.IsZero() && periodStart.Before(start)) || (!end.IsZero() && !periodStart.Before(end)) {
+				return nil
+			}
+			reports = append(reports, &report{
+				path:  entryPath,
+				start: periodStart,
+				entry: entry,
+			})
+			return nil
+		})
+	}
+
+	if err := list(directoryPath, nil); err != nil {
+		if IsResourceNotFoundError(err) || IsDirectoryDoesNotExistError(err) {
+			return nil
+		}
+		return err
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].start.Before(reports[j].start)
+	})
+	for _, r := range reports {
+		fn(r.path, r.start, r.entry)
+	}
+	return nil
+}
+
+// reportPeriod returns the period covered by a directory of reports,
+// given the year, month, day and hour named by it and its parents, of which
+// only a prefix need be present.
+func reportPeriod(fields []int) (time.Time, time.Time) {
+	values := []int{0, 1, 1, 0}
+	copy(values, fields)
+	start := time.Date(values[0], time.Month(values[1]), values[2], values[3], 0, 0, 0, time.UTC)
+
+	switch len(fields) {
+	case 1:
+		return start, start.AddDate(1, 0, 0)
+	case 2:
+		return start, start.AddDate(0, 1, 0)
+	case 3:
+		return start, start.AddDate(0, 0, 1)
+	default:
+		return start, start.Add(time.Hour)
+	}
+}
+
+// usageCount is a count in a usage record, which Manta writes as either a
+// number or a string of digits.
+type usageCount uint64
+
+func (u *usageCount) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*u = 0
+		return nil
+	}
+	value, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return err
+	}
+	*u = usageCount(value)
+	return nil
+}
+
+// StorageUsage is the usage of one of the top level directories of an
+// account, such as "stor" or "public", in a storage usage record.
+type StorageUsage struct {
+	Directories uint64
+	Keys        uint64
+	Objects     uint64
+	Bytes       uint64
+}
+
+// RequestUsage is the usage in a request usage record. Requests holds the
+// number of requests made with each HTTP method, and the bandwidth fields
+// are in bytes.
+type RequestUsage struct {
+	Requests     map[string]uint64
+	BandwidthIn  uint64
+	BandwidthOut uint64
+	HeaderIn     uint64
+	HeaderOut    uint64
+}
+
+// UsageRecord is a record of a usage report. Owner is the UUID of the
+// account. Storage is set for the records of storage reports, keyed by the
+// name of the top level directory, and Request for those of request
+// reports. Raw holds the record as it was read, for the fields of compute
+// and summary reports, and any others not decoded.
+type UsageRecord struct {
+	Owner   string
+	Storage map[string]*StorageUsage
+	Request *RequestUsage
+	Raw     json.RawMessage
+}
+
+// GetUsageReportInput represents parameters to a GetUsageReport operation.
+// Path is the full Manta path of the report, as listed by
+// ListUsageReports.
+type GetUsageReportInput struct {
+	Path string
+}
+
+// GetUsageReportOutput contains the outputs of a GetUsageReport operation.
+type GetUsageReportOutput struct {
+	Records []*UsageRecord
+}
+
+// GetUsageReport retrieves a usage report and decodes its records, which
+// may be stored either as a stream of JSON objects or as a JSON array.
+func (c *Client) GetUsageReport(input *GetUsageReportInput) (*GetUsageReportOutput, error) {
+	respBody, _, err := c.executeRequest(http.MethodGet, c.absoluteObjectPath(input.Path), nil, nil, nil)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error executing GetUsageReport request: {{err}}", err)
+	}
+
+	records, err := decodeUsageRecords(respBody)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error decoding GetUsageReport response: {{err}}", err)
+	}
+
+	return &GetUsageReportOutput{
+		Records: records,
+	}, nil
+}
+
+// decodeUsageRecords decodes the records of a usage report from reader.
+func decodeUsageRecords(reader io.Reader) ([]*UsageRecord, error) {
+	buffered := bufio.NewReader(reader)
+	var raws []json.RawMessage
+
+	for {
+		b, err := buffered.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		buffered.UnreadByte()
+		decoder := json.NewDecoder(buffered)
+		if b == '[' {
+			if err := decoder.Decode(&raws); err != nil {
+				return nil, err
+			}
+			break
+		}
+		for {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
+			}
+			raws = append(raws, raw)
+		}
+		break
+	}
+
+	records := make([]*UsageRecord, 0, len(raws))
+	for _, raw := range raws {
+		record, err := decodeUsageRecord(raw)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// decodeUsageRecord decodes a single record of a usage report.
+func decodeUsageRecord(raw json.RawMessage) (*UsageRecord, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	record := &UsageRecord{
+		Raw: raw,
+	}
+	if owner, ok := fields["owner"]; ok {
+		json.Unmarshal(owner, &record.Owner)
+	}
+
+	if requests, ok := fields["requests"]; ok {
+		var usage struct {
+			Type      map[string]usageCount `json:"type"`
+			Bandwidth struct {
+				In        usageCount `json:"in"`
+				Out       usageCount `json:"out"`
+				HeaderIn  usageCount `json:"headerIn"`
+				HeaderOut usageCount `json:"headerOut"`
+			} `json:"bandwidth"`
+		}
+		if err := json.Unmarshal(requests, &usage); err != nil {
+			return nil, err
+		}
+		record.Request = &RequestUsage{
+			Requests:     map[string]uint64{},
+			BandwidthIn:  uint64(usage.Bandwidth.In),
+			BandwidthOut: uint64(usage.Bandwidth.Out),
+			HeaderIn:     uint64(usage.Bandwidth.HeaderIn),
+			HeaderOut:    uint64(usage.Bandwidth.HeaderOut),
+		}
+		for method, count := range usage.Type {
+			record.Request.Requests[method] = uint64(count)
+		}
+	}
+
+	for name, value := range fields {
+		if name == "requests" || !bytes.Contains(value, []byte(`"bytes"`)) {
+			continue
+		}
+		var usage struct {
+			Directories usageCount  `json:"directories"`
+			Keys        usageCount  `json:"keys"`
+			Objects     usageCount  `json:"objects"`
+			Bytes       *usageCount `json:"bytes"`
+		}
+		if err := json.Unmarshal(value, &usage); err != nil || usage.Bytes == nil {
+			continue
+		}
+		if record.Storage == nil {
+			record.Storage = map[string]*StorageUsage{}
+		}
+		record.Storage[name] = &StorageUsage{
+			Directories: uint64(usage.Directories),
+			Keys:        uint64(usage.Keys),
+			Objects:     uint64(usage.Objects),
+			Bytes:       uint64(*usage.Bytes),
+		}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,95 @@
+package manta
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// getObjectResumeAttempts is the number of times in a row a GetObject stream
+// is resumed without receiving any data before the error is returned.
+const getObjectResumeAttempts = 3
+
+// resumingReader reads the body of a GetObject response, and if the stream
+// breaks before the expected number of bytes have been received, continues
+// it with a ranged request for the remainder. Each ranged request is
+// conditional on the ETag of the original response, so that data from
+// different versions of an object is never mixed.
+type resumingReader struct {
+	client    *Client
+	path      string
+	etag      string
+	body      io.ReadCloser
+	offset    uint64
+	remaining uint64
+	failures  int
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	if uint64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	for {
+		n, err := r.body.Read(p)
+		r.offset += uint64(n)
+		r.remaining -= uint64(n)
+		if n > 0 {
+			r.failures = 0
+		}
+
+		if err == nil || r.remaining == 0 {
+			if r.remaining == 0 {
+				err = nil
+			}
+			return n, err
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if n > 0 {
+			// Return the data received, and resume on the next call,
+			// where the body will fail again.
+			return n, nil
+		}
+
+		r.failures++
+		if r.failures > getObjectResumeAttempts {
+			return 0, err
+		}
+		if resumeErr := r.resume(); resumeErr != nil {
+			return 0, errwrap.Wrapf(fmt.Sprintf("Error resuming GetObject after %q: {{err}}", err), resumeErr)
+		}
+	}
+}
+
+// resume replaces the body with a ranged response for the remaining data.
+func (r *resumingReader) resume() error {
+	r.body.Close()
+	r.body = http.NoBody
+
+	headers := &http.Header{}
+	headers.Set("If-Match", r.etag)
+	headers.Set("Range", fmt.Sprintf("bytes=%d-%d", r.offset, r.offset+r.remaining-1))
+	headers.Set("Accept-Encoding", "identity")
+
+	respBody, _, err := r.client.executeRequest(http.MethodGet, r.path, nil, headers, nil)
+	if err != nil {
+		if respBody != nil {
+			respBody.Close()
+		}
+		return err
+	}
+
+	r.body = respBody
+	return nil
+}
+
+func (r *resumingReader) Close() error {
+	return r.body.Close()
+}
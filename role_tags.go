@@ -0,0 +1,244 @@
+package manta
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultSetRoleTagsConcurrency = 10
+
+// SetRoleTagsProgress describes the progress of a SetRoleTags operation
+// after an entry has been updated, skipped or has failed. Path is relative
+// to the account's /stor directory, and Error is set if it failed. The
+// total number of entries is not known until the walk is complete.
+type SetRoleTagsProgress struct {
+	Path  string
+	Error error
+
+	CompletedEntries uint64
+}
+
+// SetRoleTagsProgressFunc is called as each entry of a SetRoleTags operation
+// is dealt with. Calls are never made concurrently, but may come from any
+// goroutine.
+type SetRoleTagsProgressFunc func(progress *SetRoleTagsProgress)
+
+// SetRoleTagsInput represents parameters to a SetRoleTags operation.
+// RoleTags replace the role tags of Root and of every object and directory
+// below it, and must not be empty. Concurrency is the number of entries
+// updated at once; if it is zero, DefaultSetRoleTagsConcurrency is used.
+type SetRoleTagsInput struct {
+	Root        string
+	RoleTags    []string
+	Concurrency int
+	Progress    SetRoleTagsProgressFunc
+}
+
+// SetRoleTagsOutput contains the outputs of a SetRoleTags operation.
+// Updated and Skipped hold the paths of the entries whose role tags were
+// changed and of those which already had them, and Failed those which could
+// not be updated or, for directories, listed.
+type SetRoleTagsOutput struct {
+	Updated []string
+	Skipped []string
+	Failed  []*SetRoleTagsFailure
+}
+
+// SetRoleTagsFailure records a path whose role tags could not be set.
+type SetRoleTagsFailure struct {
+	Path  string
+	Error error
+}
+
+// SetRoleTags applies a set of role tags to a tree, like `mchmod -R`, using
+// a pool of concurrent workers which update entries as the tree is walked.
+// The other metadata of each object is read first and preserved, and
+// objects which already have exactly the given role tags are skipped.
+// Directories are always updated, since their role tags are not listed.
+//
+// A failure for one entry does not prevent the others from being updated.
+// Failures are recorded in the output, and an error summarising them is
+// returned if any path failed.
+func (c *Client) SetRoleTags(input *SetRoleTagsInput) (*SetRoleTagsOutput, error) {
+	if len(input.RoleTags) == 0 {
+		return nil, errors.New("RoleTags must be set for SetRoleTags")
+	}
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSetRoleTagsConcurrency
+	}
+
+	recorder := &roleTagsRecorder{
+		fn: input.Progress,
+	}
+
+	type roleTagsWork struct {
+		entryPath string
+		entry     *DirectoryEntry
+	}
+	work := make(chan *roleTagsWork)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				updated, err := c.setEntryRoleTags(item.entryPath, item.entry, input.RoleTags)
+				recorder.complete(item.entryPath, updated, err)
+			}
+		}()
+	}
+
+	c.Walk(input.Root, func(entryPath string, entry *DirectoryEntry, err error) error {
+		if err != nil {
+			recorder.complete(entryPath, false, err)
+			return nil
+		}
+		// The account's /stor directory itself has no role tags of its
+		// own to set.
+		if entryPath == "" {
+			return nil
+		}
+		work <- &roleTagsWork{
+			entryPath: entryPath,
+			entry:     entry,
+		}
+		return nil
+	})
+	close(work)
+	wg.Wait()
+
+	return recorder.result()
+}
+
+// setEntryRoleTags sets the role tags of the object or directory at
+// entryPath, returning false if the entry already had them. The metadata of
+// an object is written on the condition that the object has not been
+// replaced since it was read, and the update is retried if it has.
+func (c *Client) setEntryRoleTags(entryPath string, entry *DirectoryEntry, roleTags []string) (bool, error) {
+	if entry.Type == EntryTypeDirectory {
+		err := c.PutDirectory(&PutDirectoryInput{
+			DirectoryName: entryPath,
+			RoleTags:      roleTags,
+		})
+		return err == nil, err
+	}
+
+	var updated bool
+	var err error
+	for attempt := 0; attempt <= DefaultUpdateObjectRetries; attempt++ {
+		updated, err = c.setObjectRoleTags(entryPath, roleTags)
+		if err == nil || !IsPreconditionFailedError(err) {
+			break
+		}
+	}
+	return updated, err
+}
+
+// setObjectRoleTags makes a single attempt at setting the role tags of the
+// object at objectPath.
+func (c *Client) setObjectRoleTags(objectPath string, roleTags []string) (bool, error) {
+	head, err := c.HeadObject(&HeadObjectInput{
+		ObjectPath: objectPath,
+	})
+	if err != nil {
+		return false, err
+	}
+	if roleTagsEqual(head.RoleTags, roleTags) {
+		return false, nil
+	}
+
+	metadataInput := &PutObjectMetadataInput{
+		ObjectPath:   objectPath,
+		ContentType:  head.ContentType,
+		CacheControl: head.CacheControl,
+		CORS:         head.CORS,
+		RoleTags:     roleTags,
+		Metadata:     head.Metadata,
+		IfMatch:      head.ETag,
+	}
+	if !head.Expires.IsZero() {
+		metadataInput.Expires = &head.Expires
+	}
+	if err := c.PutObjectMetadata(metadataInput); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// roleTagsEqual returns whether two lists hold the same role tags, in any
+// order.
+func roleTagsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := map[string]int{}
+	for _, tag := range a {
+		counts[tag]++
+	}
+	for _, tag := range b {
+		if counts[tag] == 0 {
+			return false
+		}
+		counts[tag]--
+	}
+	return true
+}
+
+// roleTagsRecorder accumulates the output of a SetRoleTags operation and
+// reports its progress.
+type roleTagsRecorder struct {
+	lock      sync.Mutex
+	fn        SetRoleTagsProgressFunc
+	completed uint64
+	output    SetRoleTagsOutput
+}
+
+// complete records the outcome for the entry at entryPath.
+func (r *roleTagsRecorder) complete(entryPath string, updated bool, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch {
+	case err != nil:
+		r.output.Failed = append(r.output.Failed, &SetRoleTagsFailure{
+			Path:  entryPath,
+			Error: err,
+		})
+	case updated:
+		r.output.Updated = append(r.output.Updated, entryPath)
+	default:
+		r.output.Skipped = append(r.output.Skipped, entryPath)
+	}
+
+	r.completed++
+	if r.fn != nil {
+		r.fn(&SetRoleTagsProgress{
+			Path:             entryPath,
+			Error:            err,
+			CompletedEntries: r.completed,
+		})
+	}
+}
+
+// result sorts the lists of the output and returns it, along with an error
+// if any path failed.
+func (r *roleTagsRecorder) result() (*SetRoleTagsOutput, error) {
+	output := &r.output
+	sort.Strings(output.Updated)
+	sort.Strings(output.Skipped)
+	sort.Slice(output.Failed, func(i, j int) bool {
+		return output.Failed[i].Path < output.Failed[j].Path
+	})
+
+	if len(output.Failed) != 0 {
+		message := fmt.Sprintf("Error executing SetRoleTags request: failed for %d paths, including %s: {{err}}",
+			len(output.Failed), output.Failed[0].Path)
+		return output, errwrap.Wrapf(message, output.Failed[0].Error)
+	}
+	return output, nil
+}
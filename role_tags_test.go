@@ -0,0 +1,36 @@
+package manta
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSetRoleTagsFailure(t *testing.T) {
+	s, client := newTestServer(t)
+	s.put("tree/object", "a", nil)
+	s.put("tree/locked/object", "b", nil)
+	s.handle("stor/tree/locked", func(w http.ResponseWriter, r *http.Request) {
+		writeTestError(w, http.StatusForbidden, "AuthorizationError")
+	})
+
+	output, err := client.SetRoleTags(&SetRoleTagsInput{
+		Root:     "tree",
+		RoleTags: []string{"readers"},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !IsAuthorizationError(err) {
+		t.Errorf("expected an authorization error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "tree/locked") {
+		t.Errorf("expected the error to name the failed path, got %v", err)
+	}
+	if strings.Join(output.Updated, ",") != "tree,tree/object" {
+		t.Errorf("expected tree and tree/object updated, got %v", output.Updated)
+	}
+	if len(output.Failed) == 0 || output.Failed[0].Path != "tree/locked" {
+		t.Errorf("expected tree/locked to fail, got %v", output.Failed)
+	}
+}
@@ -0,0 +1,401 @@
+package manta
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jen20/manta-go/authentication"
+)
+
+// testAccount is the account name used by clients of a testServer.
+const testAccount = "test"
+
+// testSigner signs requests without a key, since the testServer does not
+// check signatures.
+type testSigner struct{}
+
+func (testSigner) Sign(string) (string, error)            { return "Signature test", nil }
+func (testSigner) SignRaw(string) (string, string, error) { return "test", "rsa-sha256", nil }
+func (testSigner) KeyFingerprint() string                 { return "test" }
+func (testSigner) DefaultAlgorithm() string               { return "rsa-sha256" }
+
+// testObject is an object or directory stored by a testServer.
+type testObject struct {
+	data      []byte
+	directory bool
+	etag      string
+	modified  time.Time
+	headers   http.Header
+}
+
+// testServer is an in-memory implementation of the parts of the Manta
+// storage API used by the client, for use in tests. Objects are keyed by
+// their path relative to the account's /stor directory. Requests for paths
+// with a prefix registered in routes are passed to that handler instead.
+type testServer struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	objects  map[string]*testObject
+	routes   map[string]http.HandlerFunc
+	requests []string
+	nextETag int
+}
+
+// newTestServer starts a testServer, returning it along with a client
+// which makes requests to it. The server is closed when the test ends.
+func newTestServer(t *testing.T) (*testServer, *Client) {
+	s := &testServer{
+		objects: map[string]*testObject{
+			"": {directory: true, modified: time.Now()},
+		},
+		routes: map[string]http.HandlerFunc{},
+	}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+
+	client, err := NewClient(&ClientOptions{
+		Endpoint:    s.URL,
+		AccountName: testAccount,
+		Signers:     []authentication.Signer{testSigner{}},
+		Logger:      log.New(ioutil.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.client.RetryWaitMin = time.Millisecond
+	client.client.RetryWaitMax = time.Millisecond
+	client.client.RetryMax = 1
+
+	return s, client
+}
+
+// handle registers handler for requests for paths beginning with prefix,
+// which is relative to the account, such as "jobs".
+func (s *testServer) handle(prefix string, handler http.HandlerFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.routes["/"+testAccount+"/"+prefix] = handler
+}
+
+// put stores an object, creating its parent directories, and returns its
+// ETag.
+func (s *testServer) put(objectPath string, data string, headers http.Header) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for dir := path.Dir(objectPath); dir != "."; dir = path.Dir(dir) {
+		if _, ok := s.objects[dir]; !ok {
+			s.objects[dir] = &testObject{directory: true, modified: time.Now()}
+		}
+	}
+	if headers == nil {
+		headers = http.Header{}
+	}
+	s.nextETag++
+	s.objects[objectPath] = &testObject{
+		data:     []byte(data),
+		etag:     fmt.Sprintf("etag-%d", s.nextETag),
+		modified: time.Now(),
+		headers:  headers,
+	}
+	return s.objects[objectPath].etag
+}
+
+// object returns the object stored at objectPath, or nil.
+func (s *testServer) object(objectPath string) *testObject {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.objects[objectPath]
+}
+
+// requestLog returns the requests made so far, as "METHOD path?query".
+func (s *testServer) requestLog() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// countRequests returns how many requests made so far began with prefix.
+func (s *testServer) countRequests(prefix string) int {
+	count := 0
+	for _, request := range s.requestLog() {
+		if strings.HasPrefix(request, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	request := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/"+testAccount+"/")
+	if r.URL.RawQuery != "" {
+		request += "?" + r.URL.RawQuery
+	}
+	s.requests = append(s.requests, request)
+	for prefix, handler := range s.routes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			s.lock.Unlock()
+			handler(w, r)
+			return
+		}
+	}
+	defer s.lock.Unlock()
+
+	storPrefix := "/" + testAccount + "/stor"
+	if r.URL.Path != storPrefix && !strings.HasPrefix(r.URL.Path, storPrefix+"/") {
+		writeTestError(w, http.StatusNotFound, "ResourceNotFound")
+		return
+	}
+	objectPath := strings.Trim(strings.TrimPrefix(r.URL.Path, storPrefix), "/")
+	object := s.objects[objectPath]
+
+	switch r.Method {
+	case http.MethodPut:
+		s.servePut(w, r, objectPath, object)
+	case http.MethodGet, http.MethodHead:
+		s.serveGet(w, r, objectPath, object)
+	case http.MethodDelete:
+		if object == nil {
+			writeTestError(w, http.StatusNotFound, "ResourceNotFound")
+			return
+		}
+		if object.directory {
+			for key := range s.objects {
+				if strings.HasPrefix(key, objectPath+"/") {
+					writeTestError(w, http.StatusBadRequest, "DirectoryNotEmpty")
+					return
+				}
+			}
+		}
+		delete(s.objects, objectPath)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *testServer) servePut(w http.ResponseWriter, r *http.Request, objectPath string, object *testObject) {
+	parent := path.Dir(objectPath)
+	if parent == "." {
+		parent = ""
+	}
+	if p := s.objects[parent]; p == nil || !p.directory {
+		writeTestError(w, http.StatusNotFound, "DirectoryDoesNotExist")
+		return
+	}
+	if r.Header.Get("If-None-Match") == "*" && object != nil {
+		writeTestError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && (object == nil || object.etag != match) {
+		writeTestError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+
+	switch {
+	case r.URL.Query().Get("metadata") == "true":
+		if object == nil {
+			writeTestError(w, http.StatusNotFound, "ResourceNotFound")
+			return
+		}
+		object.headers = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	case strings.Contains(r.Header.Get("Content-Type"), "type=directory"):
+		if object != nil && !object.directory {
+			writeTestError(w, http.StatusBadRequest, "ParentNotDirectory")
+			return
+		}
+		if object == nil {
+			object = &testObject{directory: true, modified: time.Now()}
+			s.objects[objectPath] = object
+		}
+		object.headers = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	case r.Header.Get("Location") != "":
+		source := strings.Trim(strings.TrimPrefix(r.Header.Get("Location"), "/"+testAccount+"/stor"), "/")
+		if s.objects[source] == nil {
+			writeTestError(w, http.StatusNotFound, "SourceObjectNotFound")
+			return
+		}
+		copied := *s.objects[source]
+		s.objects[objectPath] = &copied
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.nextETag++
+		s.objects[objectPath] = &testObject{
+			data:     data,
+			etag:     fmt.Sprintf("etag-%d", s.nextETag),
+			modified: time.Now(),
+			headers:  r.Header.Clone(),
+		}
+		sum := md5.Sum(data)
+		w.Header().Set("Etag", s.objects[objectPath].etag)
+		w.Header().Set("Computed-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *testServer) serveGet(w http.ResponseWriter, r *http.Request, objectPath string, object *testObject) {
+	if object == nil {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeTestError(w, http.StatusNotFound, "ResourceNotFound")
+		return
+	}
+
+	if object.directory {
+		s.serveDirectory(w, r, objectPath)
+		return
+	}
+
+	if match := r.Header.Get("If-Match"); match != "" && object.etag != match {
+		writeTestError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+	for key, values := range object.headers {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "m-") || lower == "content-type" || lower == "content-encoding" || lower == "cache-control" || lower == "role-tag" {
+			w.Header()[key] = values
+		}
+	}
+	w.Header().Set("Etag", object.etag)
+	w.Header().Set("Last-Modified", object.modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Durability-Level", "2")
+	sum := md5.Sum(object.data)
+	w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	if match := r.Header.Get("If-None-Match"); match != "" && object.etag == match {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data := object.data
+	status := http.StatusOK
+	if byteRange := r.Header.Get("Range"); byteRange != "" {
+		bounds := strings.SplitN(strings.TrimPrefix(byteRange, "bytes="), "-", 2)
+		start, _ := strconv.Atoi(bounds[0])
+		end := len(data) - 1
+		if bounds[1] != "" {
+			end, _ = strconv.Atoi(bounds[1])
+		}
+		if start >= len(data) {
+			writeTestError(w, http.StatusRequestedRangeNotSatisfiable, "RequestedRangeNotSatisfiable")
+			return
+		}
+		if end >= len(data) {
+			end = len(data) - 1
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+func (s *testServer) serveDirectory(w http.ResponseWriter, r *http.Request, directoryPath string) {
+	var names []string
+	for key := range s.objects {
+		if key == "" {
+			continue
+		}
+		parent := path.Dir(key)
+		if parent == "." {
+			parent = ""
+		}
+		if parent == directoryPath {
+			names = append(names, path.Base(key))
+		}
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "application/x-json-stream; type=directory")
+	w.Header().Set("Result-Set-Size", strconv.Itoa(len(names)))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	writeTestDirectoryPage(w, r, names, func(name string) *DirectoryEntry {
+		object := s.objects[path.Join(directoryPath, name)]
+		entry := &DirectoryEntry{
+			Name:         name,
+			ModifiedTime: object.modified,
+			Type:         EntryTypeDirectory,
+		}
+		if !object.directory {
+			entry.Type = EntryTypeObject
+			entry.Size = uint64(len(object.data))
+			entry.ETag = object.etag
+		}
+		return entry
+	})
+}
+
+// writeTestDirectoryPage writes the page of a directory listing selected by
+// the limit and marker parameters of r, from the sorted names. As in Manta,
+// the page starts with the marker itself.
+func writeTestDirectoryPage(w http.ResponseWriter, r *http.Request, names []string, entry func(string) *DirectoryEntry) {
+	marker := r.URL.Query().Get("marker")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit == 0 {
+		limit = 256
+	}
+
+	encoder := json.NewEncoder(w)
+	count := 0
+	for _, name := range names {
+		if marker != "" && name < marker {
+			continue
+		}
+		if count == limit {
+			break
+		}
+		count++
+		encoder.Encode(entry(name))
+	}
+}
+
+// writeTestError writes a Manta error response.
+func writeTestError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":    code,
+		"message": code,
+	})
+}
+
+// readTestBody reads the body of a response or request for a test.
+func readTestBody(t *testing.T, reader io.Reader) string {
+	t.Helper()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -2,22 +2,30 @@ package manta
 
 import (
 	"fmt"
-	"github.com/hashicorp/errwrap"
 	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
 )
 
 // PutSnapLinkInput represents parameters to a PutSnapLink operation.
+// LinkPath is relative to the account's /stor directory. SourcePath is
+// too, unless it begins with a "/", in which case it is taken to be a
+// full Manta path such as /:login/stor/path/to/object.
 type PutSnapLinkInput struct {
 	LinkPath   string
 	SourcePath string
 }
 
-// PutSnapLink creates a SnapLink to an object.
+// PutSnapLink creates a SnapLink to an object. A SnapLink is a new name
+// for the same underlying object data, so it is created instantly and
+// uses no additional storage. Changes to either object after the link
+// is created are not reflected in the other.
 func (c *Client) PutSnapLink(input *PutSnapLinkInput) error {
 	path := fmt.Sprintf("/%s/stor/%s", c.accountName, input.LinkPath)
 	headers := &http.Header{}
 	headers.Set("Content-Type", "application/json; type=link")
-	headers.Set("Location", input.SourcePath)
+	headers.Set("Location", c.absoluteObjectPath(input.SourcePath))
 
 	respBody, _, err := c.executeRequest(http.MethodPut, path, nil, headers, nil)
 	if respBody != nil {
@@ -29,3 +37,13 @@ func (c *Client) PutSnapLink(input *PutSnapLinkInput) error {
 
 	return nil
 }
+
+// absoluteObjectPath returns objectPath unchanged if it is already a full
+// Manta path, or the path of objectPath within the account's /stor
+// directory otherwise.
+func (c *Client) absoluteObjectPath(objectPath string) string {
+	if strings.HasPrefix(objectPath, "/") {
+		return objectPath
+	}
+	return fmt.Sprintf("/%s/stor/%s", c.accountName, objectPath)
+}
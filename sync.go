@@ -0,0 +1,610 @@
+package manta
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultSyncConcurrency = 5
+
+// SyncFailure records a path which could not be synchronised.
+type SyncFailure struct {
+	Path  string
+	Error error
+}
+
+// SyncOutput contains the outputs of a SyncUp or SyncDown operation. Each
+// list holds paths relative to the root of the synchronised trees, with
+// slash separators.
+type SyncOutput struct {
+	Transferred []string
+	Skipped     []string
+	Deleted     []string
+	Failed      []*SyncFailure
+}
+
+// syncFilter applies the Include and Exclude patterns of a sync. A pattern
+// matches a path if it matches either the whole relative path or its final
+// element, using the syntax of path.Match.
+type syncFilter struct {
+	include []string
+	exclude []string
+}
+
+func newSyncFilter(include, exclude []string) (*syncFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("Invalid pattern %q: {{err}}", pattern), err)
+		}
+	}
+	return &syncFilter{
+		include: include,
+		exclude: exclude,
+	}, nil
+}
+
+func syncPatternMatches(patterns []string, relativePath string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, relativePath); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(relativePath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether a file or directory is excluded.
+func (f *syncFilter) excluded(relativePath string) bool {
+	return syncPatternMatches(f.exclude, relativePath)
+}
+
+// included reports whether a file is to be synchronised.
+func (f *syncFilter) included(relativePath string) bool {
+	if f.excluded(relativePath) {
+		return false
+	}
+	return len(f.include) == 0 || syncPatternMatches(f.include, relativePath)
+}
+
+// syncRecorder collects the results of a sync from concurrent workers.
+type syncRecorder struct {
+	lock   sync.Mutex
+	output SyncOutput
+}
+
+func (r *syncRecorder) record(list *[]string, relativePath string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	*list = append(*list, relativePath)
+}
+
+func (r *syncRecorder) fail(relativePath string, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.output.Failed = append(r.output.Failed, &SyncFailure{
+		Path:  relativePath,
+		Error: err,
+	})
+}
+
+// result sorts the lists of the output and returns it, along with an error
+// if any path failed.
+func (r *syncRecorder) result(operation string) (*SyncOutput, error) {
+	output := &r.output
+	sort.Strings(output.Transferred)
+	sort.Strings(output.Skipped)
+	sort.Strings(output.Deleted)
+	sort.Slice(output.Failed, func(i, j int) bool {
+		return output.Failed[i].Path < output.Failed[j].Path
+	})
+
+	if len(output.Failed) != 0 {
+		message := fmt.Sprintf("%s failed for %d paths, including %s: {{err}}",
+			operation, len(output.Failed), output.Failed[0].Path)
+		return output, errwrap.Wrapf(message, output.Failed[0].Error)
+	}
+	return output, nil
+}
+
+// remoteTree lists the objects and directories below prefix, keyed by path
+// relative to prefix. A prefix which does not exist is treated as empty.
+func (c *Client) remoteTree(prefix string, filter *syncFilter) (map[string]*DirectoryEntry, map[string]bool, error) {
+	objects := map[string]*DirectoryEntry{}
+	directories := map[string]bool{}
+
+	err := c.Walk(prefix, func(entryPath string, entry *DirectoryEntry, err error) error {
+		if err != nil {
+			if entryPath == prefix && (IsResourceNotFoundError(err) || hasStatusCode(err, http.StatusNotFound)) {
+				return nil
+			}
+			return err
+		}
+		if entryPath == prefix {
+			return nil
+		}
+
+		relativePath := strings.TrimPrefix(entryPath, prefix+"/")
+		if prefix == "" {
+			relativePath = entryPath
+		}
+		if entry.Type == EntryTypeDirectory {
+			if filter.excluded(relativePath) {
+				return SkipDir
+			}
+			directories[relativePath] = true
+		} else if filter.included(relativePath) {
+			objects[relativePath] = entry
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return objects, directories, nil
+}
+
+// SyncUpInput represents parameters to a SyncUp operation.
+type SyncUpInput struct {
+	// LocalPath is the local directory to upload.
+	LocalPath string
+
+	// Prefix is the directory to upload into, relative to the account's
+	// /stor directory. It is created if it does not exist.
+	Prefix string
+
+	// Include and Exclude are glob patterns, in the syntax of path.Match,
+	// matched against the slash separated path of each file relative to
+	// LocalPath, and against its name. If Include is not empty, only
+	// matching files are uploaded. Files and directories matching Exclude
+	// are ignored.
+	Include []string
+	Exclude []string
+
+	// CompareMD5 compares the MD5 digest of files of the same size as the
+	// remote object whose recorded modification time differs, so that
+	// files which have been touched but not changed are not uploaded.
+	CompareMD5 bool
+
+	// Delete removes objects and directories below Prefix which do not
+	// exist locally, other than those excluded by the patterns.
+	Delete bool
+
+	// DryRun reports what would be done without changing anything.
+	DryRun bool
+
+	// Concurrency is the number of files uploaded at once. If it is zero,
+	// DefaultSyncConcurrency is used.
+	Concurrency int
+}
+
+// SyncUp makes the tree below Prefix match the local directory at
+// LocalPath, like `rsync`. A file is skipped if an object of the same size
+// exists whose modification time, as recorded by PutFile, matches that of
+// the file; otherwise it is uploaded with PutFile. Failures are recorded in
+// the output, and an error is returned if any path failed.
+func (c *Client) SyncUp(input *SyncUpInput) (*SyncOutput, error) {
+	filter, err := newSyncFilter(input.Include, input.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSyncConcurrency
+	}
+	prefix := strings.Trim(path.Clean("/"+input.Prefix), "/")
+
+	localFiles := map[string]os.FileInfo{}
+	localDirectories := map[string]bool{}
+	err = filepath.Walk(input.LocalPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(input.LocalPath, filePath)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			return nil
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		switch {
+		case info.IsDir():
+			if filter.excluded(relativePath) {
+				return filepath.SkipDir
+			}
+			localDirectories[relativePath] = true
+		case info.Mode().IsRegular() && filter.included(relativePath):
+			localFiles[relativePath] = info
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error reading local directory for SyncUp: {{err}}", err)
+	}
+
+	remoteObjects, remoteDirectories, err := c.remoteTree(prefix, filter)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error listing remote directory for SyncUp: {{err}}", err)
+	}
+
+	recorder := &syncRecorder{}
+
+	// Create any missing directories from the top down before uploading.
+	var missing []string
+	for relativePath := range localDirectories {
+		if !remoteDirectories[relativePath] {
+			missing = append(missing, relativePath)
+		}
+	}
+	sort.Strings(missing)
+	if !input.DryRun {
+		if prefix != "" {
+			if err := c.PutDirectoryRecursive(&PutDirectoryInput{
+				DirectoryName: prefix,
+			}); err != nil {
+				return nil, errwrap.Wrapf("Error creating remote directory for SyncUp: {{err}}", err)
+			}
+		}
+		for _, relativePath := range missing {
+			if err := c.PutDirectory(&PutDirectoryInput{
+				DirectoryName: path.Join(prefix, relativePath),
+			}); err != nil {
+				recorder.fail(relativePath, err)
+			}
+		}
+	}
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for relativePath := range work {
+				objectPath := path.Join(prefix, relativePath)
+				filePath := filepath.Join(input.LocalPath, filepath.FromSlash(relativePath))
+
+				upToDate, err := c.syncUpToDate(objectPath, filePath, localFiles[relativePath], remoteObjects[relativePath], input.CompareMD5)
+				if err != nil {
+					recorder.fail(relativePath, err)
+					continue
+				}
+				if upToDate {
+					recorder.record(&recorder.output.Skipped, relativePath)
+					continue
+				}
+
+				if !input.DryRun {
+					if err := c.PutFile(&PutFileInput{
+						ObjectPath: objectPath,
+						FilePath:   filePath,
+					}); err != nil {
+						recorder.fail(relativePath, err)
+						continue
+					}
+				}
+				recorder.record(&recorder.output.Transferred, relativePath)
+			}
+		}()
+	}
+	for relativePath := range localFiles {
+		work <- relativePath
+	}
+	close(work)
+	wg.Wait()
+
+	if input.Delete {
+		c.syncDeleteRemote(prefix, localFiles, localDirectories, remoteObjects, remoteDirectories, input.DryRun, recorder)
+	}
+
+	return recorder.result("SyncUp")
+}
+
+// syncUpToDate reports whether the object at objectPath already holds the
+// contents of the local file at filePath.
+func (c *Client) syncUpToDate(objectPath, filePath string, info os.FileInfo, remote *DirectoryEntry, compareMD5 bool) (bool, error) {
+	if remote == nil {
+		return false, nil
+	}
+
+	head, err := c.syncHead(objectPath, info)
+	if head == nil || err != nil {
+		return false, err
+	}
+
+	if value, ok := head.Metadata[fileModifiedTimeMetadataKey]; ok {
+		if modifiedTime, err := time.Parse(time.RFC3339Nano, value); err == nil && modifiedTime.Equal(info.ModTime()) {
+			return true, nil
+		}
+	}
+
+	// The MD5 digest of an encoded object is that of the stored data.
+	if !compareMD5 || head.ContentMD5 == "" || isEncodedObject(head.Metadata) {
+		return false, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	md5Hash := md5.New()
+	if _, err := io.Copy(md5Hash, file); err != nil {
+		return false, err
+	}
+	return base64.StdEncoding.EncodeToString(md5Hash.Sum(nil)) == head.ContentMD5, nil
+}
+
+// syncDeleteRemote deletes the remote objects and directories which do not
+// exist locally, deleting directories from the bottom up.
+func (c *Client) syncDeleteRemote(prefix string, localFiles map[string]os.FileInfo, localDirectories map[string]bool,
+	remoteObjects map[string]*DirectoryEntry, remoteDirectories map[string]bool, dryRun bool, recorder *syncRecorder) {
+
+	var objectPaths, relativePaths []string
+	for relativePath := range remoteObjects {
+		if _, ok := localFiles[relativePath]; !ok {
+			objectPaths = append(objectPaths, path.Join(prefix, relativePath))
+			relativePaths = append(relativePaths, relativePath)
+		}
+	}
+	if dryRun {
+		recorder.output.Deleted = append(recorder.output.Deleted, relativePaths...)
+	} else {
+		// Failures are recorded for each path below.
+		deleted, _ := c.DeleteObjects(&DeleteObjectsInput{
+			ObjectPaths: objectPaths,
+		})
+		for i, result := range deleted.Results {
+			if result.Error != nil {
+				recorder.fail(relativePaths[i], result.Error)
+			} else {
+				recorder.output.Deleted = append(recorder.output.Deleted, relativePaths[i])
+			}
+		}
+	}
+
+	var directories []string
+	for relativePath := range remoteDirectories {
+		if !localDirectories[relativePath] {
+			directories = append(directories, relativePath)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(directories)))
+	for _, relativePath := range directories {
+		if !dryRun {
+			err := c.DeleteDirectory(&DeleteDirectoryInput{
+				DirectoryName: path.Join(prefix, relativePath),
+			})
+			if err != nil {
+				// Directories holding excluded entries are left.
+				if !IsDirectoryNotEmptyError(err) {
+					recorder.fail(relativePath, err)
+				}
+				continue
+			}
+		}
+		recorder.output.Deleted = append(recorder.output.Deleted, relativePath)
+	}
+}
+
+// SyncDownInput represents parameters to a SyncDown operation. The fields
+// have the same meaning as for SyncUpInput, with the roles of the local and
+// remote trees exchanged.
+type SyncDownInput struct {
+	Prefix      string
+	LocalPath   string
+	Include     []string
+	Exclude     []string
+	Delete      bool
+	DryRun      bool
+	Concurrency int
+}
+
+// SyncDown makes the local directory at LocalPath match the tree below
+// Prefix. An object is skipped if a file of the same size exists whose
+// modification time matches that recorded for the object by PutFile, or
+// otherwise the time the object was last modified; other objects are
+// downloaded with GetFile, which sets the modification time of the file
+// accordingly. Failures are recorded in the output, and an error is
+// returned if any path failed.
+func (c *Client) SyncDown(input *SyncDownInput) (*SyncOutput, error) {
+	filter, err := newSyncFilter(input.Include, input.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSyncConcurrency
+	}
+	prefix := strings.Trim(path.Clean("/"+input.Prefix), "/")
+
+	remoteObjects, remoteDirectories, err := c.remoteTree(prefix, filter)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error listing remote directory for SyncDown: {{err}}", err)
+	}
+
+	localFiles := map[string]os.FileInfo{}
+	localDirectories := map[string]bool{}
+	err = filepath.Walk(input.LocalPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if filePath == input.LocalPath && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		relativePath, err := filepath.Rel(input.LocalPath, filePath)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			return nil
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		switch {
+		case info.IsDir():
+			if filter.excluded(relativePath) {
+				return filepath.SkipDir
+			}
+			localDirectories[relativePath] = true
+		case info.Mode().IsRegular() && filter.included(relativePath):
+			localFiles[relativePath] = info
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error reading local directory for SyncDown: {{err}}", err)
+	}
+
+	recorder := &syncRecorder{}
+
+	if !input.DryRun {
+		if err := os.MkdirAll(input.LocalPath, 0755); err != nil {
+			return nil, errwrap.Wrapf("Error creating local directory for SyncDown: {{err}}", err)
+		}
+		for relativePath := range remoteDirectories {
+			if err := os.MkdirAll(filepath.Join(input.LocalPath, filepath.FromSlash(relativePath)), 0755); err != nil {
+				recorder.fail(relativePath, err)
+			}
+		}
+	}
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for relativePath := range work {
+				objectPath := path.Join(prefix, relativePath)
+				filePath := filepath.Join(input.LocalPath, filepath.FromSlash(relativePath))
+
+				upToDate, err := c.syncDownToDate(objectPath, localFiles[relativePath])
+				if err != nil {
+					recorder.fail(relativePath, err)
+					continue
+				}
+				if upToDate {
+					recorder.record(&recorder.output.Skipped, relativePath)
+					continue
+				}
+
+				if !input.DryRun {
+					if _, err := c.GetFile(&GetFileInput{
+						ObjectPath: objectPath,
+						FilePath:   filePath,
+					}); err != nil {
+						recorder.fail(relativePath, err)
+						continue
+					}
+				}
+				recorder.record(&recorder.output.Transferred, relativePath)
+			}
+		}()
+	}
+	for relativePath := range remoteObjects {
+		work <- relativePath
+	}
+	close(work)
+	wg.Wait()
+
+	if input.Delete {
+		syncDeleteLocal(input.LocalPath, localFiles, localDirectories, remoteObjects, remoteDirectories, input.DryRun, recorder)
+	}
+
+	return recorder.result("SyncDown")
+}
+
+// syncDownToDate reports whether the local file described by info already
+// holds the contents of the object at objectPath.
+func (c *Client) syncDownToDate(objectPath string, info os.FileInfo) (bool, error) {
+	if info == nil {
+		return false, nil
+	}
+
+	head, err := c.syncHead(objectPath, info)
+	if head == nil || err != nil {
+		return false, err
+	}
+
+	if value, ok := head.Metadata[fileModifiedTimeMetadataKey]; ok {
+		if modifiedTime, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return modifiedTime.Equal(info.ModTime()), nil
+		}
+	}
+	return head.LastModified.Equal(info.ModTime().Truncate(time.Second)), nil
+}
+
+// syncHead retrieves the metadata of the object at objectPath if its size
+// may match that of the local file described by info, and otherwise returns
+// nil. The size in a directory listing, like that of a HEAD response, is
+// that of the stored data, which for objects compressed or encrypted by
+// PutObject differs from the size of the file, so for those objects the
+// size of the decoded data is compared instead. If that is not known the sizes are
+// assumed to match, leaving the decision to the other checks.
+func (c *Client) syncHead(objectPath string, info os.FileInfo) (*HeadObjectOutput, error) {
+	head, err := c.HeadObject(&HeadObjectInput{
+		ObjectPath: objectPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded, known := c.decodedContentLength(head.ContentLength, head.Metadata); !known || decoded == uint64(info.Size()) {
+		return head, nil
+	}
+	return nil, nil
+}
+
+// syncDeleteLocal deletes the local files and directories which do not exist
+// remotely, deleting directories from the bottom up.
+func syncDeleteLocal(localPath string, localFiles map[string]os.FileInfo, localDirectories map[string]bool,
+	remoteObjects map[string]*DirectoryEntry, remoteDirectories map[string]bool, dryRun bool, recorder *syncRecorder) {
+
+	for relativePath := range localFiles {
+		if _, ok := remoteObjects[relativePath]; ok {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(filepath.Join(localPath, filepath.FromSlash(relativePath))); err != nil {
+				recorder.fail(relativePath, err)
+				continue
+			}
+		}
+		recorder.output.Deleted = append(recorder.output.Deleted, relativePath)
+	}
+
+	var directories []string
+	for relativePath := range localDirectories {
+		if !remoteDirectories[relativePath] {
+			directories = append(directories, relativePath)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(directories)))
+	for _, relativePath := range directories {
+		if !dryRun {
+			// Directories holding excluded entries are left.
+			if err := os.Remove(filepath.Join(localPath, filepath.FromSlash(relativePath))); err != nil {
+				continue
+			}
+		}
+		recorder.output.Deleted = append(recorder.output.Deleted, relativePath)
+	}
+}
@@ -0,0 +1,107 @@
+package manta
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncUpToDate(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		compress bool
+		local    string
+		touched  bool
+		wantUp   bool
+		wantDown bool
+	}{
+		{name: "unchanged", local: data, wantUp: true, wantDown: true},
+		{name: "compressed unchanged", compress: true, local: data, wantUp: true, wantDown: true},
+		{name: "size changed", local: data + "more"},
+		{name: "compressed size changed", compress: true, local: data + "more"},
+		{name: "touched", local: data, touched: true, wantUp: true},
+		{name: "compressed touched", compress: true, local: data, touched: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, client := newTestServer(t)
+			err := client.PutObject(&PutObjectInput{
+				ObjectPath: "object",
+				Compress:   tc.compress,
+				Metadata: map[string]string{
+					fileModifiedTimeMetadataKey: modified.Format(time.RFC3339Nano),
+				},
+				ObjectReader: strings.NewReader(data),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filePath := filepath.Join(t.TempDir(), "file")
+			if err := ioutil.WriteFile(filePath, []byte(tc.local), 0644); err != nil {
+				t.Fatal(err)
+			}
+			fileModified := modified
+			if tc.touched {
+				fileModified = modified.Add(time.Hour)
+			}
+			if err := os.Chtimes(filePath, fileModified, fileModified); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			up, err := client.syncUpToDate("object", filePath, info, &DirectoryEntry{}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if up != tc.wantUp {
+				t.Errorf("syncUpToDate = %v, want %v", up, tc.wantUp)
+			}
+
+			down, err := client.syncDownToDate("object", info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if down != tc.wantDown {
+				t.Errorf("syncDownToDate = %v, want %v", down, tc.wantDown)
+			}
+		})
+	}
+}
+
+func TestSyncDownFailure(t *testing.T) {
+	s, client := newTestServer(t)
+	s.put("tree/object", "a", nil)
+	s.put("tree/locked", "b", nil)
+	s.handle("stor/tree/locked", func(w http.ResponseWriter, r *http.Request) {
+		writeTestError(w, http.StatusForbidden, "AuthorizationError")
+	})
+
+	output, err := client.SyncDown(&SyncDownInput{
+		Prefix:    "tree",
+		LocalPath: t.TempDir(),
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !IsAuthorizationError(err) {
+		t.Errorf("expected an authorization error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "locked") {
+		t.Errorf("expected the error to name the failed path, got %v", err)
+	}
+	if strings.Join(output.Transferred, ",") != "object" {
+		t.Errorf("expected object transferred, got %v", output.Transferred)
+	}
+}
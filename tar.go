@@ -0,0 +1,305 @@
+package manta
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// TarInput represents parameters to a Tar operation. The archive is written
+// to Writer if it is set, and otherwise uploaded as the object at ObjectPath.
+// If Gzip is set, the archive is compressed with gzip.
+type TarInput struct {
+	// Path is the root of the tree to archive, relative to the account's
+	// /stor directory. Names in the archive are relative to Path.
+	Path string
+
+	Writer     io.Writer
+	ObjectPath string
+	Gzip       bool
+}
+
+// Tar writes the tree below Path to a tar archive, streaming each object
+// from Manta in turn. Directories are included so that empty directories are
+// preserved. The modification time of each entry is that recorded by PutFile
+// or Untar if present, and the time it was last modified in Manta otherwise.
+// Objects which were compressed without their length being recorded are
+// spooled to a temporary file first, since the length of each entry is
+// written before its data.
+func (c *Client) Tar(input *TarInput) error {
+	if input.Writer == nil && input.ObjectPath == "" {
+		return errors.New("Either Writer or ObjectPath must be set for Tar")
+	}
+
+	writer := input.Writer
+	var objectWriter *ObjectWriter
+	if writer == nil {
+		contentType := "application/x-tar"
+		if input.Gzip {
+			contentType = "application/gzip"
+		}
+		objectWriter = NewObjectWriter(c, &UploadInput{
+			ObjectPath:  input.ObjectPath,
+			ContentType: contentType,
+		}, nil)
+		writer = objectWriter
+	}
+
+	err := c.writeTar(input, writer)
+	if objectWriter != nil {
+		if err != nil {
+			objectWriter.Abort(err)
+		} else {
+			err = objectWriter.Close()
+		}
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing Tar request: {{err}}", err)
+	}
+	return nil
+}
+
+// writeTar writes the archive described by input to writer.
+func (c *Client) writeTar(input *TarInput, writer io.Writer) error {
+	var gzipWriter *gzip.Writer
+	if input.Gzip {
+		gzipWriter = gzip.NewWriter(writer)
+		writer = gzipWriter
+	}
+	tarWriter := tar.NewWriter(writer)
+
+	root := strings.Trim(path.Clean("/"+input.Path), "/")
+	err := c.Walk(root, func(entryPath string, entry *DirectoryEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := entry.Name
+		if entryPath != root {
+			name = strings.TrimPrefix(entryPath, root+"/")
+		}
+
+		if entry.Type == EntryTypeDirectory {
+			if entryPath == root {
+				return nil
+			}
+			return tarWriter.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     name + "/",
+				Mode:     0755,
+				ModTime:  entry.ModifiedTime,
+			})
+		}
+
+		return c.writeTarObject(tarWriter, entryPath, name, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	if gzipWriter != nil {
+		return gzipWriter.Close()
+	}
+	return nil
+}
+
+// writeTarObject writes the object at objectPath to tarWriter under name.
+func (c *Client) writeTarObject(tarWriter *tar.Writer, objectPath, name string, entry *DirectoryEntry) error {
+	object, err := c.GetObject(&GetObjectInput{
+		ObjectPath: objectPath,
+		IfMatch:    entry.ETag,
+	})
+	if err != nil {
+		return err
+	}
+	defer object.ObjectReader.Close()
+
+	modifiedTime := object.LastModified
+	if value, ok := object.Metadata[fileModifiedTimeMetadataKey]; ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			modifiedTime = parsed
+		}
+	}
+
+	var reader io.Reader = object.ObjectReader
+	size := int64(object.ContentLength)
+	if size == 0 && entry.Size != 0 {
+		// The object was compressed without its length being recorded,
+		// and the header must be written before the data.
+		spool, spooled, err := spoolTarObject(object.ObjectReader)
+		if spool != nil {
+			defer os.Remove(spool.Name())
+			defer spool.Close()
+		}
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("Error measuring %s: {{err}}", objectPath), err)
+		}
+		reader = spool
+		size = spooled
+	}
+
+	err = tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modifiedTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tarWriter, reader)
+	return err
+}
+
+// spoolTarObject copies the data of an object whose length is unknown to a
+// temporary file, returning the file positioned at its start and the length
+// of the data. The caller removes the file.
+func spoolTarObject(reader io.Reader) (*os.File, int64, error) {
+	file, err := ioutil.TempFile("", "manta-tar-")
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := io.Copy(file, reader)
+	if err != nil {
+		return file, 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return file, 0, err
+	}
+	return file, size, nil
+}
+
+// UntarInput represents parameters to an Untar operation. The archive is
+// read from Reader if it is set, and otherwise from the object at
+// ObjectPath. Archives compressed with gzip are detected and decompressed.
+type UntarInput struct {
+	// Prefix is the directory below which the archive is expanded,
+	// relative to the account's /stor directory. It is created if it does
+	// not exist.
+	Prefix string
+
+	Reader     io.Reader
+	ObjectPath string
+}
+
+// Untar expands a tar archive below Prefix, creating a directory for each
+// directory in the archive and an object for each regular file. The
+// modification time of each file is stored in the same metadata item as
+// PutFile uses, so that GetFile and Tar restore it. Other types of entry,
+// such as symbolic links, are skipped. Names in the archive cannot refer to
+// paths outside Prefix.
+func (c *Client) Untar(input *UntarInput) error {
+	if input.Reader == nil && input.ObjectPath == "" {
+		return errors.New("Either Reader or ObjectPath must be set for Untar")
+	}
+
+	reader := input.Reader
+	if reader == nil {
+		object, err := c.GetObject(&GetObjectInput{
+			ObjectPath: input.ObjectPath,
+		})
+		if err != nil {
+			return errwrap.Wrapf("Error executing Untar request: {{err}}", err)
+		}
+		defer object.ObjectReader.Close()
+		reader = object.ObjectReader
+	}
+
+	if err := c.readTar(input, reader); err != nil {
+		return errwrap.Wrapf("Error executing Untar request: {{err}}", err)
+	}
+	return nil
+}
+
+// readTar expands the archive read from reader as described by input.
+func (c *Client) readTar(input *UntarInput, reader io.Reader) error {
+	buffered := bufio.NewReader(reader)
+	reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	tarReader := tar.NewReader(reader)
+
+	prefix := strings.Trim(path.Clean("/"+input.Prefix), "/")
+	directories := map[string]bool{}
+	ensureDirectory := func(directoryName string) error {
+		if directoryName == "" || directories[directoryName] {
+			return nil
+		}
+		err := c.PutDirectoryRecursive(&PutDirectoryInput{
+			DirectoryName: directoryName,
+		})
+		if err != nil {
+			return err
+		}
+		directories[directoryName] = true
+		return nil
+	}
+
+	if err := ensureDirectory(prefix); err != nil {
+		return err
+	}
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.Trim(path.Clean("/"+header.Name), "/")
+		if name == "" {
+			continue
+		}
+		entryPath := path.Join(prefix, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := ensureDirectory(entryPath); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			parent := path.Dir(entryPath)
+			if parent == "." {
+				parent = ""
+			}
+			if err := ensureDirectory(parent); err != nil {
+				return err
+			}
+
+			err := c.PutObject(&PutObjectInput{
+				ObjectPath:    entryPath,
+				ContentLength: uint64(header.Size),
+				ObjectReader:  tarReader,
+				Metadata: map[string]string{
+					fileModifiedTimeMetadataKey: header.ModTime.UTC().Format(time.RFC3339Nano),
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package manta
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestTarEncodedObjects(t *testing.T) {
+	_, client := newTestServer(t)
+	putTestEncodedObjects(t, client)
+
+	var archive bytes.Buffer
+	if err := client.Tar(&TarInput{Path: "objects", Writer: &archive}); err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	reader := tar.NewReader(&archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != testEncodedData {
+			t.Errorf("%s: data does not match", header.Name)
+		}
+		names[header.Name] = true
+	}
+	for _, name := range []string{"plain", "compressed", "streamed", "encrypted"} {
+		if !names[name] {
+			t.Errorf("%s missing from the archive", name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package manta
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the combined rate at which data is read through the
+// readers it wraps. A single RateLimiter may be shared between several
+// Uploaders and Downloaders to cap their total bandwidth.
+type RateLimiter struct {
+	lock           sync.Mutex
+	bytesPerSecond float64
+	tokens         float64
+	last           time.Time
+}
+
+// NewRateLimiter is used to construct a RateLimiter which allows up to
+// bytesPerSecond bytes per second, with bursts of up to one second's worth
+// of data.
+func NewRateLimiter(bytesPerSecond uint64) *RateLimiter {
+	return &RateLimiter{
+		bytesPerSecond: float64(bytesPerSecond),
+		tokens:         float64(bytesPerSecond),
+		last:           time.Now(),
+	}
+}
+
+// Reader wraps r so that reads from it are limited by l. If r is an
+// io.Seeker, so is the result. A nil RateLimiter returns r unchanged.
+func (l *RateLimiter) Reader(r io.Reader) io.Reader {
+	if l == nil || l.bytesPerSecond <= 0 {
+		return r
+	}
+
+	lr := &rateLimitedReader{
+		reader:  r,
+		limiter: l,
+	}
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		return &rateLimitedReadSeeker{
+			rateLimitedReader: lr,
+			seeker:            seeker,
+		}
+	}
+	return lr
+}
+
+// maxRead returns the largest read which should be made at once, so that no
+// single read takes much more than a second's worth of allowance.
+func (l *RateLimiter) maxRead() int {
+	if l.bytesPerSecond < 1 {
+		return 1
+	}
+	return int(l.bytesPerSecond)
+}
+
+// take consumes n bytes of allowance, sleeping until enough has accrued.
+func (l *RateLimiter) take(n int) {
+	l.lock.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.bytesPerSecond
+	if l.tokens > l.bytesPerSecond {
+		l.tokens = l.bytesPerSecond
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.lock.Unlock()
+
+	if deficit > 0 {
+		time.Sleep(time.Duration(deficit / l.bytesPerSecond * float64(time.Second)))
+	}
+}
+
+type rateLimitedReader struct {
+	reader  io.Reader
+	limiter *RateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if max := r.limiter.maxRead(); len(p) > max {
+		p = p[:max]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.limiter.take(n)
+	}
+	return n, err
+}
+
+type rateLimitedReadSeeker struct {
+	*rateLimitedReader
+	seeker io.ReadSeeker
+}
+
+func (r *rateLimitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
@@ -0,0 +1,97 @@
+package manta
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const DefaultUpdateObjectRetries = 5
+
+// UpdateObjectInput represents parameters to an UpdateObject operation.
+//
+// Update is called with the current contents of the object, or an empty
+// reader if the object does not exist, and returns the new contents. It may
+// be called more than once, so should have no side effects. The old reader
+// remains valid until the new contents have been uploaded, so the returned
+// reader may read from it lazily.
+//
+// Retries is the number of times the update is retried after the object is
+// changed by another writer; if it is zero, DefaultUpdateObjectRetries is
+// used.
+type UpdateObjectInput struct {
+	ObjectPath string
+	Update     func(old io.Reader) (io.Reader, error)
+	Retries    int
+}
+
+// UpdateObject performs an atomic read-modify-write of a small object, such
+// as a JSON state file. The new contents are uploaded on the condition that
+// the ETag of the object is unchanged since it was read, or that the object
+// still does not exist, and the whole update is retried if that condition
+// fails. The content type, durability level, cache and CORS headers, role
+// tags and metadata of the object are preserved.
+func (c *Client) UpdateObject(input *UpdateObjectInput) error {
+	retries := input.Retries
+	if retries <= 0 {
+		retries = DefaultUpdateObjectRetries
+	}
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		err = c.updateObject(input)
+		if err == nil || !(IsPreconditionFailedError(err) || IsAlreadyExistsError(err)) {
+			break
+		}
+	}
+	if err != nil {
+		return errwrap.Wrapf("Error executing UpdateObject request: {{err}}", err)
+	}
+
+	return nil
+}
+
+// updateObject makes a single attempt at an update.
+func (c *Client) updateObject(input *UpdateObjectInput) error {
+	putInput := &PutObjectInput{
+		ObjectPath: input.ObjectPath,
+	}
+
+	var old io.Reader
+	object, err := c.GetObject(&GetObjectInput{
+		ObjectPath: input.ObjectPath,
+	})
+	switch {
+	case err == nil:
+		defer object.ObjectReader.Close()
+		old = object.ObjectReader
+		putInput.IfMatch = object.ETag
+		putInput.DurabilityLevel = object.DurabilityLevel
+		putInput.ContentType = object.ContentType
+		putInput.CacheControl = object.CacheControl
+		if !object.Expires.IsZero() {
+			putInput.Expires = &object.Expires
+		}
+		putInput.CORS = object.CORS
+		putInput.RoleTags = object.RoleTags
+		putInput.Metadata = object.Metadata
+		// The stored digest no longer describes the new contents.
+		delete(putInput.Metadata, strings.TrimPrefix(contentSHA256Header, metadataHeaderPrefix))
+	case IsResourceNotFoundError(err):
+		old = bytes.NewReader(nil)
+		putInput.CreateOnly = true
+	default:
+		return err
+	}
+
+	updated, err := input.Update(old)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("Error updating %s: {{err}}", input.ObjectPath), err)
+	}
+	putInput.ObjectReader = updated
+
+	return c.PutObject(putInput)
+}
@@ -0,0 +1,56 @@
+package manta
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUpdateObjectKeepsAttributes(t *testing.T) {
+	s, client := newTestServer(t)
+	s.put("object", "a", http.Header{
+		"Content-Type":     []string{"text/plain"},
+		"Cache-Control":    []string{"max-age=60"},
+		"Role-Tag":         []string{"readers"},
+		"M-Owner":          []string{"test"},
+		"M-Content-Sha256": []string{"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+	})
+
+	err := client.UpdateObject(&UpdateObjectInput{
+		ObjectPath: "object",
+		Update: func(old io.Reader) (io.Reader, error) {
+			data, err := ioutil.ReadAll(old)
+			if err != nil {
+				return nil, err
+			}
+			return bytes.NewReader(append(data, 'b')), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := client.GetObject(&GetObjectInput{ObjectPath: "object"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.ObjectReader.Close()
+	if data := readTestBody(t, output.ObjectReader); data != "ab" {
+		t.Errorf("expected %q, got %q", "ab", data)
+	}
+	if output.ContentType != "text/plain" || output.CacheControl != "max-age=60" {
+		t.Errorf("headers not kept: content type %q, cache control %q", output.ContentType, output.CacheControl)
+	}
+	if strings.Join(output.RoleTags, ",") != "readers" {
+		t.Errorf("role tags not kept: %v", output.RoleTags)
+	}
+	if output.Metadata["owner"] != "test" {
+		t.Errorf("metadata not kept: %v", output.Metadata)
+	}
+	if _, ok := output.Metadata["content-sha256"]; ok {
+		t.Errorf("stale digest kept: %v", output.Metadata)
+	}
+}
@@ -0,0 +1,428 @@
+package manta
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	DefaultUploadPartSize           = MpuMinPartSize
+	DefaultUploadConcurrency        = 5
+	DefaultUploadMultipartThreshold = 2 * DefaultUploadPartSize
+	DefaultUploadPartRetries        = 3
+)
+
+// UploaderOptions represents the configuration of an Uploader. Any field left
+// as zero takes the corresponding default value.
+type UploaderOptions struct {
+	// PartSize is the size in bytes of each part of a multipart upload.
+	// It is increased if necessary so that the object fits within
+	// MpuMaxParts parts, and may not be less than MpuMinPartSize.
+	PartSize uint64
+
+	// Concurrency is the number of parts uploaded at once. Up to this many
+	// parts may be buffered in memory unless the source of the upload
+	// implements io.ReaderAt and io.Seeker, as *os.File does.
+	Concurrency int
+
+	// MultipartThreshold is the size in bytes below which an object is
+	// uploaded with a single PutObject request.
+	MultipartThreshold uint64
+
+	// PartRetries is the number of times the upload of a part is retried
+	// after failing, in addition to any retries made by the client.
+	PartRetries int
+
+	// RateLimiter, if set, limits the rate at which data is uploaded.
+	RateLimiter *RateLimiter
+
+	// LeavePartsOnError prevents a failed multipart upload from being
+	// aborted, so that it can later be resumed using UploadInput.UploadID.
+	LeavePartsOnError bool
+}
+
+// Uploader uploads objects to Manta, switching to a multipart upload with
+// parts uploaded concurrently for objects larger than a threshold. An
+// Uploader is safe for concurrent use.
+type Uploader struct {
+	client  *Client
+	options UploaderOptions
+}
+
+// NewUploader is used to construct an Uploader which uploads objects using
+// client. If options is nil, default options are used.
+func NewUploader(client *Client, options *UploaderOptions) *Uploader {
+	uploader := &Uploader{
+		client: client,
+	}
+	if options != nil {
+		uploader.options = *options
+	}
+
+	if uploader.options.PartSize < MpuMinPartSize {
+		uploader.options.PartSize = DefaultUploadPartSize
+	}
+	if uploader.options.Concurrency <= 0 {
+		uploader.options.Concurrency = DefaultUploadConcurrency
+	}
+	if uploader.options.MultipartThreshold == 0 {
+		uploader.options.MultipartThreshold = DefaultUploadMultipartThreshold
+	}
+	if uploader.options.PartRetries == 0 {
+		uploader.options.PartRetries = DefaultUploadPartRetries
+	}
+
+	return uploader
+}
+
+// UploadInput represents parameters to an Upload operation. The fields have
+// the same meaning as the equivalent fields of PutObjectInput.
+//
+// If UploadID is set, Upload resumes the existing multipart upload with that
+// ID rather than starting a new one. Every part already uploaded is listed
+// using ListParts, however many pages that takes, and any whose size
+// matches the part which would be uploaded in its place are kept, so
+// ObjectReader must supply the same data as the original upload and the
+// Uploader must be configured with the same PartSize.
+//
+// If Progress is set, it is called as data is uploaded. Parts kept when
+// resuming an upload are reported as transferred.
+type UploadInput struct {
+	UploadID        string
+	ObjectPath      string
+	DurabilityLevel uint64
+	ContentType     string
+	ContentLength   uint64
+	Metadata        map[string]string
+	Headers         map[string]string
+	ObjectReader    io.Reader
+	Progress        ProgressFunc
+}
+
+// UploadOutput contains the outputs of an Upload operation. UploadID is the
+// ID of the multipart upload used, or empty if the object was uploaded with
+// a single request. It is returned along with any error from a multipart
+// upload, so the upload can be resumed if LeavePartsOnError is set; ListParts
+// reports the parts which were completed.
+type UploadOutput struct {
+	UploadID string
+}
+
+// Upload uploads an object. If the size of the object is known, either from
+// ContentLength or because ObjectReader is an io.Seeker, and is below the
+// multipart threshold, a single PutObject request is made. Otherwise up to
+// the threshold is buffered to decide whether a multipart upload is needed.
+//
+// If a multipart upload fails, it is aborted unless LeavePartsOnError is set.
+func (u *Uploader) Upload(input *UploadInput) (*UploadOutput, error) {
+	if input.UploadID != "" {
+		return u.resume(input)
+	}
+
+	size := input.ContentLength
+	sizeKnown := size != 0
+	if !sizeKnown {
+		if seeker, ok := input.ObjectReader.(io.ReadSeeker); ok {
+			remaining, err := remainingLength(seeker)
+			if err != nil {
+				return nil, errwrap.Wrapf("Error determining upload size: {{err}}", err)
+			}
+			size = remaining
+			sizeKnown = true
+		}
+	}
+
+	var total uint64
+	if sizeKnown {
+		total = size
+	}
+	tracker := newProgressTracker(input.Progress, total)
+
+	reader := input.ObjectReader
+	if sizeKnown && size < u.options.MultipartThreshold {
+		return &UploadOutput{}, u.putObject(input, u.options.RateLimiter.Reader(tracker.reader(reader, 0)), size)
+	}
+	if !sizeKnown {
+		buffer := make([]byte, u.options.MultipartThreshold)
+		n, err := io.ReadFull(reader, buffer)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			tracker.total = uint64(n)
+			small := tracker.reader(bytes.NewReader(buffer[:n]), 0)
+			return &UploadOutput{}, u.putObject(input, u.options.RateLimiter.Reader(small), uint64(n))
+		}
+		if err != nil {
+			return nil, errwrap.Wrapf("Error reading upload data: {{err}}", err)
+		}
+		reader = io.MultiReader(bytes.NewReader(buffer), reader)
+	}
+
+	createOutput, err := u.client.CreateMpuUpload(&CreateMpuUploadInput{
+		ObjectPath:      input.ObjectPath,
+		DurabilityLevel: input.DurabilityLevel,
+		ContentType:     input.ContentType,
+		ContentLength:   input.ContentLength,
+		Metadata:        input.Metadata,
+		Headers:         input.Headers,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error creating multipart upload: {{err}}", err)
+	}
+
+	return u.multipartUpload(createOutput.ID, reader, size, sizeKnown, nil, tracker)
+}
+
+// resume continues the multipart upload identified by input.UploadID.
+func (u *Uploader) resume(input *UploadInput) (*UploadOutput, error) {
+	output := &UploadOutput{
+		UploadID: input.UploadID,
+	}
+
+	state, err := u.client.GetUpload(&GetUploadInput{
+		ID: input.UploadID,
+	})
+	if err != nil {
+		return output, errwrap.Wrapf("Error getting multipart upload state: {{err}}", err)
+	}
+	if state.State != MpuStateCreated {
+		return output, fmt.Errorf("Multipart upload %s cannot be resumed in state %q", input.UploadID, state.State)
+	}
+
+	listOutput, err := u.client.ListParts(&ListPartsInput{
+		ID: input.UploadID,
+	})
+	if err != nil {
+		return output, errwrap.Wrapf("Error listing multipart upload parts: {{err}}", err)
+	}
+	existing := map[uint64]*MpuPart{}
+	for _, part := range listOutput.Parts {
+		existing[part.PartNumber] = part
+	}
+
+	size := input.ContentLength
+	sizeKnown := size != 0
+	if !sizeKnown {
+		if seeker, ok := input.ObjectReader.(io.ReadSeeker); ok {
+			remaining, err := remainingLength(seeker)
+			if err != nil {
+				return output, errwrap.Wrapf("Error determining upload size: {{err}}", err)
+			}
+			size = remaining
+			sizeKnown = true
+		}
+	}
+
+	var total uint64
+	if sizeKnown {
+		total = size
+	}
+	tracker := newProgressTracker(input.Progress, total)
+
+	return u.multipartUpload(input.UploadID, input.ObjectReader, size, sizeKnown, existing, tracker)
+}
+
+// multipartUpload uploads the parts of the upload with the given ID from
+// reader, skipping any of the existing parts which are reusable, and then
+// commits it.
+func (u *Uploader) multipartUpload(id string, reader io.Reader, size uint64, sizeKnown bool, existing map[uint64]*MpuPart, tracker *progressTracker) (*UploadOutput, error) {
+	output := &UploadOutput{
+		UploadID: id,
+	}
+
+	partSize := u.partSize(size, sizeKnown)
+	parts, err := u.uploadParts(id, reader, partSize, size, sizeKnown, existing, tracker)
+	if err == nil {
+		err = u.client.CommitUpload(&CommitUploadInput{
+			ID:    id,
+			Parts: parts,
+		})
+	}
+	if err != nil {
+		if !u.options.LeavePartsOnError {
+			u.client.AbortUpload(&AbortUploadInput{
+				ID: id,
+			})
+		}
+		return output, errwrap.Wrapf("Error executing multipart upload: {{err}}", err)
+	}
+
+	return output, nil
+}
+
+func (u *Uploader) putObject(input *UploadInput, reader io.Reader, size uint64) error {
+	return u.client.PutObject(&PutObjectInput{
+		ObjectPath:      input.ObjectPath,
+		DurabilityLevel: input.DurabilityLevel,
+		ContentType:     input.ContentType,
+		ContentLength:   size,
+		Metadata:        input.Metadata,
+		Headers:         input.Headers,
+		ObjectReader:    reader,
+	})
+}
+
+// partSize returns the size of the parts used to upload an object of the
+// given size, such that it fits within MpuMaxParts parts.
+func (u *Uploader) partSize(size uint64, sizeKnown bool) uint64 {
+	partSize := u.options.PartSize
+	if sizeKnown {
+		if minimum := (size + MpuMaxParts - 1) / MpuMaxParts; minimum > partSize {
+			partSize = minimum
+		}
+	}
+	return partSize
+}
+
+// uploadPart describes a part waiting to be uploaded.
+type uploadPart struct {
+	number uint64
+	reader io.ReadSeeker
+	size   uint64
+}
+
+// uploadParts splits reader into parts of partSize bytes and uploads them
+// using the configured number of workers, returning the ETags of the parts
+// in order. If reader is an io.ReaderAt and io.Seeker and the size is known,
+// parts are read directly from it; otherwise each part is buffered before
+// upload. Parts in existing of the expected size are not uploaded again.
+func (u *Uploader) uploadParts(id string, reader io.Reader, partSize, size uint64, sizeKnown bool, existing map[uint64]*MpuPart, tracker *progressTracker) ([]string, error) {
+	var lock sync.Mutex
+	var firstErr error
+	etags := map[uint64]string{}
+
+	failed := func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return firstErr != nil
+	}
+
+	parts := make(chan *uploadPart)
+	var wg sync.WaitGroup
+	for i := 0; i < u.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range parts {
+				etag, err := u.uploadPart(id, part, tracker)
+
+				lock.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				etags[part.number] = etag
+				lock.Unlock()
+			}
+		}()
+	}
+
+	readerAt, isReaderAt := reader.(io.ReaderAt)
+	var base int64
+	if seeker, ok := reader.(io.Seeker); ok && isReaderAt {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			isReaderAt = false
+		}
+		base = current
+	} else {
+		isReaderAt = false
+	}
+
+	var partNumber uint64
+	var offset uint64
+	for !failed() {
+		if partNumber >= MpuMaxParts {
+			lock.Lock()
+			firstErr = fmt.Errorf("Object exceeds the maximum of %d parts of %d bytes", MpuMaxParts, partSize)
+			lock.Unlock()
+			break
+		}
+
+		var part *uploadPart
+		if sizeKnown && isReaderAt {
+			if offset >= size && partNumber > 0 {
+				break
+			}
+			length := partSize
+			if offset+length > size {
+				length = size - offset
+			}
+			part = &uploadPart{
+				number: partNumber,
+				reader: io.NewSectionReader(readerAt, base+int64(offset), int64(length)),
+				size:   length,
+			}
+		} else {
+			buffer := make([]byte, partSize)
+			n, err := io.ReadFull(reader, buffer)
+			if err == io.EOF && partNumber > 0 {
+				break
+			}
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				lock.Lock()
+				firstErr = errwrap.Wrapf("Error reading upload data: {{err}}", err)
+				lock.Unlock()
+				break
+			}
+			part = &uploadPart{
+				number: partNumber,
+				reader: bytes.NewReader(buffer[:n]),
+				size:   uint64(n),
+			}
+		}
+
+		if previous, ok := existing[part.number]; ok && previous.Size == part.size && previous.ETag != "" {
+			lock.Lock()
+			etags[part.number] = previous.ETag
+			lock.Unlock()
+			tracker.add(int64(part.size), part.number)
+		} else {
+			parts <- part
+		}
+		partNumber++
+		offset += part.size
+		if part.size < partSize {
+			break
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	result := make([]string, partNumber)
+	for number := range result {
+		result[number] = etags[uint64(number)]
+	}
+	return result, nil
+}
+
+// uploadPart uploads a single part, retrying up to the configured number of
+// times, and returns its ETag.
+func (u *Uploader) uploadPart(id string, part *uploadPart, tracker *progressTracker) (string, error) {
+	reader := u.options.RateLimiter.Reader(tracker.reader(part.reader, part.number)).(io.ReadSeeker)
+
+	var err error
+	for attempt := 0; attempt <= u.options.PartRetries; attempt++ {
+		if _, err = reader.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+
+		var output *UploadPartOutput
+		output, err = u.client.UploadPart(&UploadPartInput{
+			ID:            id,
+			PartNumber:    part.number,
+			ContentLength: part.size,
+			ObjectReader:  reader,
+		})
+		if err == nil {
+			return output.ETag, nil
+		}
+	}
+
+	return "", errwrap.Wrapf(fmt.Sprintf("Error uploading part %d: {{err}}", part.number), err)
+}
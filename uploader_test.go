@@ -0,0 +1,164 @@
+package manta
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// zeroReader is an io.Reader, io.ReaderAt and io.Seeker over size zero
+// bytes, which stands in for a large file without allocating it.
+type zeroReader struct {
+	size   int64
+	offset int64
+}
+
+func (z *zeroReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= z.size {
+		return 0, io.EOF
+	}
+	n := len(p)
+	if remaining := z.size - off; int64(n) > remaining {
+		n = int(remaining)
+	}
+	for i := range p[:n] {
+		p[i] = 0
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	n, err := z.ReadAt(p, z.offset)
+	z.offset += int64(n)
+	return n, err
+}
+
+func (z *zeroReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += z.offset
+	case io.SeekEnd:
+		offset += z.size
+	}
+	z.offset = offset
+	return offset, nil
+}
+
+// testUpload serves a multipart upload in the created state from a
+// testServer, holding the given number of full-sized parts, and records the
+// parts uploaded and the ETags committed.
+type testUpload struct {
+	lock      sync.Mutex
+	uploaded  []uint64
+	committed []string
+}
+
+func handleTestUpload(s *testServer, id string, existing int) *testUpload {
+	upload := &testUpload{}
+
+	var names []string
+	for i := 0; i < existing; i++ {
+		names = append(names, strconv.Itoa(i))
+	}
+	sort.Strings(names)
+
+	prefix := "/" + testAccount + "/uploads/" + id[:1] + "/" + id
+	s.handle("uploads/"+id[:1]+"/"+id, func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case r.Method == http.MethodGet && rest == "/state":
+			json.NewEncoder(w).Encode(&GetUploadOutput{
+				ID:    id,
+				State: MpuStateCreated,
+			})
+		case r.Method == http.MethodGet && rest == "":
+			w.Header().Set("Content-Type", "application/x-json-stream; type=directory")
+			w.Header().Set("Result-Set-Size", strconv.Itoa(existing))
+			writeTestDirectoryPage(w, r, names, func(name string) *DirectoryEntry {
+				return &DirectoryEntry{
+					Name:         name,
+					Type:         EntryTypeObject,
+					ETag:         "existing-" + name,
+					Size:         MpuMinPartSize,
+					ModifiedTime: time.Now(),
+				}
+			})
+		case r.Method == http.MethodPut:
+			number, _ := strconv.ParseUint(strings.TrimPrefix(rest, "/"), 10, 64)
+			io.Copy(ioutil.Discard, r.Body)
+			upload.lock.Lock()
+			upload.uploaded = append(upload.uploaded, number)
+			upload.lock.Unlock()
+			w.Header().Set("Etag", "uploaded-"+strconv.FormatUint(number, 10))
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && rest == "/commit":
+			var body struct {
+				Parts []string `json:"parts"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			upload.lock.Lock()
+			upload.committed = body.Parts
+			upload.lock.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	return upload
+}
+
+func TestUploaderResume(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing int
+	}{
+		{name: "no parts", existing: 0},
+		{name: "single page", existing: 3},
+		{name: "past the first page", existing: listDirectoryPageSize + 76},
+		{name: "several pages", existing: 3 * listDirectoryPageSize},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, client := newTestServer(t)
+			id := "f00dcafe"
+			upload := handleTestUpload(s, id, tc.existing)
+
+			// The object is one byte longer than the existing parts, so
+			// only the last part should be uploaded.
+			size := int64(tc.existing)*MpuMinPartSize + 1
+			_, err := NewUploader(client, nil).Upload(&UploadInput{
+				UploadID:      id,
+				ObjectPath:    "large",
+				ContentLength: uint64(size),
+				ObjectReader:  &zeroReader{size: size},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(upload.uploaded) != 1 || upload.uploaded[0] != uint64(tc.existing) {
+				t.Errorf("expected only part %d to be uploaded, got %v", tc.existing, upload.uploaded)
+			}
+			if len(upload.committed) != tc.existing+1 {
+				t.Fatalf("expected %d parts committed, got %d", tc.existing+1, len(upload.committed))
+			}
+			for number, etag := range upload.committed[:tc.existing] {
+				if expected := "existing-" + strconv.Itoa(number); etag != expected {
+					t.Fatalf("expected part %d to be committed as %q, got %q", number, expected, etag)
+				}
+			}
+		})
+	}
+}
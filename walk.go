@@ -0,0 +1,125 @@
+package manta
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// SkipDir may be returned by a WalkFunc called for a directory to skip the
+// contents of that directory. It is not returned as an error by Walk.
+var SkipDir = errors.New("skip this directory")
+
+// WalkFunc is the type of the function called by Walk for each object and
+// directory visited. entryPath is the path of the entry relative to the
+// account's /stor directory, with root as a prefix.
+//
+// If the root cannot be read, or a directory cannot be listed, the function
+// is called with a nil entry and the error; returning the error stops the
+// walk, while returning nil continues it. If the function returns SkipDir
+// for a directory, its contents are not visited; for an object, the rest of
+// the directory containing it is skipped. Any other error stops the walk and
+// is returned by Walk.
+type WalkFunc func(entryPath string, entry *DirectoryEntry, err error) error
+
+// Walk walks the tree rooted at root depth-first, calling walkFn for the
+// root and for each object and directory beneath it, including root. Entries
+// within each directory are visited in order of name. Directories are listed
+// a page at a time, so arbitrarily large directories may be walked.
+func (c *Client) Walk(root string, walkFn WalkFunc) error {
+	root = strings.Trim(path.Clean("/"+root), "/")
+
+	entry, err := c.rootEntry(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = c.walk(root, entry, walkFn)
+	}
+	if err == SkipDir {
+		return nil
+	}
+	return err
+}
+
+// rootEntry returns a DirectoryEntry describing the root of a walk. The root
+// of the account's /stor directory is always a directory.
+func (c *Client) rootEntry(root string) (*DirectoryEntry, error) {
+	if root == "" {
+		return &DirectoryEntry{
+			Name: "stor",
+			Type: EntryTypeDirectory,
+		}, nil
+	}
+
+	head, err := c.HeadObject(&HeadObjectInput{
+		ObjectPath: root,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &DirectoryEntry{
+		ETag:         head.ETag,
+		ModifiedTime: head.LastModified,
+		Name:         path.Base(root),
+		Size:         head.ContentLength,
+		Type:         EntryTypeObject,
+		Durability:   head.DurabilityLevel,
+	}
+	if isDirectoryContentType(head.ContentType) {
+		entry.ETag = ""
+		entry.Size = 0
+		entry.Type = EntryTypeDirectory
+	}
+
+	return entry, nil
+}
+
+// walk recursively descends the tree from entryPath.
+func (c *Client) walk(entryPath string, entry *DirectoryEntry, walkFn WalkFunc) error {
+	if err := walkFn(entryPath, entry, nil); err != nil {
+		return err
+	}
+	if entry.Type != EntryTypeDirectory {
+		return nil
+	}
+
+	err := c.listDirectoryAll(entryPath, func(child *DirectoryEntry) error {
+		err := c.walk(path.Join(entryPath, child.Name), child, walkFn)
+		if err == SkipDir && child.Type == EntryTypeDirectory {
+			return nil
+		}
+		if err != nil {
+			return &walkFuncError{err}
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+
+	if walkErr, ok := err.(*walkFuncError); ok {
+		// SkipDir returned for an object skips the rest of the
+		// directory containing it.
+		if walkErr.err == SkipDir {
+			return nil
+		}
+		return walkErr.err
+	}
+
+	err = walkFn(entryPath, nil, err)
+	if err == SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkFuncError distinguishes an error returned by a WalkFunc from an error
+// listing a directory.
+type walkFuncError struct {
+	err error
+}
+
+func (e *walkFuncError) Error() string {
+	return e.err.Error()
+}